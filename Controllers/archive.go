@@ -0,0 +1,116 @@
+package Controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/spf13/viper"
+	"github.com/thedevsaddam/renderer"
+)
+
+var archiveService services.ArchiveService
+
+// RunArchiveWorker archive chaque heure les todos terminés depuis plus de
+// ARCHIVE_AFTER_DAYS jours (30 par défaut, 0 désactive l'archivage automatique)
+func RunArchiveWorker(ctx context.Context) {
+	worker, ok := archiveService.(*services.ArchiveServiceImp)
+	if !ok {
+		return
+	}
+	after := services.DefaultArchiveAfter
+	if viper.IsSet("ARCHIVE_AFTER_DAYS") {
+		days := viper.GetInt("ARCHIVE_AFTER_DAYS")
+		if days <= 0 {
+			return
+		}
+		after = time.Duration(days) * 24 * time.Hour
+	}
+	worker.Run(ctx, time.Hour, after)
+}
+
+// includeArchived lit ?include=archived, les valeurs pouvant être séparées par des virgules
+func includeArchived(r *http.Request) bool {
+	for _, include := range strings.Split(r.URL.Query().Get("include"), ",") {
+		if strings.TrimSpace(include) == "archived" {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveTodo archive un todo et ses sous-tâches : POST /todo/{id}/archive
+func ArchiveTodo(w http.ResponseWriter, r *http.Request) {
+	archiveTodoAction("archive", archiveService.ArchiveTodo)(w, r)
+}
+
+// UnarchiveTodo restaure un todo, ses sous-tâches et ses parents : POST /todo/{id}/unarchive
+func UnarchiveTodo(w http.ResponseWriter, r *http.Request) {
+	archiveTodoAction("unarchive", archiveService.UnarchiveTodo)(w, r)
+}
+
+// ArchiveList archive une liste et ses todos : POST /lists/{id}/archive
+func ArchiveList(w http.ResponseWriter, r *http.Request) {
+	archiveListAction("archive", archiveService.ArchiveList)(w, r)
+}
+
+// UnarchiveList restaure une liste et les todos archivés avec elle : POST /lists/{id}/unarchive
+func UnarchiveList(w http.ResponseWriter, r *http.Request) {
+	archiveListAction("unarchive", archiveService.UnarchiveList)(w, r)
+}
+
+func archiveTodoAction(action string, apply func(userID, id uint) (models.TodoModel, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid ID",
+			})
+			return
+		}
+		todo, err := apply(currentUserID(r), uint(id))
+		if err != nil {
+			log.Printf("Error on todo %s: %v", action, err)
+			rnd.JSON(w, todoErrorStatus(err), renderer.M{
+				"message": "Failed to " + action + " todo",
+				"error":   err.Error(),
+			})
+			return
+		}
+		rnd.JSON(w, http.StatusOK, renderer.M{
+			"message": "Todo " + action + "d successfully",
+			"todo":    todo,
+		})
+	}
+}
+
+func archiveListAction(action string, apply func(userID, id uint) (models.ListModel, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid ID",
+			})
+			return
+		}
+		list, err := apply(currentUserID(r), uint(id))
+		if err != nil {
+			log.Printf("Error on list %s: %v", action, err)
+			rnd.JSON(w, listErrorStatus(err), renderer.M{
+				"message": "Failed to " + action + " list",
+				"error":   err.Error(),
+			})
+			return
+		}
+		rnd.JSON(w, http.StatusOK, renderer.M{
+			"message": "List " + action + "d successfully",
+			"list":    list,
+		})
+	}
+}
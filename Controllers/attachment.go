@@ -0,0 +1,178 @@
+package Controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-todo1/blobstore"
+	"github.com/go-todo1/services"
+	"github.com/spf13/viper"
+	"github.com/thedevsaddam/renderer"
+)
+
+const defaultAttachmentMaxSize = 10 << 20
+
+var attachmentService services.AttachmentService
+var attachmentMaxSize int64 = defaultAttachmentMaxSize
+
+// newBlobStore choisit le stockage des pièces jointes selon ATTACHMENT_STORE (local ou s3)
+func newBlobStore() (blobstore.Store, error) {
+	switch store := viper.GetString("ATTACHMENT_STORE"); store {
+	case "", "local":
+		dir := viper.GetString("ATTACHMENT_DIR")
+		if dir == "" {
+			dir = "data/attachments"
+		}
+		return blobstore.NewLocal(dir)
+	case "s3":
+		return blobstore.NewS3(viper.GetString("S3_ENDPOINT"), viper.GetString("S3_REGION"), viper.GetString("S3_BUCKET"),
+			os.Getenv("S3_ACCESS_KEY"), os.Getenv("S3_SECRET_KEY")), nil
+	default:
+		return nil, fmt.Errorf("unknown attachment store %q", store)
+	}
+}
+
+// attachmentErrorStatus associe les erreurs du service des pièces jointes au code HTTP à renvoyer
+func attachmentErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound), errors.Is(err, services.ErrAttachmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAttachmentTooLarge), errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrAttachmentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrEmptyAttachment):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// ListAttachments retourne les pièces jointes d'un todo : GET /todo/{id}/attachments
+func ListAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	attachments, err := attachmentService.List(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching attachments: %v", err)
+		rnd.JSON(w, attachmentErrorStatus(err), renderer.M{
+			"message": "Failed to fetch attachments",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": attachments,
+	})
+}
+
+// UploadAttachment joint un fichier à un todo : POST /todo/{id}/attachments, formulaire
+// multipart avec le champ "file"
+func UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	// marge pour les en-têtes multipart ; au-delà de 1 Mo les parties vont dans des fichiers temporaires
+	r.Body = http.MaxBytesReader(w, r.Body, attachmentMaxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		status := attachmentErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		rnd.JSON(w, status, renderer.M{
+			"message": "Invalid multipart form",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "The file field is required",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := attachmentService.Upload(uint(id), currentUserID(r), header.Filename, file, header.Size)
+	if err != nil {
+		log.Printf("Error uploading attachment: %v", err)
+		rnd.JSON(w, attachmentErrorStatus(err), renderer.M{
+			"message": "Failed to save attachment",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message":    "Attachment uploaded successfully",
+		"attachment": attachment,
+	})
+}
+
+// DownloadAttachment envoie le fichier : GET /attachments/{id}. Les requêtes Range et
+// If-Range sont gérées par http.ServeContent.
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	attachment, content, err := attachmentService.Open(currentUserID(r), uint(id))
+	if err != nil {
+		if attachmentErrorStatus(err) == http.StatusNotFound {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error opening attachment: %v", err)
+		http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "true" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.Checksum+`"`)
+	http.ServeContent(w, r, attachment.FileName, attachment.CreatedAt, content)
+}
+
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := attachmentService.Delete(currentUserID(r), uint(id)); err != nil {
+		if attachmentErrorStatus(err) == http.StatusNotFound {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting attachment: %v", err)
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Attachment deleted successfully"))
+}
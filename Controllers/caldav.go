@@ -0,0 +1,340 @@
+package Controllers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/ical"
+	"github.com/go-todo1/services"
+)
+
+// Préfixe sous lequel le serveur CalDAV est monté dans main.go
+const caldavPrefix = "/caldav"
+
+const maxCalendarObjectSize = 1 << 20
+
+var calendarService services.CalendarService
+
+// ICalFeed sert le flux iCalendar en lecture seule d'un utilisateur : /ical/{jeton}.ics
+func ICalFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(chi.URLParam(r, "feed"), ".ics")
+	user, err := userService.GetByFeedToken(token)
+	if err != nil {
+		http.Error(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+	todos, err := calendarService.Todos(user.ID)
+	if err != nil {
+		log.Printf("Error fetching todos for feed: %v", err)
+		http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := ical.WriteCalendar(w, "Todos "+user.Name, todos); err != nil {
+		log.Printf("Error writing feed: %v", err)
+	}
+}
+
+// caldavUser authentifie la requête CalDAV par le jeton présent dans l'URL
+func caldavUser(w http.ResponseWriter, r *http.Request) (models.UserModel, bool) {
+	user, err := userService.GetByFeedToken(chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return user, false
+	}
+	return user, true
+}
+
+func CalDAVOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+func CalDAVPropfindCollection(w http.ResponseWriter, r *http.Request) {
+	user, ok := caldavUser(w, r)
+	if !ok {
+		return
+	}
+	todos, err := calendarService.Todos(user.ID)
+	if err != nil {
+		log.Printf("Error fetching todos for CalDAV: %v", err)
+		http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+		return
+	}
+
+	responses := []davResponse{collectionResponse(user, todos)}
+	if r.Header.Get("Depth") != "0" {
+		for _, todo := range todos {
+			responses = append(responses, resourceResponse(user, todo, false))
+		}
+	}
+	writeMultistatus(w, responses)
+}
+
+func CalDAVPropfindResource(w http.ResponseWriter, r *http.Request) {
+	user, todo, ok := caldavResource(w, r)
+	if !ok {
+		return
+	}
+	writeMultistatus(w, []davResponse{resourceResponse(user, todo, false)})
+}
+
+// CalDAVReport gère calendar-query (tous les VTODO) et calendar-multiget (liste de href)
+func CalDAVReport(w http.ResponseWriter, r *http.Request) {
+	user, ok := caldavUser(w, r)
+	if !ok {
+		return
+	}
+	kind, hrefs, err := parseReport(io.LimitReader(r.Body, maxCalendarObjectSize))
+	if err != nil {
+		http.Error(w, "Invalid REPORT body", http.StatusBadRequest)
+		return
+	}
+
+	var responses []davResponse
+	switch kind {
+	case "calendar-query":
+		todos, err := calendarService.Todos(user.ID)
+		if err != nil {
+			log.Printf("Error fetching todos for CalDAV: %v", err)
+			http.Error(w, "Failed to fetch todos", http.StatusInternalServerError)
+			return
+		}
+		for _, todo := range todos {
+			responses = append(responses, resourceResponse(user, todo, true))
+		}
+	case "calendar-multiget":
+		for _, href := range hrefs {
+			todo, err := calendarService.Find(user.ID, uidFromHref(href))
+			if err != nil {
+				responses = append(responses, davResponse{href: href, status: http.StatusNotFound})
+				continue
+			}
+			responses = append(responses, resourceResponse(user, todo, true))
+		}
+	default:
+		http.Error(w, "Unsupported REPORT", http.StatusBadRequest)
+		return
+	}
+	writeMultistatus(w, responses)
+}
+
+func CalDAVGet(w http.ResponseWriter, r *http.Request) {
+	_, todo, ok := caldavResource(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", ical.ETag(todo))
+	if err := ical.WriteCalendar(w, "", []models.TodoModel{todo}); err != nil {
+		log.Printf("Error writing calendar object: %v", err)
+	}
+}
+
+func CalDAVPut(w http.ResponseWriter, r *http.Request) {
+	user, ok := caldavUser(w, r)
+	if !ok {
+		return
+	}
+	uid := uidFromHref(chi.URLParam(r, "resource"))
+
+	todo, bodyUID, err := ical.ParseTodo(io.LimitReader(r.Body, maxCalendarObjectSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if bodyUID != uid {
+		http.Error(w, "UID must match the resource name", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := calendarService.Find(user.ID, uid)
+	exists := err == nil
+	if err != nil && !errors.Is(err, services.ErrTodoNotFound) {
+		log.Printf("Error fetching todo for CalDAV: %v", err)
+		http.Error(w, "Failed to fetch todo", http.StatusInternalServerError)
+		return
+	}
+	if match := r.Header.Get("If-None-Match"); match == "*" && exists {
+		http.Error(w, "Resource already exists", http.StatusPreconditionFailed)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && (!exists || (match != "*" && match != ical.ETag(existing))) {
+		http.Error(w, "Resource has changed", http.StatusPreconditionFailed)
+		return
+	}
+
+	saved, created, err := calendarService.Put(user.ID, uid, todo)
+	if err != nil {
+		status := todoErrorStatus(err)
+		if status != http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		log.Printf("Error saving todo from CalDAV: %v", err)
+		http.Error(w, "Failed to save todo", status)
+		return
+	}
+	w.Header().Set("ETag", ical.ETag(saved))
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func CalDAVDelete(w http.ResponseWriter, r *http.Request) {
+	user, todo, ok := caldavResource(w, r)
+	if !ok {
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != ical.ETag(todo) {
+		http.Error(w, "Resource has changed", http.StatusPreconditionFailed)
+		return
+	}
+	if err := calendarService.Remove(user.ID, ical.UID(todo)); err != nil {
+		log.Printf("Error deleting todo from CalDAV: %v", err)
+		http.Error(w, "Failed to delete todo", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func caldavResource(w http.ResponseWriter, r *http.Request) (models.UserModel, models.TodoModel, bool) {
+	user, ok := caldavUser(w, r)
+	if !ok {
+		return user, models.TodoModel{}, false
+	}
+	todo, err := calendarService.Find(user.ID, uidFromHref(chi.URLParam(r, "resource")))
+	if err != nil {
+		if errors.Is(err, services.ErrTodoNotFound) {
+			http.Error(w, "Resource not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching todo for CalDAV: %v", err)
+			http.Error(w, "Failed to fetch todo", http.StatusInternalServerError)
+		}
+		return user, todo, false
+	}
+	return user, todo, true
+}
+
+// uidFromHref extrait l'UID d'un href ou d'un nom de ressource "<uid>.ics"
+func uidFromHref(href string) string {
+	name := href[strings.LastIndex(href, "/")+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.TrimSuffix(name, ".ics")
+}
+
+func collectionHref(user models.UserModel) string {
+	return caldavPrefix + "/" + user.FeedToken + "/"
+}
+
+func resourceHref(user models.UserModel, todo models.TodoModel) string {
+	return collectionHref(user) + url.PathEscape(ical.UID(todo)) + ".ics"
+}
+
+type davResponse struct {
+	href   string
+	props  string
+	status int
+}
+
+func collectionResponse(user models.UserModel, todos []models.TodoModel) davResponse {
+	var ctag int64
+	for _, todo := range todos {
+		if ts := todo.UpdatedAt.UnixNano(); ts > ctag {
+			ctag = ts
+		}
+	}
+	props := `<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>` +
+		`<d:displayname>` + xmlEscape("Todos "+user.Name) + `</d:displayname>` +
+		`<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>` +
+		fmt.Sprintf(`<cs:getctag>%d-%d</cs:getctag>`, len(todos), ctag)
+	return davResponse{href: collectionHref(user), props: props, status: http.StatusOK}
+}
+
+func resourceResponse(user models.UserModel, todo models.TodoModel, withData bool) davResponse {
+	props := `<d:resourcetype/>` +
+		`<d:getcontenttype>text/calendar; charset=utf-8; component=VTODO</d:getcontenttype>` +
+		`<d:getetag>` + xmlEscape(ical.ETag(todo)) + `</d:getetag>`
+	if withData {
+		var buf bytes.Buffer
+		ical.WriteCalendar(&buf, "", []models.TodoModel{todo})
+		props += `<c:calendar-data>` + xmlEscape(buf.String()) + `</c:calendar-data>`
+	}
+	return davResponse{href: resourceHref(user, todo), props: props, status: http.StatusOK}
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, resp := range responses {
+		b.WriteString(`<d:response><d:href>` + xmlEscape(resp.href) + `</d:href>`)
+		status := fmt.Sprintf("<d:status>HTTP/1.1 %d %s</d:status>", resp.status, http.StatusText(resp.status))
+		if resp.status == http.StatusOK {
+			b.WriteString(`<d:propstat><d:prop>` + resp.props + `</d:prop>` + status + `</d:propstat>`)
+		} else {
+			b.WriteString(status)
+		}
+		b.WriteString(`</d:response>`)
+	}
+	b.WriteString(`</d:multistatus>`)
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// parseReport retourne le nom de l'élément racine et les href demandés
+func parseReport(r io.Reader) (string, []string, error) {
+	dec := xml.NewDecoder(r)
+	var root string
+	var hrefs []string
+	inHref := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root == "" {
+				root = t.Name.Local
+			}
+			inHref = t.Name.Local == "href"
+		case xml.CharData:
+			if inHref {
+				hrefs = append(hrefs, strings.TrimSpace(string(t)))
+			}
+		case xml.EndElement:
+			inHref = false
+		}
+	}
+	if root == "" {
+		return "", nil, errors.New("empty REPORT body")
+	}
+	return root, hrefs, nil
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
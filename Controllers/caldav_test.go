@@ -0,0 +1,119 @@
+package Controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupCalDAV(t *testing.T) (http.Handler, models.UserModel) {
+	db, err := gorm.Open(sqlite.Open("file:caldav?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	db.AutoMigrate(&models.TodoModel{}, &models.UserModel{}, &models.OutboxEvent{}, &models.TodoDependency{}, &models.CommentModel{}, &models.TodoActivity{}, &models.TimeEntry{}, &models.FocusSession{}, &models.CustomField{}, &models.CustomFieldValue{})
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	userService = services.NewUserServiceImp(db)
+	calendarService = services.NewCalendarServiceImp(db, services.NewTodoServiceImp(db, ""))
+	user, err := userService.Create(models.UserModel{Name: "Sara", Email: "sara@example.com"})
+	assert.NoError(t, err)
+
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+	router := chi.NewRouter()
+	router.Get("/ical/{feed}", ICalFeed)
+	router.MethodFunc("PROPFIND", "/caldav/{token}/", CalDAVPropfindCollection)
+	router.MethodFunc("REPORT", "/caldav/{token}/", CalDAVReport)
+	router.Get("/caldav/{token}/{resource}", CalDAVGet)
+	router.Put("/caldav/{token}/{resource}", CalDAVPut)
+	router.Delete("/caldav/{token}/{resource}", CalDAVDelete)
+	return router, user
+}
+
+func serve(router http.Handler, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCalDAVRoundTrip(t *testing.T) {
+	router, user := setupCalDAV(t)
+	base := "/caldav/" + user.FeedToken + "/"
+	vtodo := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:abc-123\r\nSUMMARY:Buy milk\r\nSTATUS:NEEDS-ACTION\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	rr := serve(router, "PUT", base+"abc-123.ics", vtodo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	rr = serve(router, "PUT", base+"abc-123.ics", vtodo, map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+	rr = serve(router, "PROPFIND", base, "", map[string]string{"Depth": "1"})
+	assert.Equal(t, http.StatusMultiStatus, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<d:href>"+base+"abc-123.ics</d:href>")
+	assert.Contains(t, rr.Body.String(), `<c:comp name="VTODO"/>`)
+
+	completed := strings.Replace(vtodo, "NEEDS-ACTION", "COMPLETED", 1)
+	rr = serve(router, "PUT", base+"abc-123.ics", completed, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	multiget := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+		`<d:prop><d:getetag/><c:calendar-data/></d:prop><d:href>` + base + `abc-123.ics</d:href>` +
+		`<d:href>` + base + `missing.ics</d:href></c:calendar-multiget>`
+	rr = serve(router, "REPORT", base, multiget, nil)
+	assert.Equal(t, http.StatusMultiStatus, rr.Code)
+	assert.Contains(t, rr.Body.String(), "STATUS:COMPLETED")
+	assert.Contains(t, rr.Body.String(), "HTTP/1.1 404 Not Found")
+
+	rr = serve(router, "GET", "/ical/"+user.FeedToken+".ics", "", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "SUMMARY:Buy milk")
+
+	rr = serve(router, "DELETE", base+"abc-123.ics", "", nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = serve(router, "GET", base+"abc-123.ics", "", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = serve(router, "GET", "/ical/wrong-token.ics", "", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCalDAVPutReplacesTodo(t *testing.T) {
+	router, user := setupCalDAV(t)
+	base := "/caldav/" + user.FeedToken + "/"
+	full := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:rep-1\r\nSUMMARY:Report\r\nDESCRIPTION:Draft first\r\nDUE:20261103T090000Z\r\nPRIORITY:1\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	bare := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:rep-1\r\nSUMMARY:Report\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	rr := serve(router, "PUT", base+"rep-1.ics", full, nil)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = serve(router, "PUT", base+"rep-1.ics", bare, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = serve(router, "GET", base+"rep-1.ics", "", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "DESCRIPTION")
+	assert.NotContains(t, rr.Body.String(), "DUE")
+	assert.NotContains(t, rr.Body.String(), "PRIORITY")
+
+	invalid := strings.Replace(bare, "END:VTODO", "RRULE:FREQ=SOMETIMES\r\nEND:VTODO", 1)
+	rr = serve(router, "PUT", base+"rep-1.ics", invalid, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,167 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var commentService services.CommentService
+
+type commentRequest struct {
+	Body string `json:"body"` // markdown
+}
+
+// commentErrorStatus associe les erreurs du service des commentaires au code HTTP à renvoyer
+func commentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound), errors.Is(err, services.ErrCommentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNotAuthor):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// ListComments retourne les commentaires d'un todo : GET /todo/{id}/comments
+func ListComments(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	comments, err := commentService.List(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching comments: %v", err)
+		rnd.JSON(w, commentErrorStatus(err), renderer.M{
+			"message": "Failed to fetch comments",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": comments,
+	})
+}
+
+// CreateComment ajoute un commentaire : POST /todo/{id}/comments {"body": "@bob peux-tu relire ?"}
+func CreateComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	comment, err := commentService.Create(models.CommentModel{TodoID: uint(id), AuthorID: currentUserID(r), Body: req.Body})
+	if err != nil {
+		log.Printf("Error creating comment: %v", err)
+		rnd.JSON(w, commentErrorStatus(err), renderer.M{
+			"message": "Failed to save comment",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Comment created successfully",
+		"comment": comment,
+	})
+}
+
+// UpdateComment modifie un commentaire de l'utilisateur : PUT /comments/{id}
+func UpdateComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	var req commentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	comment, err := commentService.Update(currentUserID(r), uint(id), req.Body)
+	if err != nil {
+		log.Printf("Error updating comment: %v", err)
+		rnd.JSON(w, commentErrorStatus(err), renderer.M{
+			"message": "Failed to update comment",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Comment updated successfully",
+		"comment": comment,
+	})
+}
+
+func DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := commentService.Delete(currentUserID(r), uint(id)); err != nil {
+		switch commentErrorStatus(err) {
+		case http.StatusNotFound:
+			http.Error(w, "Comment not found", http.StatusNotFound)
+		case http.StatusForbidden:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Error deleting comment: %v", err)
+			http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Comment deleted successfully"))
+}
+
+// TodoActivity retourne le fil d'un todo, commentaires et modifications mêlés : GET /todo/{id}/activity
+func TodoActivity(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	entries, err := commentService.Timeline(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching activity: %v", err)
+		rnd.JSON(w, commentErrorStatus(err), renderer.M{
+			"message": "Failed to fetch activity",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": entries,
+	})
+}
@@ -0,0 +1,247 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var focusService services.FocusService
+
+// focusKeepAlive espace les commentaires envoyés sur un flux sans session pour garder la connexion
+const focusKeepAlive = 15 * time.Second
+
+type focusRequest struct {
+	TodoID  *uint  `json:"todo_id"`
+	Kind    string `json:"kind"`    // work (par défaut) ou break
+	Minutes int    `json:"minutes"` // 25 pour le travail et 5 pour une pause par défaut
+}
+
+// focusErrorStatus associe les erreurs des sessions de concentration au code HTTP à renvoyer
+func focusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound), errors.Is(err, services.ErrFocusNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFocusActive), errors.Is(err, services.ErrFocusStateChanged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidFocus):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// StartFocus démarre une session : POST /focus {"todo_id": 3, "kind": "work", "minutes": 25}
+func StartFocus(w http.ResponseWriter, r *http.Request) {
+	var req focusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	session, err := focusService.Start(models.FocusSession{
+		UserID:          currentUserID(r),
+		TodoID:          req.TodoID,
+		Kind:            req.Kind,
+		DurationSeconds: int64(req.Minutes) * 60,
+	})
+	if err != nil {
+		log.Printf("Error starting focus session: %v", err)
+		rnd.JSON(w, focusErrorStatus(err), renderer.M{
+			"message": "Failed to start focus session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Focus session started",
+		"session": session,
+	})
+}
+
+// CurrentFocus retourne la session en cours de l'utilisateur, null s'il n'y en a pas : GET /focus/current
+func CurrentFocus(w http.ResponseWriter, r *http.Request) {
+	session, err := focusService.Current(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching focus session: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch focus session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"session": session,
+	})
+}
+
+// focusAction applique pause, resume ou complete : POST /focus/{id}/{action}
+func focusAction(action string, apply func(userID, id uint) (models.FocusSession, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid ID",
+			})
+			return
+		}
+		session, err := apply(currentUserID(r), uint(id))
+		if err != nil {
+			log.Printf("Error on focus session %s: %v", action, err)
+			rnd.JSON(w, focusErrorStatus(err), renderer.M{
+				"message": "Failed to " + action + " focus session",
+				"error":   err.Error(),
+			})
+			return
+		}
+		rnd.JSON(w, http.StatusOK, renderer.M{
+			"session": session,
+		})
+	}
+}
+
+func PauseFocus(w http.ResponseWriter, r *http.Request) {
+	focusAction("pause", focusService.Pause)(w, r)
+}
+
+func ResumeFocus(w http.ResponseWriter, r *http.Request) {
+	focusAction("resume", focusService.Resume)(w, r)
+}
+
+func CompleteFocus(w http.ResponseWriter, r *http.Request) {
+	focusAction("complete", focusService.Complete)(w, r)
+}
+
+// FocusStats retourne le temps de concentration par todo : GET /focus/stats?from=2024-08-01&to=2024-09-01
+func FocusStats(w http.ResponseWriter, r *http.Request) {
+	from, err := parseDateParam(r, "from")
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid date",
+			"error":   err.Error(),
+		})
+		return
+	}
+	to, err := parseDateParam(r, "to")
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid date",
+			"error":   err.Error(),
+		})
+		return
+	}
+	stats, err := focusService.Stats(currentUserID(r), from, to)
+	if err != nil {
+		log.Printf("Error computing focus stats: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to compute focus stats",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": stats,
+	})
+}
+
+// FocusStream envoie en Server-Sent Events l'état de la session chaque seconde (événement
+// "tick", data null sans session) et sa fin (événement "complete") : GET /focus/stream
+func FocusStream(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	rc := http.NewResponseController(w)
+	// le flux dure plus longtemps que le WriteTimeout du serveur
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Error clearing write deadline: %v", err)
+	}
+
+	updates, cancel := focusService.Subscribe(userID)
+	defer cancel()
+	session, err := focusService.Current(userID)
+	if err != nil {
+		log.Printf("Error fetching focus session: %v", err)
+		http.Error(w, "Failed to fetch focus session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, data interface{}) bool {
+		payload, _ := json.Marshal(data)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	// l'échéance est recalculée à chaque tick plutôt que décrémentée, pour ne pas dériver
+	var deadline time.Time
+	track := func(s *models.FocusSession) {
+		session = s
+		if s != nil {
+			deadline = time.Now().Add(time.Duration(s.RemainingSeconds) * time.Second)
+		}
+	}
+	track(session)
+	if !send("tick", session) {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	idle := time.Now()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case s := <-updates:
+			if s.State == models.FocusCompleted {
+				track(nil)
+				if !send("complete", s) {
+					return
+				}
+				continue
+			}
+			track(&s)
+			if !send("tick", session) {
+				return
+			}
+		case now := <-ticker.C:
+			if session == nil {
+				if now.Sub(idle) >= focusKeepAlive {
+					idle = now
+					if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil || rc.Flush() != nil {
+						return
+					}
+				}
+				continue
+			}
+			if session.State == models.FocusRunning {
+				session.RemainingSeconds = int64(math.Max(0, math.Ceil(deadline.Sub(now).Seconds())))
+				if session.RemainingSeconds == 0 {
+					// termine la session en base ; l'abonnement reçoit l'événement de fin
+					if _, err := focusService.Current(userID); err != nil {
+						log.Printf("Error completing focus session: %v", err)
+					}
+					continue
+				}
+			}
+			if !send("tick", session) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package Controllers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thedevsaddam/renderer"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestFocusStream(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:focus?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	db.AutoMigrate(&models.TodoModel{}, &models.FocusSession{})
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	rnd = renderer.New(renderer.Options{})
+	focusService = services.NewFocusServiceImp(db)
+	router := chi.NewRouter()
+	router.Post("/focus", StartFocus)
+	router.Get("/focus/stream", FocusStream)
+	router.Post("/focus/{id}/complete", CompleteFocus)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/focus/stream", nil)
+	req.Header.Set("X-User-ID", "7")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() (string, string) {
+		var event, data string
+		for lines.Scan() {
+			line := lines.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event != "":
+				return event, data
+			}
+		}
+		return "", ""
+	}
+
+	event, data := next()
+	assert.Equal(t, "tick", event)
+	assert.Equal(t, "null", data, "no session yet")
+
+	start, _ := http.NewRequest("POST", server.URL+"/focus", strings.NewReader(`{"minutes": 10}`))
+	start.Header.Set("X-User-ID", "7")
+	res, err := http.DefaultClient.Do(start)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	event, data = next()
+	assert.Equal(t, "tick", event)
+	assert.Contains(t, data, `"state":"running"`)
+	assert.Contains(t, data, `"remaining_seconds":600`)
+
+	session, err := focusService.Current(7)
+	require.NoError(t, err)
+	_, err = focusService.Complete(7, session.ID)
+	require.NoError(t, err)
+	for event == "tick" {
+		event, data = next()
+	}
+	assert.Equal(t, "complete", event)
+	assert.Contains(t, data, `"state":"completed"`)
+}
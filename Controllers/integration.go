@@ -0,0 +1,177 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var integrationService services.IntegrationService
+
+// RunChatDigests publie les résumés quotidiens des intégrations de messagerie
+func RunChatDigests(ctx context.Context) {
+	worker, ok := integrationService.(*services.IntegrationServiceImp)
+	if !ok {
+		return
+	}
+	worker.Run(ctx, time.Minute)
+}
+
+// RunChatDeliveries envoie les publications des intégrations de messagerie en attente
+func RunChatDeliveries(ctx context.Context) {
+	worker, ok := integrationService.(*services.IntegrationServiceImp)
+	if !ok {
+		return
+	}
+	worker.RunDeliveries(ctx, 5*time.Second)
+}
+
+func integrationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrIntegrationNotFound), errors.Is(err, services.ErrListNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidIntegration):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func ListIntegrations(w http.ResponseWriter, r *http.Request) {
+	integrations, err := integrationService.List(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching integrations: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch integrations",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": integrations,
+	})
+}
+
+// CreateIntegration ajoute une intégration de messagerie : POST /integrations
+// {"name": "Team", "format": "slack", "url": "https://hooks.slack.com/services/...", "list_id": 3,
+// "events": "todo.completed,digest", "digest_time": "09:00", "timezone": "Europe/Paris"}
+func CreateIntegration(w http.ResponseWriter, r *http.Request) {
+	var integration models.ChatIntegration
+	if err := json.NewDecoder(r.Body).Decode(&integration); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	integration.UserID = currentUserID(r)
+
+	created, err := integrationService.Create(integration)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrListNotFound) {
+			status = http.StatusNotFound
+		}
+		log.Printf("Error creating integration: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to save integration",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message":     "Integration created successfully",
+		"integration": created,
+	})
+}
+
+func UpdateIntegration(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var integration models.ChatIntegration
+	if err := json.NewDecoder(r.Body).Decode(&integration); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	updated, err := integrationService.Update(currentUserID(r), uint(id), integration)
+	if err != nil {
+		log.Printf("Error updating integration: %v", err)
+		rnd.JSON(w, integrationErrorStatus(err), renderer.M{
+			"message": "Failed to update integration",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message":     "Integration updated successfully",
+		"integration": updated,
+	})
+}
+
+func DeleteIntegration(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := integrationService.Delete(currentUserID(r), uint(id)); err != nil {
+		if errors.Is(err, services.ErrIntegrationNotFound) {
+			http.Error(w, "Integration not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting integration: %v", err)
+		http.Error(w, "Failed to delete integration", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Integration deleted successfully"))
+}
+
+// TestIntegration publie un message de test : POST /integrations/{id}/test ;
+// un refus du webhook est signalé par 502
+func TestIntegration(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	if err := integrationService.Test(currentUserID(r), uint(id)); err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, services.ErrIntegrationNotFound) {
+			status = http.StatusNotFound
+		}
+		log.Printf("Error testing integration: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to post test message",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Test message posted",
+	})
+}
@@ -0,0 +1,228 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var listService services.ListService
+
+// listErrorStatus associe les erreurs du service des listes au code HTTP à renvoyer
+func listErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrListNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrStatusInUse), errors.Is(err, services.ErrFieldInUse):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// ListLists retourne les listes de l'utilisateur, avec les listes archivées si ?include=archived
+func ListLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := listService.List(currentUserID(r), includeArchived(r))
+	if err != nil {
+		log.Printf("Error fetching lists: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch lists",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": lists,
+	})
+}
+
+// CreateList crée une liste ; sans "statuses", elle reçoit le flux par défaut
+func CreateList(w http.ResponseWriter, r *http.Request) {
+	var list models.ListModel
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	list.UserID = currentUserID(r)
+
+	created, err := listService.Create(list)
+	if err != nil {
+		log.Printf("Error creating list: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Failed to save list",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "List created successfully",
+		"list":    created,
+	})
+}
+
+func UpdateList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var list models.ListModel
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	updated, err := listService.Update(currentUserID(r), uint(id), list)
+	if err != nil {
+		log.Printf("Error updating list: %v", err)
+		rnd.JSON(w, listErrorStatus(err), renderer.M{
+			"message": "Failed to update list",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "List updated successfully",
+		"list":    updated,
+	})
+}
+
+func DeleteList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := listService.Delete(currentUserID(r), uint(id)); err != nil {
+		if errors.Is(err, services.ErrListNotFound) {
+			http.Error(w, "List not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting list: %v", err)
+		http.Error(w, "Failed to delete list", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("List deleted successfully"))
+}
+
+// SetListWorkflow remplace les colonnes d'une liste : PUT /lists/{id}/workflow
+// [{"name": "todo"}, {"name": "doing", "transitions": "todo done"}, {"name": "done", "done": true}]
+func SetListWorkflow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var statuses []models.WorkflowStatus
+	if err := json.NewDecoder(r.Body).Decode(&statuses); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	list, err := listService.SetWorkflow(currentUserID(r), uint(id), statuses)
+	if err != nil {
+		log.Printf("Error updating workflow: %v", err)
+		rnd.JSON(w, listErrorStatus(err), renderer.M{
+			"message": "Failed to update workflow",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Workflow updated successfully",
+		"list":    list,
+	})
+}
+
+// SetListFields remplace les champs personnalisés d'une liste : PUT /lists/{id}/fields
+// [{"name": "story_points", "type": "number"}, {"name": "env", "type": "enum", "options": ["dev", "prod"], "required": true}]
+func SetListFields(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var fields []models.CustomField
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	list, err := listService.SetFields(currentUserID(r), uint(id), fields)
+	if err != nil {
+		log.Printf("Error updating custom fields: %v", err)
+		rnd.JSON(w, listErrorStatus(err), renderer.M{
+			"message": "Failed to update custom fields",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Custom fields updated successfully",
+		"list":    list,
+	})
+}
+
+// ListBoard retourne le tableau kanban d'une liste : GET /lists/{id}/board, 0 pour les todos sans liste
+func ListBoard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	board, err := listService.Board(currentUserID(r), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrListNotFound) {
+			status = http.StatusNotFound
+		}
+		log.Printf("Error fetching board: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to fetch board",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": board,
+	})
+}
@@ -0,0 +1,78 @@
+package Controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/go-todo1/mailin"
+	"github.com/go-todo1/services"
+	"github.com/spf13/viper"
+)
+
+var mailIngestService services.MailIngestService
+
+// RunMailIngest reçoit par SMTP, sur MAIL_LISTEN_ADDR ("127.0.0.1:2525" par exemple), les
+// e-mails transférés par les utilisateurs et en fait des todos. Sans MAIL_LISTEN_ADDR, rien
+// n'est écouté. Chaque utilisateur a sa propre adresse secrète, todo+<jeton>@MAIL_DOMAIN
+// (MAIL_ADDRESS remplace todo@MAIL_DOMAIN) ; MAIL_MAX_SIZE limite la taille, MAIL_MAX_SESSIONS
+// le nombre de connexions simultanées.
+func RunMailIngest(ctx context.Context) {
+	addr := viper.GetString("MAIL_LISTEN_ADDR")
+	if addr == "" {
+		return
+	}
+	address := viper.GetString("MAIL_ADDRESS")
+	if address == "" {
+		address = "todo@" + viper.GetString("MAIL_DOMAIN")
+	}
+	server := &mailin.Server{
+		Addr:        addr,
+		Domain:      viper.GetString("MAIL_DOMAIN"),
+		MaxSize:     viper.GetInt64("MAIL_MAX_SIZE"),
+		MaxSessions: viper.GetInt("MAIL_MAX_SESSIONS"),
+		Accept: func(rcpt string) bool {
+			token, ok := services.RecipientToken(rcpt, address)
+			if !ok {
+				return false
+			}
+			_, err := userService.GetByMailToken(token)
+			return err == nil
+		},
+		Handler: func(ctx context.Context, env mailin.Envelope) error {
+			return ingestMail(env, address)
+		},
+	}
+	log.Println("Receiving emails on", addr)
+	if err := server.ListenAndServe(ctx); err != nil {
+		log.Printf("Error receiving emails: %v", err)
+	}
+}
+
+// ingestMail crée un todo pour chaque destinataire ; un destinataire inconnu est refusé
+// définitivement, pour que le serveur expéditeur renvoie un avis de non-remise
+func ingestMail(env mailin.Envelope, address string) error {
+	msg, err := mailin.Parse(bytes.NewReader(env.Data))
+	if err != nil {
+		return &mailin.Error{Code: 554, Message: "5.6.0 Malformed message"}
+	}
+	created := 0
+	for _, rcpt := range env.To {
+		token, _ := services.RecipientToken(rcpt, address)
+		todo, err := mailIngestService.Ingest(token, msg)
+		if err != nil {
+			if errors.Is(err, services.ErrUnknownRecipient) {
+				log.Printf("Rejected email to %q: %v", rcpt, err)
+				continue
+			}
+			return err
+		}
+		created++
+		log.Printf("Created todo %d from email", todo.ID)
+	}
+	if created == 0 {
+		return &mailin.Error{Code: 550, Message: "5.1.1 No such recipient"}
+	}
+	return nil
+}
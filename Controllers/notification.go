@@ -0,0 +1,185 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/notifications"
+	"github.com/spf13/viper"
+	"github.com/thedevsaddam/renderer"
+)
+
+var (
+	inbox  *notifications.Inbox
+	mailer *notifications.Mailer // nil sans SMTP_HOST : les e-mails restent en attente
+)
+
+// newMailer configure l'envoi des notifications par e-mail à partir de SMTP_HOST, SMTP_PORT
+// (25 par défaut, 1025 pour MailHog), SMTP_USERNAME, SMTP_FROM, APP_URL et de la variable
+// d'environnement SMTP_PASSWORD
+func newMailer() (*notifications.Mailer, error) {
+	host := viper.GetString("SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	port := viper.GetString("SMTP_PORT")
+	if port == "" {
+		port = "25"
+	}
+	from := viper.GetString("SMTP_FROM")
+	if from == "" {
+		from = "Todos <todo@localhost>"
+	}
+	sender := &notifications.SMTP{
+		Addr:     net.JoinHostPort(host, port),
+		Username: viper.GetString("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+	return notifications.NewMailer(Database, sender, viper.GetString("APP_URL"))
+}
+
+// RunNotificationMailer envoie les notifications par e-mail en arrière-plan, si SMTP est configuré
+func RunNotificationMailer(ctx context.Context) {
+	if mailer == nil {
+		return
+	}
+	mailer.Run(ctx, 10*time.Second)
+}
+
+// ListNotifications retourne la boîte de réception : GET /notifications?unread=true&limit=50
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	limit := 0
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid limit",
+			})
+			return
+		}
+		limit = n
+	}
+	list, err := inbox.List(userID, r.URL.Query().Get("unread") == "true", limit)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch notifications",
+			"error":   err.Error(),
+		})
+		return
+	}
+	unread, err := inbox.Unread(userID)
+	if err != nil {
+		log.Printf("Error counting notifications: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch notifications",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data":   list,
+		"unread": unread,
+	})
+}
+
+// MarkNotificationRead marque une notification comme lue : POST /notifications/{id}/read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	n, err := inbox.MarkRead(currentUserID(r), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, notifications.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		log.Printf("Error marking notification as read: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to mark notification as read",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"notification": n,
+	})
+}
+
+// MarkAllNotificationsRead marque toute la boîte de réception comme lue : POST /notifications/read
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	count, err := inbox.MarkAllRead(currentUserID(r))
+	if err != nil {
+		log.Printf("Error marking notifications as read: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to mark notifications as read",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Notifications marked as read",
+		"count":   count,
+	})
+}
+
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs, err := inbox.Preferences(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching notification preferences: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch notification preferences",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": prefs,
+	})
+}
+
+// SetNotificationPreferences choisit les canaux par type : PUT /notifications/preferences
+// [{"type": "mention", "in_app": true, "email": false}]
+func SetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var prefs []models.NotificationPreference
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	updated, err := inbox.SetPreferences(currentUserID(r), prefs)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, notifications.ErrInvalidPreference) {
+			status = http.StatusBadRequest
+		}
+		log.Printf("Error updating notification preferences: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to update notification preferences",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Notification preferences updated",
+		"data":    updated,
+	})
+}
@@ -0,0 +1,64 @@
+package Controllers
+
+import (
+	"net/http"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/quickadd"
+	"github.com/thedevsaddam/renderer"
+)
+
+// parseQuickAdd analyse text selon ?lang=en|fr (les deux par défaut) et ?tz=Europe/Paris
+func parseQuickAdd(w http.ResponseWriter, r *http.Request, text string) (quickadd.Result, bool) {
+	lang := r.URL.Query().Get("lang")
+	if lang != "" && lang != quickadd.English && lang != quickadd.French {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid lang, expected en or fr",
+		})
+		return quickadd.Result{}, false
+	}
+	now := time.Now()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid tz",
+				"error":   err.Error(),
+			})
+			return quickadd.Result{}, false
+		}
+		now = now.In(loc)
+	}
+	return quickadd.Parse(text, now, lang), true
+}
+
+// applyQuickAdd remplace le titre du todo par son analyse ; les étiquettes sont créées au
+// besoin par TodoService.Create, dans la transaction du todo
+func applyQuickAdd(w http.ResponseWriter, r *http.Request, t *models.TodoModel) bool {
+	result, ok := parseQuickAdd(w, r, t.Title)
+	if !ok {
+		return false
+	}
+	result.Apply(t)
+	t.TagNames = append(t.TagNames, result.Tags...)
+	return true
+}
+
+// PreviewQuickAdd retourne l'analyse d'une saisie rapide sans rien enregistrer : GET /todo/parse?text=...
+func PreviewQuickAdd(w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("text")
+	if text == "" {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "The text is required",
+		})
+		return
+	}
+	result, ok := parseQuickAdd(w, r, text)
+	if !ok {
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": result,
+	})
+}
@@ -0,0 +1,37 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-todo1/quickadd"
+	"github.com/stretchr/testify/assert"
+	"github.com/thedevsaddam/renderer"
+)
+
+func TestPreviewQuickAdd(t *testing.T) {
+	rnd = renderer.New(renderer.Options{})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/todo/parse?lang=fr&tz=Europe/Paris&text=Payer+la+facture+demain+%C3%A0+17h+%23finance+!haute", nil)
+	PreviewQuickAdd(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Data quickadd.Result `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "Payer la facture", body.Data.Title)
+	assert.Equal(t, []string{"finance"}, body.Data.Tags)
+	assert.Equal(t, "A", body.Data.Priority)
+	if assert.NotNil(t, body.Data.DueAt) {
+		assert.Equal(t, 17, body.Data.DueAt.Hour())
+	}
+
+	for _, url := range []string{"/todo/parse", "/todo/parse?text=x&lang=de", "/todo/parse?text=x&tz=Mars/Olympus"} {
+		rr = httptest.NewRecorder()
+		PreviewQuickAdd(rr, httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+	}
+}
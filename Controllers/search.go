@@ -0,0 +1,37 @@
+package Controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var searchService services.SearchService
+
+func SearchTodos(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "The q parameter is required",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	results, err := searchService.Search(currentUserID(r), q, limit)
+	if err != nil {
+		log.Printf("Error searching todos: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to search todos",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": results,
+	})
+}
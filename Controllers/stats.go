@@ -0,0 +1,108 @@
+package Controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var statsService services.StatsService
+
+// statsErrorStatus renvoie 400 pour une période trop longue, 500 sinon
+func statsErrorStatus(err error) int {
+	if errors.Is(err, services.ErrStatsRangeTooLong) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// parseStatsFilter lit ?user=me&list={id}&from=&to=&tz=Europe/Paris ; list=0 désigne l'Inbox
+func parseStatsFilter(r *http.Request) (services.StatsFilter, error) {
+	var filter services.StatsFilter
+	query := r.URL.Query()
+	filter.Location = time.Local
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return filter, fmt.Errorf("invalid tz %q", tz)
+		}
+		filter.Location = loc
+	}
+	var err error
+	if filter.UserID, err = parseUserParam(r); err != nil {
+		return filter, err
+	}
+	if param := query.Get("list"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid list %q", param)
+		}
+		listID := uint(id)
+		filter.ListID = &listID
+	}
+	if filter.From, err = parseDateParamIn(r, "from", filter.Location); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseDateParamIn(r, "to", filter.Location); err != nil {
+		return filter, err
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	return filter, nil
+}
+
+// Stats retourne les todos terminés par jour, le temps moyen pour terminer, le taux de retard
+// et les séries de jours productifs : GET /stats?user=me&from=2024-08-01&to=2024-09-01
+func Stats(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		rnd.JSON(w, filterErrorStatus(err), renderer.M{
+			"message": "Invalid filter",
+			"error":   err.Error(),
+		})
+		return
+	}
+	stats, err := statsService.Productivity(filter)
+	if err != nil {
+		log.Printf("Error computing stats: %v", err)
+		rnd.JSON(w, statsErrorStatus(err), renderer.M{
+			"message": "Failed to compute stats",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": stats,
+	})
+}
+
+// StatsBurndown retourne les todos ouverts en fin de journée, par liste : GET /stats/burndown, mêmes filtres
+func StatsBurndown(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStatsFilter(r)
+	if err != nil {
+		rnd.JSON(w, filterErrorStatus(err), renderer.M{
+			"message": "Invalid filter",
+			"error":   err.Error(),
+		})
+		return
+	}
+	burndowns, err := statsService.Burndown(filter)
+	if err != nil {
+		log.Printf("Error computing burndown: %v", err)
+		rnd.JSON(w, statsErrorStatus(err), renderer.M{
+			"message": "Failed to compute burndown",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": burndowns,
+	})
+}
@@ -0,0 +1,117 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var tagService services.TagService
+
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := tagService.List(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching tags: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch tags",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": tags,
+	})
+}
+
+func CreateTag(w http.ResponseWriter, r *http.Request) {
+	var tag models.TagModel
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	tag.UserID = currentUserID(r)
+
+	created, err := tagService.Create(tag)
+	if err != nil {
+		log.Printf("Error creating tag: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Failed to save tag",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Tag created successfully",
+		"tag":     created,
+	})
+}
+
+func UpdateTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var tag models.TagModel
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	updated, err := tagService.Update(currentUserID(r), uint(id), tag)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrTagNotFound) {
+			status = http.StatusNotFound
+		}
+		log.Printf("Error updating tag: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to update tag",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Tag updated successfully",
+		"tag":     updated,
+	})
+}
+
+func DeleteTag(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := tagService.Delete(currentUserID(r), uint(id)); err != nil {
+		if errors.Is(err, services.ErrTagNotFound) {
+			http.Error(w, "Tag not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting tag: %v", err)
+		http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Tag deleted successfully"))
+}
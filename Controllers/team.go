@@ -0,0 +1,40 @@
+package Controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var teamService services.TeamService
+
+// TeamWorkload retourne la charge par responsable : GET /team/workload?list=3
+func TeamWorkload(w http.ResponseWriter, r *http.Request) {
+	var listID *uint
+	if param := r.URL.Query().Get("list"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid list",
+			})
+			return
+		}
+		list := uint(id)
+		listID = &list
+	}
+	workloads, err := teamService.Workload(currentUserID(r), listID)
+	if err != nil {
+		log.Printf("Error fetching workload: %v", err)
+		rnd.JSON(w, listErrorStatus(err), renderer.M{
+			"message": "Failed to fetch workload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": workloads,
+	})
+}
@@ -0,0 +1,244 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var templateService services.TemplateService
+
+// templateErrorStatus associe les erreurs des modèles au code HTTP ; à l'instanciation,
+// les erreurs de création des todos suivent todoErrorStatus
+func templateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound), errors.Is(err, services.ErrListNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidTemplate):
+		return http.StatusBadRequest
+	}
+	return todoErrorStatus(err)
+}
+
+func ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := templateService.List(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching templates: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch templates",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": templates,
+	})
+}
+
+func GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	template, err := templateService.Get(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching template: %v", err)
+		rnd.JSON(w, templateErrorStatus(err), renderer.M{
+			"message": "Failed to fetch template",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"template": template,
+	})
+}
+
+// CreateTemplate crée un modèle : POST /templates
+// {"name": "Onboarding", "items": [{"title": "Accounts", "due_offset_days": 1, "tags": ["it"], "subtasks": [{"title": "Email"}]}]}
+func CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template models.TemplateModel
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	template.UserID = currentUserID(r)
+
+	created, err := templateService.Create(template)
+	if err != nil {
+		log.Printf("Error creating template: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Failed to save template",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message":  "Template created successfully",
+		"template": created,
+	})
+}
+
+func UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var template models.TemplateModel
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	updated, err := templateService.Update(currentUserID(r), uint(id), template)
+	if err != nil {
+		log.Printf("Error updating template: %v", err)
+		rnd.JSON(w, templateErrorStatus(err), renderer.M{
+			"message": "Failed to update template",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message":  "Template updated successfully",
+		"template": updated,
+	})
+}
+
+func DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := templateService.Delete(currentUserID(r), uint(id)); err != nil {
+		if errors.Is(err, services.ErrTemplateNotFound) {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting template: %v", err)
+		http.Error(w, "Failed to delete template", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Template deleted successfully"))
+}
+
+// InstantiateTemplate crée les todos d'un modèle : POST /templates/{id}/instantiate
+// {"start": "2024-09-02", "list_name": "Onboarding Alice"} ou {"start": ..., "list_id": 3} pour une liste existante
+func InstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var body struct {
+		Start    string `json:"start"`
+		ListID   *uint  `json:"list_id"`
+		ListName string `json:"list_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	options := services.TemplateInstantiation{ListID: body.ListID, ListName: body.ListName}
+	if body.Start != "" {
+		if options.Start, err = time.ParseInLocation("2006-01-02", body.Start, time.Local); err != nil {
+			if options.Start, err = time.Parse(time.RFC3339, body.Start); err != nil {
+				rnd.JSON(w, http.StatusBadRequest, renderer.M{
+					"message": "Invalid start date",
+					"error":   err.Error(),
+				})
+				return
+			}
+		}
+	}
+
+	instance, err := templateService.Instantiate(currentUserID(r), uint(id), options)
+	if err != nil {
+		log.Printf("Error instantiating template: %v", err)
+		rnd.JSON(w, templateErrorStatus(err), renderer.M{
+			"message": "Failed to instantiate template",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Template instantiated successfully",
+		"list":    instance.List,
+		"todos":   instance.Todos,
+	})
+}
+
+// SaveListAsTemplate enregistre une liste comme modèle : POST /lists/{id}/template {"name": "Sprint"}
+func SaveListAsTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			log.Printf("Error decoding JSON: %v", err)
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid request payload",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	template, err := templateService.FromList(currentUserID(r), uint(id), body.Name)
+	if err != nil {
+		log.Printf("Error saving list as template: %v", err)
+		rnd.JSON(w, templateErrorStatus(err), renderer.M{
+			"message": "Failed to save template",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message":  "Template created successfully",
+		"template": template,
+	})
+}
@@ -0,0 +1,331 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var timeService services.TimeService
+
+type timeEntryRequest struct {
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      string     `json:"note"`
+}
+
+// timeErrorStatus associe les erreurs du suivi du temps au code HTTP à renvoyer
+func timeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound), errors.Is(err, services.ErrTimeEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrNotEntryOwner):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrNoRunningTimer):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidTimeEntry):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// parseTimeFilter lit ?user=me&list=&from=&to= ; les dates sont au format 2006-01-02 ou RFC 3339
+func parseTimeFilter(r *http.Request) (services.TimeFilter, error) {
+	var filter services.TimeFilter
+	query := r.URL.Query()
+	var err error
+	if filter.UserID, err = parseUserParam(r); err != nil {
+		return filter, err
+	}
+	if param := query.Get("list"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid list %q", param)
+		}
+		listID := uint(id)
+		filter.ListID = &listID
+	}
+	if filter.From, err = parseDateParam(r, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseDateParam(r, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// errOtherUser refuse un ?user= qui n'est pas l'utilisateur courant
+var errOtherUser = errors.New("only your own data can be requested")
+
+// parseUserParam lit ?user=me|{id} ; absent : l'utilisateur courant, seul autorisé
+func parseUserParam(r *http.Request) (uint, error) {
+	param := r.URL.Query().Get("user")
+	switch param {
+	case "", "me":
+		return currentUserID(r), nil
+	}
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid user %q", param)
+	}
+	if uint(id) != currentUserID(r) {
+		return 0, errOtherUser
+	}
+	return uint(id), nil
+}
+
+// filterErrorStatus renvoie 403 pour les données d'un autre utilisateur, 400 sinon
+func filterErrorStatus(err error) int {
+	if errors.Is(err, errOtherUser) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// parseDateParam lit une date au format 2006-01-02 (heure locale) ou RFC 3339 ; absente : zéro
+func parseDateParam(r *http.Request, name string) (time.Time, error) {
+	return parseDateParamIn(r, name, time.Local)
+}
+
+// parseDateParamIn lit une date comme parseDateParam, le format 2006-01-02 étant pris dans loc
+func parseDateParamIn(r *http.Request, name string, loc *time.Location) (time.Time, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", param, loc)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, param); err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s date %q", name, param)
+		}
+	}
+	return t, nil
+}
+
+// StartTimer démarre le chronomètre de l'utilisateur : POST /todo/{id}/timer/start
+func StartTimer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	entry, err := timeService.Start(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error starting timer: %v", err)
+		rnd.JSON(w, timeErrorStatus(err), renderer.M{
+			"message": "Failed to start timer",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Timer started",
+		"entry":   entry,
+	})
+}
+
+// StopTimer arrête le chronomètre de l'utilisateur : POST /todo/{id}/timer/stop
+func StopTimer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	entry, err := timeService.Stop(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error stopping timer: %v", err)
+		rnd.JSON(w, timeErrorStatus(err), renderer.M{
+			"message": "Failed to stop timer",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Timer stopped",
+		"entry":   entry,
+	})
+}
+
+// ListTimeEntries retourne les périodes d'un todo et leur total : GET /todo/{id}/time
+func ListTimeEntries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	entries, err := timeService.List(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching time entries: %v", err)
+		rnd.JSON(w, timeErrorStatus(err), renderer.M{
+			"message": "Failed to fetch time entries",
+			"error":   err.Error(),
+		})
+		return
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.Seconds
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data":          entries,
+		"total_seconds": total,
+	})
+}
+
+// CreateTimeEntry saisit une période à la main : POST /todo/{id}/time
+// {"started_at": "...", "ended_at": "...", "note": "..."}
+func CreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	var req timeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	entry, err := timeService.Create(models.TimeEntry{
+		TodoID:    uint(id),
+		UserID:    currentUserID(r),
+		StartedAt: req.StartedAt,
+		EndedAt:   req.EndedAt,
+		Note:      req.Note,
+	})
+	if err != nil {
+		log.Printf("Error creating time entry: %v", err)
+		rnd.JSON(w, timeErrorStatus(err), renderer.M{
+			"message": "Failed to save time entry",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Time entry created successfully",
+		"entry":   entry,
+	})
+}
+
+// UpdateTimeEntry corrige une période de l'utilisateur : PUT /time/{id}
+func UpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	var patch services.TimeEntryPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	entry, err := timeService.Update(currentUserID(r), uint(id), patch)
+	if err != nil {
+		log.Printf("Error updating time entry: %v", err)
+		rnd.JSON(w, timeErrorStatus(err), renderer.M{
+			"message": "Failed to update time entry",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Time entry updated successfully",
+		"entry":   entry,
+	})
+}
+
+func DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := timeService.Delete(currentUserID(r), uint(id)); err != nil {
+		switch timeErrorStatus(err) {
+		case http.StatusNotFound:
+			http.Error(w, "Time entry not found", http.StatusNotFound)
+		case http.StatusForbidden:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Error deleting time entry: %v", err)
+			http.Error(w, "Failed to delete time entry", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Time entry deleted successfully"))
+}
+
+// TimeTotals retourne le temps passé par todo, liste ou utilisateur :
+// GET /time/totals?group=todo|list|user&user=me&list=3&from=2024-08-01&to=2024-09-01
+func TimeTotals(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTimeFilter(r)
+	if err != nil {
+		rnd.JSON(w, filterErrorStatus(err), renderer.M{
+			"message": "Invalid filter",
+			"error":   err.Error(),
+		})
+		return
+	}
+	group := r.URL.Query().Get("group")
+	if group != "" && group != services.GroupByTodo && group != services.GroupByList && group != services.GroupByUser {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid group, expected todo, list or user",
+		})
+		return
+	}
+	totals, err := timeService.Totals(filter, group)
+	if err != nil {
+		log.Printf("Error computing time totals: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to compute time totals",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": totals,
+	})
+}
+
+// Timesheet exporte les périodes en CSV : GET /time/timesheet.csv, mêmes filtres que /time/totals
+func Timesheet(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseTimeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), filterErrorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="timesheet.csv"`)
+	w.WriteHeader(http.StatusOK)
+	if err := timeService.Timesheet(w, filter); err != nil {
+		// Les en-têtes sont déjà envoyés : on ne peut que journaliser l'erreur
+		log.Printf("Error exporting timesheet: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package Controllers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,9 +9,13 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/notifications"
 	"github.com/go-todo1/services"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -22,6 +27,7 @@ import (
 var rnd *renderer.Render
 var todoService services.TodoService
 var Database *gorm.DB
+var dispatcher *events.Dispatcher
 
 func InitRenderAndDB() {
 	InitDatabase()
@@ -33,7 +39,48 @@ func InitRenderAndDB() {
 	if apiKey == "" {
 		log.Fatal("RAPIDAPI_KEY environment variable not set")
 	}
-	todoService = services.NewTodoServiceImp(Database, apiKey)
+	todos := services.NewTodoServiceImp(Database, apiKey)
+	if err := todos.EnsurePositions(); err != nil {
+		log.Fatalf("Failed to assign todo positions: %v", err)
+	}
+	todoService = todos
+	userService = services.NewUserServiceImp(Database)
+	tagService = services.NewTagServiceImp(Database)
+	listService = services.NewListServiceImp(Database)
+	commentService = services.NewCommentServiceImp(Database)
+	teamService = services.NewTeamServiceImp(Database)
+	timeService = services.NewTimeServiceImp(Database)
+	focusService = services.NewFocusServiceImp(Database)
+	statsService = services.NewStatsServiceImp(Database)
+	templateService = services.NewTemplateServiceImp(Database)
+	archiveService = services.NewArchiveServiceImp(Database)
+	integrationService = services.NewIntegrationServiceImp(Database, viper.GetString("APP_URL"))
+	calendarService = services.NewCalendarServiceImp(Database, todoService)
+	search := services.NewSearchServiceImp(Database)
+	if err := search.EnsureIndex(); err != nil {
+		log.Fatalf("Failed to create search index: %v", err)
+	}
+	searchService = search
+	webhookService = services.NewWebhookServiceImp(Database)
+	store, err := newBlobStore()
+	if err != nil {
+		log.Fatalf("Failed to open attachment store: %v", err)
+	}
+	if size := viper.GetInt64("ATTACHMENT_MAX_SIZE"); size > 0 {
+		attachmentMaxSize = size
+	}
+	attachmentService = services.NewAttachmentServiceImp(Database, store, attachmentMaxSize)
+	mailIngestService = services.NewMailIngestServiceImp(Database, todoService, attachmentService)
+	inbox = notifications.NewInbox(Database)
+	if mailer, err = newMailer(); err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+
+	// Les abonnés reçoivent les événements de domaine écrits dans l'outbox
+	dispatcher = events.NewDispatcher(Database)
+	dispatcher.Subscribe("webhooks", webhookService.Enqueue)
+	dispatcher.Subscribe("attachments", attachmentService.Purge) // fichiers des todos supprimés
+	dispatcher.Subscribe("chat", integrationService.Announce)
 }
 
 func InitDatabase() {
@@ -74,13 +121,18 @@ func InitDatabase() {
 		}
 	}
 
-	if err := Database.AutoMigrate(&models.TodoModel{}); err != nil {
+	if err := Database.AutoMigrate(&models.TodoModel{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.UserModel{}, &models.TagModel{}, &models.TodoDependency{}, &models.ListModel{}, &models.WorkflowStatus{}, &models.CommentModel{}, &models.TodoActivity{}, &models.NotificationModel{}, &models.AttachmentModel{}, &models.TimeEntry{}, &models.FocusSession{}, &models.CustomField{}, &models.CustomFieldValue{}, &models.TemplateModel{}, &models.NotificationPreference{}, &models.ChatIntegration{}, &models.ChatDelivery{}); err != nil {
 		log.Fatalf("Failed to auto-migrate database: %v", err)
 	}
 
 	log.Println("Database connected and tables ensured")
 }
 
+// RunEventDispatcher distribue les événements de l'outbox aux abonnés jusqu'à l'annulation du contexte
+func RunEventDispatcher(ctx context.Context) {
+	dispatcher.Run(ctx, time.Second)
+}
+
 func GetRenderer() *renderer.Render {
 	return rnd
 }
@@ -99,11 +151,93 @@ func (m *MockRenderer) Template(w http.ResponseWriter, status int, files []strin
 	return err
 }
 
+// FetchTodos liste les todos dans l'ordre manuel ; ?tags=a,b&match=any|all filtre
+// par étiquettes, ?list=3&status=in_review par liste et colonne (list=0 : sans liste),
+// ?assignee=me|none|42 par responsable (me : tous les todos assignés à l'utilisateur,
+// quel que soit leur propriétaire), ?sort=priority trie par priorité, ?tree=true
+// imbrique les sous-tâches et ?include=archived ajoute les todos archivés
 func FetchTodos(w http.ResponseWriter, r *http.Request) {
-	var todos []models.TodoModel
-	if err := Database.Find(&todos).Error; err != nil {
+	filter := services.TodoFilter{
+		UserID:   currentUserID(r),
+		Sort:     r.URL.Query().Get("sort"),
+		Tree:     r.URL.Query().Get("tree") == "true",
+		Status:   r.URL.Query().Get("status"),
+		Archived: includeArchived(r),
+	}
+	if param := r.URL.Query().Get("list"); param != "" {
+		listID, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid list",
+			})
+			return
+		}
+		id := uint(listID)
+		filter.ListID = &id
+	}
+	if param := r.URL.Query().Get("assignee"); param != "" {
+		var assignee uint
+		switch param {
+		case "me":
+			assignee = currentUserID(r)
+			if assignee == 0 {
+				rnd.JSON(w, http.StatusBadRequest, renderer.M{
+					"message": "assignee=me requires the X-User-ID header",
+				})
+				return
+			}
+		case "none":
+		default:
+			id, err := strconv.ParseUint(param, 10, 64)
+			if err != nil || id == 0 {
+				rnd.JSON(w, http.StatusBadRequest, renderer.M{
+					"message": "Invalid assignee, expected me, none or a user ID",
+				})
+				return
+			}
+			assignee = uint(id)
+		}
+		filter.Assignee = &assignee
+	}
+	for _, name := range strings.Split(r.URL.Query().Get("tags"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+	// ?field=story_points>=3&field=env=prod : conditions sur les champs personnalisés, toutes requises
+	for _, param := range r.URL.Query()["field"] {
+		cond, err := services.ParseFieldCondition(param)
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "Invalid field filter",
+				"error":   err.Error(),
+			})
+			return
+		}
+		filter.Fields = append(filter.Fields, cond)
+	}
+	switch r.URL.Query().Get("match") {
+	case "", "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid match, expected any or all",
+		})
+		return
+	}
+
+	if filter.Sort != "" && filter.Sort != services.SortPosition && filter.Sort != services.SortPriority && !services.IsFieldSort(filter.Sort) {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid sort, expected position, priority or field.{name}",
+		})
+		return
+	}
+
+	todos, err := todoService.List(filter)
+	if err != nil {
 		log.Printf("Error fetching todos: %v", err)
-		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+		rnd.JSON(w, todoErrorStatus(err), renderer.M{
 			"message": "Failed to fetch todos",
 			"error":   err.Error(),
 		})
@@ -145,10 +279,16 @@ func CreateTodo(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	t.UserID = currentUserID(r)
+	t.ActorID = t.UserID
+	// ?parse=true : le titre est une saisie rapide ("Payer la facture demain 17h #finance")
+	if r.URL.Query().Get("parse") == "true" && !applyQuickAdd(w, r, &t) {
+		return
+	}
 	createdTodo, err := todoService.Create(t)
 	if err != nil {
 		log.Printf("Error creating todo: %v", err)
-		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+		rnd.JSON(w, todoErrorStatus(err), renderer.M{
 			"message": "Failed to save todo",
 			"error":   err.Error(),
 		})
@@ -169,9 +309,11 @@ func DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	if err := todoService.Delete(uint(id)); err != nil {
+	if err := todoService.Delete(currentUserID(r), uint(id)); err != nil {
 		log.Printf("Error deleting todo: %v", err)
-		if err.Error() == "database error" {
+		if errors.Is(err, services.ErrTodoNotFound) {
+			http.Error(w, "Todo not found", http.StatusNotFound)
+		} else if err.Error() == "database error" {
 			http.Error(w, "database error", http.StatusInternalServerError)
 		} else {
 			http.Error(w, "Failed to delete todo", http.StatusInternalServerError)
@@ -193,7 +335,7 @@ func UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var t models.TodoModel
+	var t services.TodoPatch
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 		log.Printf("Error decoding JSON: %v", err)
 		rnd.JSON(w, http.StatusBadRequest, renderer.M{
@@ -203,12 +345,13 @@ func UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Updating Todo with ID: %d and Data: %+v", id, t)
+	t.ActorID = currentUserID(r)
+	log.Printf("Updating Todo with ID: %d and Data: %+v", id, t.TodoModel)
 
-	updatedTodo, err := todoService.Update(uint(id), t)
+	updatedTodo, err := todoService.Update(currentUserID(r), uint(id), t)
 	if err != nil {
 		log.Printf("Error updating todo: %v", err)
-		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+		rnd.JSON(w, todoErrorStatus(err), renderer.M{
 			"message": "Failed to update todo",
 			"error":   err.Error(),
 		})
@@ -221,6 +364,97 @@ func UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// todoErrorStatus associe les erreurs métier du service au code HTTP à renvoyer
+func todoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTodoNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidParent), errors.Is(err, services.ErrMaxDepth), errors.Is(err, services.ErrTagNotFound), errors.Is(err, services.ErrInvalidTag),
+		errors.Is(err, services.ErrInvalidRecurrence), errors.Is(err, services.ErrInvalidDependency),
+		errors.Is(err, services.ErrListNotFound), errors.Is(err, services.ErrInvalidStatus), errors.Is(err, services.ErrAssigneeNotFound),
+		errors.Is(err, services.ErrInvalidEstimate), errors.Is(err, services.ErrInvalidFieldValue),
+		errors.Is(err, services.ErrInvalidFieldFilter):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAssigneeWork):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrIncompleteChildren), errors.Is(err, services.ErrOpenDependencies),
+		errors.Is(err, services.ErrDependencyCycle), errors.Is(err, services.ErrInvalidTransition):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// TodoGraph retourne le graphe de dépendances d'un todo : GET /todo/{id}/graph
+func TodoGraph(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	graph, err := todoService.Graph(currentUserID(r), uint(id))
+	if err != nil {
+		log.Printf("Error fetching dependency graph: %v", err)
+		rnd.JSON(w, todoErrorStatus(err), renderer.M{
+			"message": "Failed to fetch dependency graph",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": graph,
+	})
+}
+
+type moveRequest struct {
+	Before uint `json:"before"` // placer le todo juste avant ce todo
+	After  uint `json:"after"`  // ou juste après celui-ci
+}
+
+// MoveTodo change la position d'un todo : POST /todo/{id}/move {"before": 3} ou {"after": 3}
+func MoveTodo(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	moved, err := todoService.Move(currentUserID(r), uint(id), req.Before, req.After)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrTodoNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrInvalidAnchor):
+			status = http.StatusBadRequest
+		}
+		log.Printf("Error moving todo: %v", err)
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to move todo",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Todo moved successfully",
+		"todo":    moved,
+	})
+}
+
 // Nouvelle fonction pour obtenir une citation depuis l'API RapidAPI
 func GetQuoteHandler(w http.ResponseWriter, r *http.Request) {
 	// Appeler la méthode GetQuote du service
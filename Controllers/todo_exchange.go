@@ -0,0 +1,98 @@
+package Controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-todo1/exchange"
+	"github.com/thedevsaddam/renderer"
+)
+
+const maxImportSize = 10 << 20
+
+func ExportTodos(w http.ResponseWriter, r *http.Request) {
+	format, err := exchange.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid format",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", exchange.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	if err := todoService.Export(currentUserID(r), w, format); err != nil {
+		// Les en-têtes sont déjà envoyés : on ne peut que journaliser l'erreur
+		log.Printf("Error exporting todos: %v", err)
+	}
+}
+
+// TodoTxt expose les todos de l'utilisateur au format todo.txt (lecture seule) pour les outils en ligne de commande
+func TodoTxt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", exchange.ContentType(exchange.FormatTodoTxt))
+	w.WriteHeader(http.StatusOK)
+	if err := todoService.Export(currentUserID(r), w, exchange.FormatTodoTxt); err != nil {
+		log.Printf("Error exporting todo.txt: %v", err)
+	}
+}
+
+func ImportTodos(w http.ResponseWriter, r *http.Request) {
+	format, err := exchange.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid format",
+			"error":   err.Error(),
+		})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			rnd.JSON(w, http.StatusBadRequest, renderer.M{
+				"message": "The file field is required",
+				"error":   err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := todoService.Import(currentUserID(r), body, format, dryRun)
+	if err != nil {
+		log.Printf("Error importing todos: %v", err)
+		status := http.StatusInternalServerError
+		var maxBytesError *http.MaxBytesError
+		var docErr *exchange.DocumentError
+		switch {
+		case errors.As(err, &maxBytesError):
+			status = http.StatusRequestEntityTooLarge
+		case errors.As(err, &docErr), errors.Is(err, exchange.ErrUnknownFormat):
+			status = http.StatusBadRequest
+		}
+		rnd.JSON(w, status, renderer.M{
+			"message": "Failed to import todos",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "Todos imported successfully"
+	if dryRun {
+		message = "Dry run, no todo was saved"
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": message,
+		"report":  report,
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	models "github.com/go-todo1/Models"
 	"github.com/go-todo1/mocks"
+	"github.com/go-todo1/services"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/thedevsaddam/renderer"
@@ -137,12 +138,13 @@ func TestDeleteTodo(t *testing.T) {
 			id:   "1",
 			request: func() *http.Request {
 				req, _ := http.NewRequest("DELETE", "/todos/1", nil)
+				req.Header.Set("X-User-ID", "3")
 				return req
 			},
 			setup: func() {
 				ctrl := gomock.NewController(t)
 				todoServiceMock := mocks.NewMockTodoService(ctrl)
-				todoServiceMock.EXPECT().Delete(gomock.Eq(uint(1))).Return(nil)
+				todoServiceMock.EXPECT().Delete(gomock.Eq(uint(3)), gomock.Eq(uint(1))).Return(nil)
 				todoService = todoServiceMock
 			},
 			checkResult: func(rr *httptest.ResponseRecorder) {
@@ -160,7 +162,7 @@ func TestDeleteTodo(t *testing.T) {
 			setup: func() {
 				ctrl := gomock.NewController(t)
 				todoServiceMock := mocks.NewMockTodoService(ctrl)
-				todoServiceMock.EXPECT().Delete(gomock.Eq(uint(1))).Return(errors.New("database error"))
+				todoServiceMock.EXPECT().Delete(gomock.Eq(uint(0)), gomock.Eq(uint(1))).Return(errors.New("database error"))
 				todoService = todoServiceMock
 			},
 			checkResult: func(rr *httptest.ResponseRecorder) {
@@ -168,6 +170,24 @@ func TestDeleteTodo(t *testing.T) {
 				assert.Contains(t, rr.Body.String(), "database error")
 			},
 		},
+		{
+			name: "another user's todo",
+			id:   "1",
+			request: func() *http.Request {
+				req, _ := http.NewRequest("DELETE", "/todos/1", nil)
+				req.Header.Set("X-User-ID", "4")
+				return req
+			},
+			setup: func() {
+				ctrl := gomock.NewController(t)
+				todoServiceMock := mocks.NewMockTodoService(ctrl)
+				todoServiceMock.EXPECT().Delete(gomock.Eq(uint(4)), gomock.Eq(uint(1))).Return(services.ErrTodoNotFound)
+				todoService = todoServiceMock
+			},
+			checkResult: func(rr *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -187,6 +207,96 @@ func TestDeleteTodo(t *testing.T) {
 	}
 }
 
+func TestMoveTodo(t *testing.T) {
+	rnd = renderer.New(renderer.Options{})
+
+	testCases := []struct {
+		name        string
+		body        string
+		setup       func(m *mocks.MockTodoService)
+		checkResult func(rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "success",
+			body: `{"before": 2}`,
+			setup: func(m *mocks.MockTodoService) {
+				m.EXPECT().Move(uint(0), uint(1), uint(2), uint(0)).Return(models.TodoModel{ID: 1, Position: "00000001i"}, nil)
+			},
+			checkResult: func(rr *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Contains(t, rr.Body.String(), "00000001i")
+			},
+		},
+		{
+			name: "invalid anchor",
+			body: `{}`,
+			setup: func(m *mocks.MockTodoService) {
+				m.EXPECT().Move(uint(0), uint(1), uint(0), uint(0)).Return(models.TodoModel{}, services.ErrInvalidAnchor)
+			},
+			checkResult: func(rr *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+			},
+		},
+		{
+			name: "not found",
+			body: `{"after": 2}`,
+			setup: func(m *mocks.MockTodoService) {
+				m.EXPECT().Move(uint(0), uint(1), uint(0), uint(2)).Return(models.TodoModel{}, services.ErrTodoNotFound)
+			},
+			checkResult: func(rr *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rr.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			todoServiceMock := mocks.NewMockTodoService(ctrl)
+			tc.setup(todoServiceMock)
+			todoService = todoServiceMock
+
+			rr := httptest.NewRecorder()
+			router := chi.NewRouter()
+			router.Post("/todos/{id}/move", MoveTodo)
+			req, _ := http.NewRequest("POST", "/todos/1/move", strings.NewReader(tc.body))
+			router.ServeHTTP(rr, req)
+			tc.checkResult(rr)
+		})
+	}
+}
+
+func TestTodoGraph(t *testing.T) {
+	rnd = renderer.New(renderer.Options{})
+	ctrl := gomock.NewController(t)
+	todoServiceMock := mocks.NewMockTodoService(ctrl)
+	todoServiceMock.EXPECT().Graph(uint(0), uint(2)).Return(services.DependencyGraph{
+		Root:  2,
+		Nodes: []services.GraphNode{{ID: 1, Title: "Design"}, {ID: 2, Title: "Build", Blocked: true}},
+		Edges: []services.GraphEdge{{From: 2, To: 1}},
+	}, nil)
+	todoServiceMock.EXPECT().Graph(uint(0), uint(9)).Return(services.DependencyGraph{}, services.ErrTodoNotFound)
+	todoService = todoServiceMock
+
+	router := chi.NewRouter()
+	router.Get("/todos/{id}/graph", TodoGraph)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/todos/2/graph", nil)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Data services.DependencyGraph `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, []services.GraphEdge{{From: 2, To: 1}}, body.Data.Edges)
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/todos/9/graph", nil)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
 func TestUpdateTodo(t *testing.T) {
 	rnd = renderer.New(renderer.Options{})
 	testCases := []struct {
@@ -207,9 +317,10 @@ func TestUpdateTodo(t *testing.T) {
 			setup: func() {
 				ctrl := gomock.NewController(t)
 				todoServiceMock := mocks.NewMockTodoService(ctrl)
-				todoServiceMock.EXPECT().Update(uint(1), models.TodoModel{
-					Title:     "Updated Title",
-					Completed: true,
+				completed := true
+				todoServiceMock.EXPECT().Update(uint(0), uint(1), services.TodoPatch{
+					TodoModel: models.TodoModel{Title: "Updated Title"},
+					Completed: &completed,
 				}).Return(models.TodoModel{
 					ID:        1,
 					Title:     "Updated Title",
@@ -233,9 +344,10 @@ func TestUpdateTodo(t *testing.T) {
 			setup: func() {
 				ctrl := gomock.NewController(t)
 				todoServiceMock := mocks.NewMockTodoService(ctrl)
-				todoServiceMock.EXPECT().Update(gomock.Eq(uint(1)), gomock.Eq(models.TodoModel{
-					Title:     "Updated Title",
-					Completed: true,
+				completed := true
+				todoServiceMock.EXPECT().Update(gomock.Eq(uint(0)), gomock.Eq(uint(1)), gomock.Eq(services.TodoPatch{
+					TodoModel: models.TodoModel{Title: "Updated Title"},
+					Completed: &completed,
 				})).Return(models.TodoModel{}, fmt.Errorf("database error"))
 				todoService = todoServiceMock
 			},
@@ -244,6 +356,27 @@ func TestUpdateTodo(t *testing.T) {
 				assert.Contains(t, rr.Body.String(), "Failed to update todo")
 			},
 		},
+		{
+			name: "incomplete subtasks",
+			id:   "1",
+			request: func() *http.Request {
+				body := `{"completed":true}`
+				req, _ := http.NewRequest("PUT", "/todos/1", strings.NewReader(body))
+				return req
+			},
+			setup: func() {
+				ctrl := gomock.NewController(t)
+				todoServiceMock := mocks.NewMockTodoService(ctrl)
+				completed := true
+				todoServiceMock.EXPECT().Update(uint(0), uint(1), services.TodoPatch{Completed: &completed}).
+					Return(models.TodoModel{}, services.ErrIncompleteChildren)
+				todoService = todoServiceMock
+			},
+			checkResult: func(rr *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusConflict, rr.Code)
+				assert.Contains(t, rr.Body.String(), "incomplete subtasks")
+			},
+		},
 	}
 
 	for _, tc := range testCases {
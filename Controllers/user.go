@@ -0,0 +1,102 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var userService services.UserService
+
+// currentUserID identifie l'utilisateur par l'en-tête X-User-ID ; 0 si absent ou invalide.
+// L'application n'a pas d'authentification propre, l'en-tête est posé par le proxy frontal.
+func currentUserID(r *http.Request) uint {
+	id, err := strconv.ParseUint(r.Header.Get("X-User-ID"), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+func CreateUser(w http.ResponseWriter, r *http.Request) {
+	var u models.UserModel
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	created, err := userService.Create(u)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Failed to save user",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "User created successfully",
+		"user":    created,
+	})
+}
+
+func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, err := userService.Get(currentUserID(r))
+	if err != nil {
+		renderUserError(w, err)
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"user": user,
+	})
+}
+
+func RegenerateFeedToken(w http.ResponseWriter, r *http.Request) {
+	user, err := userService.RegenerateFeedToken(currentUserID(r))
+	if err != nil {
+		renderUserError(w, err)
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Feed token regenerated",
+		"user":    user,
+	})
+}
+
+// RegenerateMailToken change l'adresse de réception des e-mails : POST /users/me/mail-token
+func RegenerateMailToken(w http.ResponseWriter, r *http.Request) {
+	user, err := userService.RegenerateMailToken(currentUserID(r))
+	if err != nil {
+		renderUserError(w, err)
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"message": "Mail token regenerated",
+		"user":    user,
+	})
+}
+
+func renderUserError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrUserNotFound) {
+		rnd.JSON(w, http.StatusNotFound, renderer.M{
+			"message": "User not found",
+		})
+		return
+	}
+	log.Printf("Error fetching user: %v", err)
+	rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+		"message": "Failed to fetch user",
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,133 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/thedevsaddam/renderer"
+)
+
+var webhookService services.WebhookService
+
+// RunWebhookWorker livre les webhooks en attente jusqu'à l'annulation du contexte
+func RunWebhookWorker(ctx context.Context) {
+	worker, ok := webhookService.(*services.WebhookServiceImp)
+	if !ok {
+		return
+	}
+	worker.Run(ctx, 5*time.Second)
+}
+
+func ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := webhookService.ListSubscriptions(currentUserID(r))
+	if err != nil {
+		log.Printf("Error fetching webhooks: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch webhooks",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": subs,
+	})
+}
+
+// webhookRequest accepte un secret choisi par le client, que WebhookSubscription ne lit pas
+type webhookRequest struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook enregistre l'abonnement ; son secret n'est renvoyé que dans cette réponse
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding JSON: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+	sub := req.WebhookSubscription
+	sub.Secret = req.Secret
+	sub.UserID = currentUserID(r)
+	if sub.URL == "" {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "The url is required",
+		})
+		return
+	}
+
+	created, err := webhookService.CreateSubscription(sub)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Failed to save webhook",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rnd.JSON(w, http.StatusCreated, renderer.M{
+		"message": "Webhook created successfully",
+		"webhook": created,
+		"secret":  created.Secret,
+	})
+}
+
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := webhookService.DeleteSubscription(currentUserID(r), uint(id)); err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting webhook: %v", err)
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Webhook deleted successfully"))
+}
+
+func ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		rnd.JSON(w, http.StatusBadRequest, renderer.M{
+			"message": "Invalid ID",
+		})
+		return
+	}
+	deliveries, err := webhookService.ListDeliveries(currentUserID(r), uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			rnd.JSON(w, http.StatusNotFound, renderer.M{
+				"message": "Webhook not found",
+			})
+			return
+		}
+		log.Printf("Error fetching webhook deliveries: %v", err)
+		rnd.JSON(w, http.StatusInternalServerError, renderer.M{
+			"message": "Failed to fetch deliveries",
+			"error":   err.Error(),
+		})
+		return
+	}
+	rnd.JSON(w, http.StatusOK, renderer.M{
+		"data": deliveries,
+	})
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Actions de l'historique d'un todo
+const (
+	ActivityCreated    = "created"
+	ActivityUpdated    = "updated"
+	ActivityCompleted  = "completed"
+	ActivityReopened   = "reopened"
+	ActivityArchived   = "archived"
+	ActivityUnarchived = "unarchived"
+)
+
+// Change est l'ancienne et la nouvelle valeur d'un champ
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Changes est stocké en JSON dans une colonne texte
+type Changes map[string]Change
+
+func (c Changes) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *Changes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported changes type %T", value)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// TodoActivity est une entrée de l'historique des modifications d'un todo
+type TodoActivity struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TodoID    uint      `json:"todo_id" gorm:"index;not null"`
+	ActorID   uint      `json:"actor_id"` // 0 si inconnu (import, synchronisation CalDAV)
+	Action    string    `json:"action" gorm:"size:32;not null"`
+	Changes   Changes   `json:"changes" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AttachmentModel décrit un fichier joint à un todo ; le contenu est dans le blobstore sous StorageKey
+type AttachmentModel struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	TodoID      uint      `json:"todo_id" gorm:"index;not null"`
+	UploaderID  uint      `json:"uploader_id"`
+	FileName    string    `json:"file_name" gorm:"size:255;not null"`
+	ContentType string    `json:"content_type" gorm:"size:128"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-" gorm:"size:255;uniqueIndex;not null"`
+	Checksum    string    `json:"checksum" gorm:"size:64"` // sha256 en hexadécimal
+	CreatedAt   time.Time `json:"created_at"`
+}
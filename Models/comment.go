@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CommentModel est un commentaire en markdown sur un todo
+type CommentModel struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TodoID    uint      `json:"todo_id" gorm:"index;not null"`
+	AuthorID  uint      `json:"author_id" gorm:"index"`
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	BodyHTML  string    `json:"body_html" gorm:"-"` // rendu HTML du markdown, voir le package markdown
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Types des champs personnalisés
+const (
+	FieldText     = "text"
+	FieldNumber   = "number"
+	FieldDate     = "date" // valeur au format 2006-01-02
+	FieldEnum     = "enum" // une valeur parmi Options
+	FieldCheckbox = "checkbox"
+)
+
+// FieldOptions est stocké en JSON dans une colonne texte
+type FieldOptions []string
+
+func (o FieldOptions) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+func (o *FieldOptions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported options type %T", value)
+	}
+	return json.Unmarshal(data, o)
+}
+
+func (o FieldOptions) Contains(option string) bool {
+	for _, o := range o {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomField est un champ défini par le propriétaire d'une liste pour ses todos
+type CustomField struct {
+	ID       uint         `json:"id" gorm:"primary_key"`
+	ListID   uint         `json:"list_id" gorm:"uniqueIndex:idx_custom_fields_list_name"`
+	Name     string       `json:"name" gorm:"size:32;not null;uniqueIndex:idx_custom_fields_list_name"` // identifiant, ex. "story_points"
+	Label    string       `json:"label" gorm:"size:64"`
+	Type     string       `json:"type" gorm:"size:16;not null"`
+	Options  FieldOptions `json:"options,omitempty" gorm:"type:text"` // valeurs permises d'un champ enum
+	Required bool         `json:"required"`
+	Position int          `json:"position"`
+}
+
+// CustomFieldValue est la valeur d'un champ pour un todo, dans la colonne de son type
+// (text et enum : TextValue, checkbox : BoolValue) pour filtrer et trier en SQL
+type CustomFieldValue struct {
+	ID          uint    `gorm:"primary_key"`
+	TodoID      uint    `gorm:"not null;uniqueIndex:idx_custom_field_values_todo_field"`
+	FieldID     uint    `gorm:"not null;uniqueIndex:idx_custom_field_values_todo_field;index"`
+	TextValue   *string `gorm:"size:255"`
+	NumberValue *float64
+	DateValue   *time.Time
+	BoolValue   *bool
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// TodoDependency indique que TodoID ne peut pas être terminé avant DependsOnID
+type TodoDependency struct {
+	TodoID      uint      `json:"todo_id" gorm:"primaryKey;autoIncrement:false"`
+	DependsOnID uint      `json:"depends_on_id" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
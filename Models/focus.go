@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Types et états d'une session de concentration
+const (
+	FocusWork  = "work"
+	FocusBreak = "break"
+
+	FocusRunning   = "running"
+	FocusPaused    = "paused"
+	FocusCompleted = "completed"
+)
+
+// FocusSession est une session pomodoro, éventuellement liée à un todo ; le temps de
+// concentration exclut les pauses
+type FocusSession struct {
+	ID              uint       `json:"id" gorm:"primary_key"`
+	UserID          uint       `json:"user_id" gorm:"index"`
+	TodoID          *uint      `json:"todo_id" gorm:"index"`
+	Kind            string     `json:"kind" gorm:"size:16;not null"`
+	State           string     `json:"state" gorm:"size:16;index;not null"`
+	DurationSeconds int64      `json:"duration_seconds"` // durée prévue
+	StartedAt       time.Time  `json:"started_at"`
+	PausedAt        *time.Time `json:"paused_at"`      // début de la pause en cours
+	PausedSeconds   int64      `json:"paused_seconds"` // total des pauses terminées
+	FocusSeconds    int64      `json:"focus_seconds"`  // temps effectif, fixé à la fin de la session
+	Interruptions   int        `json:"interruptions"`  // nombre de pauses d'une session de travail
+	EndedAt         *time.Time `json:"ended_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	RemainingSeconds int64 `json:"remaining_seconds" gorm:"-"` // calculé à la lecture
+}
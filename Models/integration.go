@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// EventDigest abonne une intégration de messagerie au résumé quotidien
+const EventDigest = "digest"
+
+// ChatIntegration publie les événements des todos et un résumé quotidien dans un canal
+// de messagerie, par son webhook entrant
+type ChatIntegration struct {
+	ID           uint       `json:"id" gorm:"primary_key"`
+	UserID       uint       `json:"user_id" gorm:"index"`
+	Name         string     `json:"name" gorm:"size:128;not null"`
+	Format       string     `json:"format" gorm:"size:16;not null"` // "slack", "mattermost" ou "discord"
+	URL          string     `json:"url" gorm:"size:2048;not null"`  // URL du webhook entrant
+	ListID       *uint      `json:"list_id" gorm:"index"`           // liste suivie ; nil : tous les todos de l'utilisateur
+	Events       string     `json:"events" gorm:"size:255"`         // séparés par des virgules, parmi les événements des todos et "digest"
+	DigestTime   string     `json:"digest_time" gorm:"size:5"`      // heure locale du résumé, "09:00" par défaut
+	Timezone     string     `json:"timezone" gorm:"size:64"`        // fuseau IANA du résumé, celui du serveur si vide
+	LastEventID  uint       `json:"-"`                              // dernier événement à la création : les précédents ne sont pas publiés
+	LastDigestAt *time.Time `json:"last_digest_at"`
+	LastError    string     `json:"last_error" gorm:"size:255"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ChatDelivery est la publication d'un événement dans une intégration, envoyée en arrière-plan
+// avec les statuts et les nouvelles tentatives des livraisons de webhooks
+type ChatDelivery struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	IntegrationID uint       `json:"integration_id" gorm:"index;not null"`
+	EventID       uint       `json:"event_id" gorm:"index"`
+	Event         string     `json:"event" gorm:"size:64;not null"`
+	Payload       string     `json:"payload" gorm:"type:longtext;not null"` // corps prêt à envoyer, au format de l'intégration
+	Status        string     `json:"status" gorm:"size:16;index;not null"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ListModel regroupe des todos autour d'un flux de travail (colonnes du tableau kanban)
+type ListModel struct {
+	ID         uint             `json:"id" gorm:"primary_key"`
+	UserID     uint             `json:"user_id" gorm:"index"`
+	Name       string           `json:"name" gorm:"size:128;not null"`
+	Statuses   []WorkflowStatus `json:"statuses" gorm:"foreignKey:ListID"` // colonnes, dans l'ordre de Position
+	Fields     []CustomField    `json:"fields" gorm:"foreignKey:ListID"`   // champs personnalisés, dans l'ordre de Position
+	ArchivedAt *time.Time       `json:"archived_at" gorm:"index"`          // archivée avec ses todos
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// WorkflowStatus est une colonne du flux de travail d'une liste
+type WorkflowStatus struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	ListID      uint   `json:"list_id" gorm:"uniqueIndex:idx_workflow_statuses_list_name"`
+	Name        string `json:"name" gorm:"size:32;not null;uniqueIndex:idx_workflow_statuses_list_name"` // identifiant, ex. "in_review"
+	Label       string `json:"label" gorm:"size:64"`
+	Position    int    `json:"position"`
+	Done        bool   `json:"done"`                        // un todo dans cette colonne est terminé
+	Transitions string `json:"transitions" gorm:"size:255"` // colonnes atteignables séparées par des espaces, vide = toutes
+}
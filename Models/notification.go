@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Types de notification
+const (
+	NotificationMention  = "mention"
+	NotificationAssigned = "assigned"
+)
+
+// États de l'envoi d'une notification par e-mail ; vide : pas d'e-mail
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// NotificationModel est une notification destinée à un utilisateur
+type NotificationModel struct {
+	ID          uint       `json:"id" gorm:"primary_key"`
+	UserID      uint       `json:"user_id" gorm:"index;not null"`
+	Type        string     `json:"type" gorm:"size:32;not null"`
+	ActorID     uint       `json:"actor_id"`
+	TodoID      uint       `json:"todo_id" gorm:"index"`
+	CommentID   *uint      `json:"comment_id"`
+	Message     string     `json:"message" gorm:"size:255"`
+	ReadAt      *time.Time `json:"read_at" gorm:"index"`
+	Hidden      bool       `json:"-"`                                     // absente de la boîte de réception (préférence in_app désactivée)
+	EmailStatus string     `json:"email_status" gorm:"size:16;index"`     // EmailPending, EmailSent, EmailFailed ou vide
+	Attempts    int        `json:"-"`                                     // tentatives d'envoi par e-mail
+	NextEmailAt *time.Time `json:"-"`                                     // prochaine tentative d'envoi
+	EmailError  string     `json:"email_error,omitempty" gorm:"size:255"` // dernière erreur SMTP
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// NotificationPreference choisit les canaux d'un type de notification pour un utilisateur ;
+// sans préférence enregistrée, tous les canaux sont actifs
+type NotificationPreference struct {
+	ID     uint   `json:"-" gorm:"primary_key"`
+	UserID uint   `json:"-" gorm:"uniqueIndex:idx_notification_preferences_user_type;not null"`
+	Type   string `json:"type" gorm:"size:32;uniqueIndex:idx_notification_preferences_user_type;not null"`
+	InApp  bool   `json:"in_app"`
+	Email  bool   `json:"email"`
+}
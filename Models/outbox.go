@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OutboxEvent est un événement de domaine écrit dans la même transaction que la modification
+type OutboxEvent struct {
+	ID            uint       `json:"id" gorm:"primary_key"`
+	Type          string     `json:"type" gorm:"size:64;not null"`
+	TodoID        uint       `json:"todo_id" gorm:"index"`
+	Payload       string     `json:"payload" gorm:"type:longtext;not null"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error" gorm:"type:text"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	DispatchedAt  *time.Time `json:"dispatched_at" gorm:"index"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type TagModel struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_tag_models_user_name"`
+	Name      string    `json:"name" gorm:"size:64;not null;uniqueIndex:idx_tag_models_user_name"`
+	Color     string    `json:"color" gorm:"size:7;not null"` // couleur CSS "#rrggbb"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
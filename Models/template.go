@@ -0,0 +1,57 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TemplateItem est un todo de modèle ; ses sous-tâches sont imbriquées
+type TemplateItem struct {
+	Title           string         `json:"title"`
+	Description     string         `json:"description,omitempty"`
+	Priority        string         `json:"priority,omitempty"`
+	Tags            []string       `json:"tags,omitempty"`            // noms d'étiquettes, créées au besoin
+	DueOffsetDays   *int           `json:"due_offset_days,omitempty"` // échéance en jours après la date de départ
+	EstimateMinutes *int           `json:"estimate_minutes,omitempty"`
+	Subtasks        []TemplateItem `json:"subtasks,omitempty"`
+}
+
+// TemplateItems est stocké en JSON dans une colonne texte
+type TemplateItems []TemplateItem
+
+func (t TemplateItems) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *TemplateItems) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported template items type %T", value)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// TemplateModel décrit une liste et ses todos à recréer à partir d'une date de départ
+type TemplateModel struct {
+	ID          uint          `json:"id" gorm:"primary_key"`
+	UserID      uint          `json:"user_id" gorm:"index"`
+	Name        string        `json:"name" gorm:"size:128;not null"`
+	Description string        `json:"description" gorm:"type:text"`
+	Items       TemplateItems `json:"items" gorm:"type:text"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
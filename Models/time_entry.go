@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// TimeEntry est une période de travail sur un todo ; EndedAt nil : le chronomètre tourne
+type TimeEntry struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	TodoID    uint       `json:"todo_id" gorm:"index;not null"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	StartedAt time.Time  `json:"started_at" gorm:"index"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Seconds   int64      `json:"seconds"` // durée, calculée à l'arrêt ou à la saisie
+	Note      string     `json:"note" gorm:"size:255"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -3,9 +3,43 @@ package models
 import "time"
 
 type TodoModel struct {
-	ID        uint      `json:"id" gorm:"primary_key"`
-	Title     string    `json:"title"`
-	Completed bool      `json:"completed"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id" gorm:"primary_key"`
+	UserID          uint       `json:"user_id" gorm:"index"`
+	UID             string     `json:"uid" gorm:"size:255;index"` // UID iCalendar fourni par un client CalDAV
+	Title           string     `json:"title"`
+	Description     string     `json:"description" gorm:"type:text"`
+	Completed       bool       `json:"completed"`
+	Priority        string     `json:"priority" gorm:"size:1"` // de "A" (la plus haute) à "Z", vide = aucune
+	DueAt           *time.Time `json:"due_at"`
+	CompletedAt     *time.Time `json:"completed_at" gorm:"index"`      // date de fin, base des statistiques
+	Projects        string     `json:"projects" gorm:"size:255"`       // noms séparés par des espaces, sans le "+"
+	Contexts        string     `json:"contexts" gorm:"size:255"`       // noms séparés par des espaces, sans le "@"
+	Position        string     `json:"position" gorm:"size:255;index"` // clé d'ordre manuel, voir le package position
+	ParentID        *uint      `json:"parent_id" gorm:"index"`         // todo parent ; 0 en mise à jour détache le todo
+	Recurrence      string     `json:"recurrence" gorm:"size:255"`     // RRULE RFC 5545 sans le préfixe, voir le package rrule
+	SeriesID        *uint      `json:"series_id" gorm:"index"`         // premier todo de la série récurrente
+	ListID          *uint      `json:"list_id" gorm:"index"`           // liste du todo ; 0 en mise à jour le retire de sa liste
+	Status          string     `json:"status" gorm:"size:32;index"`    // colonne du flux de travail, Completed en découle
+	AssigneeID      *uint      `json:"assignee_id" gorm:"index"`       // utilisateur responsable ; 0 en mise à jour le désassigne
+	EstimateMinutes *int       `json:"estimate_minutes"`               // estimation en minutes ; 0 en mise à jour l'efface
+	ArchivedAt      *time.Time `json:"archived_at" gorm:"index"`       // archivé : masqué des listes sans ?include=archived
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	Tags     []TagModel `json:"tags" gorm:"many2many:todo_tags;joinForeignKey:TodoID;joinReferences:TagID"`
+	TagIDs   []uint     `json:"tag_ids,omitempty" gorm:"-"` // étiquettes à assigner ; nil = inchangées, vide = aucune
+	TagNames []string   `json:"-" gorm:"-"`                 // noms d'étiquettes à assigner à la création, créées au besoin
+
+	Progress         int         `json:"progress" gorm:"-"`                    // pourcentage d'avancement calculé sur les sous-tâches
+	Children         []TodoModel `json:"children,omitempty" gorm:"-"`          // sous-tâches, en réponse arborescente
+	CompleteChildren bool        `json:"complete_children,omitempty" gorm:"-"` // terminer aussi les sous-tâches au lieu de refuser
+	Next             *TodoModel  `json:"next,omitempty" gorm:"-"`              // occurrence créée en terminant un todo récurrent
+
+	DependsOn          []uint `json:"depends_on,omitempty" gorm:"-"`          // todos à terminer avant celui-ci ; nil = inchangées
+	Blocked            bool   `json:"blocked" gorm:"-"`                       // vrai si une dépendance est encore ouverte
+	IgnoreDependencies bool   `json:"ignore_dependencies,omitempty" gorm:"-"` // terminer malgré des dépendances ouvertes
+
+	Fields map[string]interface{} `json:"fields,omitempty" gorm:"-"` // champs personnalisés de la liste par nom ; nil en mise à jour = inchangés
+
+	ActorID uint `json:"-" gorm:"-"` // auteur de la modification, pour l'historique ; posé par le contrôleur
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type UserModel struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	Name      string    `json:"name" gorm:"size:255;not null"`
+	Email     string    `json:"email" gorm:"size:255;uniqueIndex;not null"`
+	Handle    string    `json:"handle" gorm:"size:64;uniqueIndex;not null"`     // identifiant des @mentions
+	FeedToken string    `json:"feed_token" gorm:"size:64;uniqueIndex;not null"` // jeton secret des flux iCal et CalDAV
+	MailToken string    `json:"mail_token" gorm:"size:64;uniqueIndex;not null"` // jeton secret de l'adresse de réception, todo+<jeton>@MAIL_DOMAIN
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
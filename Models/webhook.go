@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Événements du cycle de vie d'un todo (événements de domaine et webhooks)
+const (
+	EventTodoCreated   = "todo.created"
+	EventTodoCompleted = "todo.completed"
+	EventTodoReopened  = "todo.reopened"
+	EventTodoDeleted   = "todo.deleted"
+)
+
+// Statuts possibles d'une livraison de webhook
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	UserID    uint      `json:"user_id" gorm:"index"` // seuls ses todos sont envoyés
+	URL       string    `json:"url" gorm:"size:2048;not null"`
+	Secret    string    `json:"-" gorm:"size:255;not null"` // renvoyé une seule fois, à la création
+	Events    string    `json:"events" gorm:"size:255"`     // liste séparée par des virgules, vide = tous les événements
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primary_key"`
+	SubscriptionID uint       `json:"subscription_id" gorm:"index;not null"`
+	EventID        uint       `json:"event_id" gorm:"index"`
+	Event          string     `json:"event" gorm:"size:64;not null"`
+	Payload        string     `json:"payload" gorm:"type:longtext;not null"`
+	Status         string     `json:"status" gorm:"size:16;index;not null"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"index"`
+	ResponseCode   int        `json:"response_code"`
+	LastError      string     `json:"last_error" gorm:"type:text"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
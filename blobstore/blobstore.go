@@ -0,0 +1,38 @@
+// Package blobstore stocke le contenu binaire des pièces jointes, sur le disque local
+// ou dans un bucket compatible S3 (AWS, MinIO…). Les métadonnées restent en base.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blobstore: object not found")
+	ErrInvalidKey = errors.New("blobstore: invalid key")
+)
+
+// Store est implémenté par Local et S3. Les clés sont des chemins relatifs séparés par "/".
+type Store interface {
+	// Put écrit size octets lus dans r sous la clé, en remplaçant un objet existant
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open retourne le contenu de l'objet ; Seek permet de servir des requêtes Range
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete supprime l'objet ; supprimer un objet absent n'est pas une erreur
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey refuse les clés vides, absolues ou qui remontent l'arborescence
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+// Package chat met en forme les messages envoyés aux webhooks entrants des messageries :
+// Slack et Mattermost (texte et « attachments ») et Discord (« embeds »).
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formats de webhook entrant
+const (
+	Slack      = "slack"
+	Mattermost = "mattermost"
+	Discord    = "discord"
+)
+
+// Couleurs de la barre latérale des messages
+const (
+	ColorInfo    = "#3498db"
+	ColorSuccess = "#2ecc71"
+	ColorWarning = "#e67e22"
+	ColorMuted   = "#95a5a6"
+)
+
+// Limites de Discord, les plus strictes des trois formats
+const (
+	maxContent     = 2000
+	maxTitle       = 256
+	maxDescription = 4096
+	maxFields      = 25
+	maxFieldValue  = 1024
+)
+
+// Formats reconnus par Payload
+var Formats = []string{Slack, Mattermost, Discord}
+
+// Field est une valeur courte affichée en colonne (liste, échéance…)
+type Field struct {
+	Name  string
+	Value string
+	Short bool
+}
+
+// Item est une ligne d'une section, avec un lien facultatif
+type Item struct {
+	Text string
+	URL  string
+}
+
+// Section est une liste à puces précédée d'un titre en gras
+type Section struct {
+	Title string
+	Items []Item
+}
+
+// Message est un message indépendant du format ; le texte n'est pas interprété
+// (les caractères spéciaux de chaque format sont échappés)
+type Message struct {
+	Text     string // résumé en une ligne, utilisé aussi dans les notifications
+	Title    string
+	URL      string // lien du titre
+	Color    string // "#rrggbb"
+	Fields   []Field
+	Sections []Section
+}
+
+// ValidFormat indique si le format est reconnu par Payload
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload retourne le corps JSON à envoyer au webhook entrant du format donné
+func Payload(format string, msg Message) ([]byte, error) {
+	switch format {
+	case Slack, Mattermost:
+		return json.Marshal(slackPayload(format, msg))
+	case Discord:
+		return json.Marshal(discordPayload(msg))
+	}
+	return nil, fmt.Errorf("unknown chat format %q", format)
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Fallback  string       `json:"fallback"`
+	Color     string       `json:"color,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []slackField `json:"fields,omitempty"`
+	MrkdwnIn  []string     `json:"mrkdwn_in,omitempty"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+// slackPayload sert aussi à Mattermost, qui accepte les « attachments » de Slack
+// mais dont le texte est en Markdown standard
+func slackPayload(format string, msg Message) slackMessage {
+	escape, bold, link := slackEscape, func(s string) string { return "*" + s + "*" }, slackLink
+	if format == Mattermost {
+		escape, bold, link = markdownEscape, func(s string) string { return "**" + s + "**" }, markdownLink
+	}
+	attachment := slackAttachment{
+		Fallback:  plain(msg),
+		Color:     msg.Color,
+		Title:     msg.Title,
+		TitleLink: msg.URL,
+		Text:      sections(msg.Sections, escape, bold, link, "• "),
+	}
+	if format == Slack {
+		attachment.Title = slackEscape(msg.Title)
+		attachment.MrkdwnIn = []string{"text"}
+	}
+	for _, f := range msg.Fields {
+		attachment.Fields = append(attachment.Fields, slackField{Title: f.Name, Value: escape(f.Value), Short: f.Short})
+	}
+	return slackMessage{Text: escape(msg.Text), Attachments: []slackAttachment{attachment}}
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []discordField `json:"fields,omitempty"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds,omitempty"`
+}
+
+func discordPayload(msg Message) discordMessage {
+	embed := discordEmbed{
+		Title:       truncate(msg.Title, maxTitle),
+		URL:         msg.URL,
+		Description: truncate(sections(msg.Sections, markdownEscape, func(s string) string { return "**" + s + "**" }, markdownLink, "- "), maxDescription),
+	}
+	if color, err := strconv.ParseInt(strings.TrimPrefix(msg.Color, "#"), 16, 32); err == nil {
+		embed.Color = int(color)
+	}
+	for i, f := range msg.Fields {
+		if i == maxFields {
+			break
+		}
+		embed.Fields = append(embed.Fields, discordField{Name: f.Name, Value: truncate(markdownEscape(f.Value), maxFieldValue), Inline: f.Short})
+	}
+	return discordMessage{Content: truncate(markdownEscape(msg.Text), maxContent), Embeds: []discordEmbed{embed}}
+}
+
+func sections(sections []Section, escape, bold func(string) string, link func(text, url string) string, bullet string) string {
+	var b strings.Builder
+	for _, section := range sections {
+		if len(section.Items) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if section.Title != "" {
+			b.WriteString(bold(escape(section.Title)) + "\n")
+		}
+		for _, item := range section.Items {
+			text := escape(item.Text)
+			if item.URL != "" {
+				text = link(text, item.URL)
+			}
+			b.WriteString(bullet + text + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// plain est la version texte du message, pour les clients qui n'affichent pas les pièces jointes
+func plain(msg Message) string {
+	lines := []string{msg.Text}
+	for _, section := range msg.Sections {
+		for _, item := range section.Items {
+			lines = append(lines, "- "+item.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+func slackLink(text, url string) string {
+	return "<" + url + "|" + text + ">"
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`, "[", `\[`, "]", `\]`)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func markdownLink(text, url string) string {
+	return "[" + text + "](" + url + ")"
+}
+
+// truncate coupe s à n caractères, en terminant par "…"
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
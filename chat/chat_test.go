@@ -0,0 +1,65 @@
+package chat_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-todo1/chat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var message = chat.Message{
+	Text:   "Completed: Ship <v2> & *party*",
+	Title:  "Ship <v2> & *party*",
+	URL:    "https://todo.example.com/#todo-1",
+	Color:  chat.ColorSuccess,
+	Fields: []chat.Field{{Name: "List", Value: "Release_1", Short: true}},
+	Sections: []chat.Section{
+		{Title: "Due today", Items: []chat.Item{{Text: "Write [notes]", URL: "https://todo.example.com/#todo-2"}, {Text: "and 3 more"}}},
+		{Title: "Empty"},
+	},
+}
+
+func decode(t *testing.T, format string) map[string]interface{} {
+	body, err := chat.Payload(format, message)
+	require.NoError(t, err)
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	return payload
+}
+
+func TestSlackPayload(t *testing.T) {
+	payload := decode(t, chat.Slack)
+	assert.Equal(t, "Completed: Ship &lt;v2&gt; &amp; *party*", payload["text"])
+	attachment := payload["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "#2ecc71", attachment["color"])
+	assert.Equal(t, "https://todo.example.com/#todo-1", attachment["title_link"])
+	assert.Equal(t, "*Due today*\n• <https://todo.example.com/#todo-2|Write [notes]>\n• and 3 more", attachment["text"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"title": "List", "value": "Release_1", "short": true}}, attachment["fields"])
+	assert.Contains(t, attachment["fallback"], "- Write [notes]")
+}
+
+func TestMattermostPayload(t *testing.T) {
+	payload := decode(t, chat.Mattermost)
+	assert.Equal(t, `Completed: Ship <v2> & \*party\*`, payload["text"])
+	attachment := payload["attachments"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "**Due today**\n• [Write \\[notes\\]](https://todo.example.com/#todo-2)\n• and 3 more", attachment["text"])
+	assert.Nil(t, attachment["mrkdwn_in"])
+}
+
+func TestDiscordPayload(t *testing.T) {
+	payload := decode(t, chat.Discord)
+	assert.Equal(t, `Completed: Ship <v2> & \*party\*`, payload["content"])
+	embed := payload["embeds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "Ship <v2> & *party*", embed["title"])
+	assert.Equal(t, float64(0x2ecc71), embed["color"])
+	assert.Equal(t, "**Due today**\n- [Write \\[notes\\]](https://todo.example.com/#todo-2)\n- and 3 more", embed["description"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "List", "value": `Release\_1`, "inline": true}}, embed["fields"])
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := chat.Payload("teams", message)
+	assert.Error(t, err)
+	assert.False(t, chat.ValidFormat("teams"))
+}
@@ -0,0 +1,161 @@
+// Package events implémente les événements de domaine des todos : ils sont écrits
+// dans la table outbox par la transaction qui modifie le todo, puis distribués
+// aux abonnés par un Dispatcher (livraison au moins une fois).
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+// Types d'événements de domaine
+const (
+	TodoCreated   = models.EventTodoCreated
+	TodoCompleted = models.EventTodoCompleted
+	TodoReopened  = models.EventTodoReopened
+	TodoDeleted   = models.EventTodoDeleted
+)
+
+const (
+	batchSize   = 100
+	baseBackoff = time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+type Event struct {
+	ID         uint             `json:"id"`
+	Type       string           `json:"type"`
+	TodoID     uint             `json:"todo_id"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Todo       models.TodoModel `json:"todo"`
+}
+
+// Handler reçoit un événement ; une erreur provoque une nouvelle tentative plus tard.
+// Comme la livraison est « au moins une fois », un handler doit être idempotent.
+type Handler func(Event) error
+
+// Record écrit l'événement dans l'outbox avec la transaction tx
+func Record(tx *gorm.DB, eventType string, todo models.TodoModel) error {
+	payload, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxEvent{
+		Type:          eventType,
+		TodoID:        todo.ID,
+		Payload:       string(payload),
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+type Dispatcher struct {
+	Db          *gorm.DB
+	mu          sync.RWMutex
+	subscribers []subscriber
+}
+
+func NewDispatcher(db *gorm.DB) *Dispatcher {
+	return &Dispatcher{Db: db}
+}
+
+// Subscribe enregistre un handler appelé pour chaque événement
+func (d *Dispatcher) Subscribe(name string, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers = append(d.subscribers, subscriber{name: name, handler: h})
+}
+
+// DispatchPending livre les événements en attente, dans l'ordre, et retourne le nombre livré
+func (d *Dispatcher) DispatchPending() (int, error) {
+	var pending []models.OutboxEvent
+	err := d.Db.Where("dispatched_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Order("id").Limit(batchSize).Find(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+
+	d.mu.RLock()
+	subs := append([]subscriber(nil), d.subscribers...)
+	d.mu.RUnlock()
+
+	delivered := 0
+	for i := range pending {
+		row := &pending[i]
+		evt, err := decode(*row)
+		if err == nil {
+			err = deliver(subs, evt)
+		}
+
+		if err != nil {
+			row.Attempts++
+			row.LastError = err.Error()
+			row.NextAttemptAt = time.Now().Add(backoff(row.Attempts))
+			log.Printf("Error dispatching event %d (%s): %v", row.ID, row.Type, err)
+		} else {
+			now := time.Now()
+			row.DispatchedAt = &now
+			row.LastError = ""
+			delivered++
+		}
+		if err := d.Db.Save(row).Error; err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// Run distribue les événements à intervalle régulier jusqu'à l'annulation du contexte
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.DispatchPending(); err != nil {
+				log.Printf("Error dispatching events: %v", err)
+			}
+		}
+	}
+}
+
+func deliver(subs []subscriber, evt Event) error {
+	for _, s := range subs {
+		if err := s.handler(evt); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func decode(row models.OutboxEvent) (Event, error) {
+	evt := Event{ID: row.ID, Type: row.Type, TodoID: row.TodoID, OccurredAt: row.CreatedAt}
+	if err := json.Unmarshal([]byte(row.Payload), &evt.Todo); err != nil {
+		return Event{}, err
+	}
+	return evt, nil
+}
+
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
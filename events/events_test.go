@@ -0,0 +1,81 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func initSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.TodoModel{}, &models.OutboxEvent{}, &models.TodoDependency{}, &models.CommentModel{}, &models.TodoActivity{}, &models.TimeEntry{}, &models.FocusSession{}, &models.CustomField{}, &models.CustomFieldValue{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestDispatchLifecycleEvents(t *testing.T) {
+	db := initSQLiteDB(t)
+	todoService := services.NewTodoServiceImp(db, "")
+	boolPtr := func(b bool) *bool { return &b }
+
+	todo, err := todoService.Create(models.TodoModel{Title: "Prepare standup"})
+	assert.NoError(t, err)
+	_, err = todoService.Update(0, todo.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	_, err = todoService.Update(0, todo.ID, services.TodoPatch{Completed: boolPtr(false)})
+	assert.NoError(t, err)
+	assert.NoError(t, todoService.Delete(0, todo.ID))
+
+	var received []string
+	dispatcher := events.NewDispatcher(db)
+	dispatcher.Subscribe("recorder", func(e events.Event) error {
+		received = append(received, e.Type)
+		assert.Equal(t, todo.ID, e.TodoID)
+		return nil
+	})
+
+	n, err := dispatcher.DispatchPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []string{events.TodoCreated, events.TodoCompleted, events.TodoReopened, events.TodoDeleted}, received)
+
+	// Les événements livrés ne sont plus redistribués
+	n, err = dispatcher.DispatchPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestDispatchRetriesFailedHandler(t *testing.T) {
+	db := initSQLiteDB(t)
+	assert.NoError(t, events.Record(db, events.TodoCreated, models.TodoModel{ID: 7, Title: "Retry me"}))
+
+	dispatcher := events.NewDispatcher(db)
+	dispatcher.Subscribe("failing", func(e events.Event) error {
+		return errors.New("subscriber unavailable")
+	})
+
+	n, err := dispatcher.DispatchPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	var row models.OutboxEvent
+	assert.NoError(t, db.First(&row).Error)
+	assert.Nil(t, row.DispatchedAt)
+	assert.Equal(t, 1, row.Attempts)
+	assert.Equal(t, "failing: subscriber unavailable", row.LastError)
+}
@@ -0,0 +1,142 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+)
+
+var csvHeader = []string{"id", "title", "description", "completed", "priority", "due_at", "completed_at", "projects", "contexts", "created_at", "updated_at"}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvEncoder{w: cw}, nil
+}
+
+func (e *csvEncoder) Encode(todo models.TodoModel) error {
+	return e.w.Write([]string{
+		strconv.FormatUint(uint64(todo.ID), 10),
+		CSVCell(todo.Title),
+		CSVCell(todo.Description),
+		strconv.FormatBool(todo.Completed),
+		todo.Priority,
+		formatOptionalTime(todo.DueAt),
+		formatOptionalTime(todo.CompletedAt),
+		CSVCell(todo.Projects),
+		CSVCell(todo.Contexts),
+		formatTime(todo.CreatedAt),
+		formatTime(todo.UpdatedAt),
+	})
+}
+
+// formulaPrefixes sont les premiers caractères qui font d'une cellule une formule de tableur
+const formulaPrefixes = "=+-@\t\r"
+
+// CSVCell neutralise une cellule qu'un tableur prendrait pour une formule en la préfixant
+// d'une apostrophe ; l'import la retire
+func CSVCell(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// plainCSVCell retire l'apostrophe ajoutée par CSVCell
+func plainCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func decodeCSV(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid CSV document: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("invalid CSV document: missing title column")
+	}
+
+	var records []Record
+	for row := 1; ; row++ {
+		line, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV document: %w", err)
+		}
+		records = append(records, decodeCSVRow(row, columns, line))
+	}
+	return records, nil
+}
+
+func decodeCSVRow(row int, columns map[string]int, line []string) Record {
+	rec := Record{Row: row}
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(line) {
+			return plainCSVCell(strings.TrimSpace(line[i]))
+		}
+		return ""
+	}
+
+	rec.Todo.Title = get("title")
+	if rec.Todo.Title == "" {
+		rec.Err = errors.New("title is required")
+		return rec
+	}
+	if v := get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			rec.Err = fmt.Errorf("invalid completed value %q", v)
+			return rec
+		}
+		rec.Todo.Completed = completed
+	}
+	if p := strings.ToUpper(get("priority")); ValidPriority(p) {
+		rec.Todo.Priority = p
+	} else {
+		rec.Err = fmt.Errorf("invalid priority %q", p)
+		return rec
+	}
+	rec.Todo.Description = get("description")
+	rec.Todo.Projects = get("projects")
+	rec.Todo.Contexts = get("contexts")
+	if rec.Todo.DueAt, rec.Err = parseOptionalTime(get("due_at")); rec.Err != nil {
+		return rec
+	}
+	if rec.Todo.CompletedAt, rec.Err = parseOptionalTime(get("completed_at")); rec.Err != nil {
+		return rec
+	}
+	rec.Todo.CreatedAt, rec.Err = parseTime(get("created_at"))
+	return rec
+}
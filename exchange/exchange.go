@@ -0,0 +1,175 @@
+// Package exchange convertit les todos vers et depuis les formats d'échange
+// JSON, CSV et Markdown utilisés par l'import et l'export.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "md"
+	FormatTodoTxt  = "txt"
+)
+
+var ErrUnknownFormat = errors.New("unknown format, expected json, csv, md or txt")
+
+// DocumentError signale un document illisible dans son ensemble, à distinguer des erreurs
+// d'écriture ou de base de données de l'import
+type DocumentError struct {
+	Err error
+}
+
+func (e *DocumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// Encoder écrit les todos un par un pour pouvoir exporter en flux
+type Encoder interface {
+	Encode(todo models.TodoModel) error
+	Close() error
+}
+
+// Record est une ligne lue lors d'un import ; Err est renseigné si la ligne est invalide
+type Record struct {
+	Row  int
+	Todo models.TodoModel
+	Err  error
+}
+
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// Report décrit le résultat d'un import ; en mode dry-run, Imported et Todos
+// indiquent ce qui aurait été créé.
+type Report struct {
+	Format     string             `json:"format"`
+	DryRun     bool               `json:"dry_run"`
+	Total      int                `json:"total"`
+	Imported   int                `json:"imported"`
+	Duplicates []RowError         `json:"duplicates"`
+	Errors     []RowError         `json:"errors"`
+	Todos      []models.TodoModel `json:"todos"`
+}
+
+// ParseFormat normalise le nom de format (json, csv, md, markdown, txt ou todo.txt)
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatMarkdown, "markdown":
+		return FormatMarkdown, nil
+	case FormatTodoTxt, "todo.txt":
+		return FormatTodoTxt, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType retourne le type MIME correspondant au format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatTodoTxt:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+func NewEncoder(w io.Writer, format string) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return newJSONEncoder(w), nil
+	case FormatCSV:
+		return newCSVEncoder(w)
+	case FormatMarkdown:
+		return newMarkdownEncoder(w)
+	case FormatTodoTxt:
+		return todoTxtEncoder{w: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Decode lit toutes les lignes du format donné. Une erreur, une *DocumentError, n'est
+// retournée que si le document entier est illisible ; les erreurs de ligne sont dans Record.Err.
+func Decode(r io.Reader, format string) ([]Record, error) {
+	var records []Record
+	var err error
+	switch format {
+	case FormatJSON:
+		records, err = decodeJSON(r)
+	case FormatCSV:
+		records, err = decodeCSV(r)
+	case FormatMarkdown:
+		records, err = decodeMarkdown(r)
+	case FormatTodoTxt:
+		records, err = decodeTodoTxt(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, &DocumentError{Err: err}
+	}
+	return records, nil
+}
+
+// DuplicateKey retourne la clé utilisée pour détecter les doublons (titre normalisé)
+func DuplicateKey(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func parseOptionalTime(s string) (*time.Time, error) {
+	t, err := parseTime(s)
+	if err != nil || t.IsZero() {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+// ValidPriority indique si p est une priorité todo.txt, de "A" à "Z", ou vide
+func ValidPriority(p string) bool {
+	return p == "" || (len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z')
+}
@@ -0,0 +1,117 @@
+package exchange_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/exchange"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2024, 7, 25, 10, 0, 0, 0, time.UTC)
+	todos := []models.TodoModel{
+		{ID: 1, Title: "Learn Go", Completed: true, CreatedAt: created},
+		{ID: 2, Title: `Write "quoted", report`, CreatedAt: created},
+	}
+
+	for _, format := range []string{exchange.FormatJSON, exchange.FormatCSV, exchange.FormatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := exchange.NewEncoder(&buf, format)
+			assert.NoError(t, err)
+			for _, todo := range todos {
+				assert.NoError(t, enc.Encode(todo))
+			}
+			assert.NoError(t, enc.Close())
+
+			records, err := exchange.Decode(&buf, format)
+			assert.NoError(t, err)
+			assert.Len(t, records, 2)
+			for i, rec := range records {
+				assert.NoError(t, rec.Err)
+				assert.Equal(t, todos[i].Title, rec.Todo.Title)
+				assert.Equal(t, todos[i].Completed, rec.Todo.Completed)
+				assert.Zero(t, rec.Todo.ID)
+			}
+		})
+	}
+}
+
+func TestCSVFormulaCells(t *testing.T) {
+	var buf bytes.Buffer
+	enc, err := exchange.NewEncoder(&buf, exchange.FormatCSV)
+	assert.NoError(t, err)
+	assert.NoError(t, enc.Encode(models.TodoModel{ID: 1, Title: `=HYPERLINK("http://evil")`, Description: "-2+3", Projects: "@home"}))
+	assert.NoError(t, enc.Close())
+	assert.Contains(t, buf.String(), `"'=HYPERLINK(""http://evil"")",'-2+3`)
+	assert.Contains(t, buf.String(), "'@home")
+
+	records, err := exchange.Decode(&buf, exchange.FormatCSV)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, `=HYPERLINK("http://evil")`, records[0].Todo.Title, "the import removes the apostrophe")
+	assert.Equal(t, "-2+3", records[0].Todo.Description)
+}
+
+func TestDecodeRowErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		input   string
+		errors  map[int]string
+		records int
+	}{
+		{
+			name:    "json",
+			format:  exchange.FormatJSON,
+			input:   `[{"title":"ok"},{"title":""},{"title":"bad","completed":"yes"}]`,
+			errors:  map[int]string{2: "title is required", 3: "cannot unmarshal"},
+			records: 3,
+		},
+		{
+			name:    "csv",
+			format:  exchange.FormatCSV,
+			input:   "title,completed\nok,false\n,true\nbad,maybe\n",
+			errors:  map[int]string{2: "title is required", 3: `invalid completed value "maybe"`},
+			records: 3,
+		},
+		{
+			name:    "markdown",
+			format:  exchange.FormatMarkdown,
+			input:   "# Todos\n\n- [ ] ok\nsome text\n- [x] done\n",
+			errors:  map[int]string{4: "not a task list item"},
+			records: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			records, err := exchange.Decode(strings.NewReader(tc.input), tc.format)
+			assert.NoError(t, err)
+			assert.Len(t, records, tc.records)
+			for _, rec := range records {
+				if msg, ok := tc.errors[rec.Row]; ok {
+					assert.ErrorContains(t, rec.Err, msg)
+				} else {
+					assert.NoError(t, rec.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidDocument(t *testing.T) {
+	_, err := exchange.Decode(strings.NewReader(`{"title":"not an array"}`), exchange.FormatJSON)
+	var docErr *exchange.DocumentError
+	assert.ErrorAs(t, err, &docErr)
+
+	_, err = exchange.Decode(strings.NewReader("name\nfoo\n"), exchange.FormatCSV)
+	assert.EqualError(t, err, "invalid CSV document: missing title column")
+
+	_, err = exchange.ParseFormat("xml")
+	assert.Equal(t, exchange.ErrUnknownFormat, err)
+}
@@ -0,0 +1,112 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	models "github.com/go-todo1/Models"
+)
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONEncoder(w io.Writer) *jsonEncoder {
+	return &jsonEncoder{w: w}
+}
+
+func (e *jsonEncoder) Encode(todo models.TodoModel) error {
+	b, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.count == 0 {
+		sep = "[\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+func decodeJSON(r io.Reader) ([]Record, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("invalid JSON document: expected an array of todos")
+	}
+
+	var records []Record
+	for row := 1; dec.More(); row++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON document: %w", err)
+		}
+		records = append(records, decodeJSONRow(row, raw))
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+	return records, nil
+}
+
+func decodeJSONRow(row int, raw json.RawMessage) Record {
+	rec := Record{Row: row}
+	var fields struct {
+		Title       *string `json:"title"`
+		Description string  `json:"description"`
+		Completed   *bool   `json:"completed"`
+		Priority    string  `json:"priority"`
+		DueAt       string  `json:"due_at"`
+		CompletedAt string  `json:"completed_at"`
+		Projects    string  `json:"projects"`
+		Contexts    string  `json:"contexts"`
+		CreatedAt   string  `json:"created_at"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		rec.Err = err
+		return rec
+	}
+	if fields.Title == nil || *fields.Title == "" {
+		rec.Err = errors.New("title is required")
+		return rec
+	}
+	rec.Todo.Title = *fields.Title
+	rec.Todo.Description = fields.Description
+	if fields.Completed != nil {
+		rec.Todo.Completed = *fields.Completed
+	}
+	if !ValidPriority(fields.Priority) {
+		rec.Err = fmt.Errorf("invalid priority %q", fields.Priority)
+		return rec
+	}
+	rec.Todo.Priority = fields.Priority
+	rec.Todo.Projects = fields.Projects
+	rec.Todo.Contexts = fields.Contexts
+	if rec.Todo.DueAt, rec.Err = parseOptionalTime(fields.DueAt); rec.Err != nil {
+		return rec
+	}
+	if rec.Todo.CompletedAt, rec.Err = parseOptionalTime(fields.CompletedAt); rec.Err != nil {
+		return rec
+	}
+	rec.Todo.CreatedAt, rec.Err = parseTime(fields.CreatedAt)
+	return rec
+}
@@ -0,0 +1,65 @@
+package exchange
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+)
+
+var taskItem = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+?)\s*$`)
+
+type markdownEncoder struct {
+	w io.Writer
+}
+
+func newMarkdownEncoder(w io.Writer) (*markdownEncoder, error) {
+	if _, err := io.WriteString(w, "# Todos\n\n"); err != nil {
+		return nil, err
+	}
+	return &markdownEncoder{w: w}, nil
+}
+
+func (e *markdownEncoder) Encode(todo models.TodoModel) error {
+	box := " "
+	if todo.Completed {
+		box = "x"
+	}
+	title := strings.Join(strings.Fields(todo.Title), " ")
+	_, err := fmt.Fprintf(e.w, "- [%s] %s\n", box, title)
+	return err
+}
+
+func (e *markdownEncoder) Close() error {
+	return nil
+}
+
+// decodeMarkdown lit une liste de tâches GitHub ; titres et lignes vides sont ignorés
+func decodeMarkdown(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		rec := Record{Row: line}
+		m := taskItem.FindStringSubmatch(text)
+		if m == nil {
+			rec.Err = errors.New("not a task list item")
+		} else {
+			rec.Todo.Title = m[2]
+			rec.Todo.Completed = m[1] != " "
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid Markdown document: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,34 @@
+package exchange
+
+import (
+	"fmt"
+	"io"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/todotxt"
+)
+
+type todoTxtEncoder struct {
+	w io.Writer
+}
+
+func (e todoTxtEncoder) Encode(todo models.TodoModel) error {
+	_, err := fmt.Fprintln(e.w, todotxt.FromTodo(todo).String())
+	return err
+}
+
+func (e todoTxtEncoder) Close() error {
+	return nil
+}
+
+func decodeTodoTxt(r io.Reader) ([]Record, error) {
+	lines, err := todotxt.ParseAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid todo.txt document: %w", err)
+	}
+	records := make([]Record, 0, len(lines))
+	for _, line := range lines {
+		records = append(records, Record{Row: line.Row, Todo: line.Task.ToTodo(), Err: line.Err})
+	}
+	return records, nil
+}
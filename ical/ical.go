@@ -0,0 +1,334 @@
+// Package ical convertit les todos en composants VTODO iCalendar (RFC 5545) et inversement.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+)
+
+const (
+	prodID        = "-//go-todo1//Todo//EN"
+	uidDomain     = "go-todo1"
+	dateLayout    = "20060102"
+	dateTimeUTC   = "20060102T150405Z"
+	dateTimeLocal = "20060102T150405"
+	maxLineOctets = 75
+)
+
+var derivedUID = regexp.MustCompile(`^todo-(\d+)@` + uidDomain + `$`)
+
+// UID retourne l'identifiant iCalendar d'un todo
+func UID(todo models.TodoModel) string {
+	if todo.UID != "" {
+		return todo.UID
+	}
+	return fmt.Sprintf("todo-%d@%s", todo.ID, uidDomain)
+}
+
+// TodoIDFromUID retourne l'ID d'un todo dont l'UID a été dérivé par UID
+func TodoIDFromUID(uid string) (uint, bool) {
+	m := derivedUID.FindStringSubmatch(uid)
+	if m == nil {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(m[1], 10, 64)
+	return uint(id), err == nil
+}
+
+// ETag retourne une étiquette qui change à chaque modification du todo
+func ETag(todo models.TodoModel) string {
+	return fmt.Sprintf(`"%d-%d"`, todo.ID, todo.UpdatedAt.UnixNano())
+}
+
+// WriteCalendar écrit un VCALENDAR contenant un VTODO par todo
+func WriteCalendar(w io.Writer, name string, todos []models.TodoModel) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + prodID)
+	lw.line("CALSCALE:GREGORIAN")
+	if name != "" {
+		lw.line("X-WR-CALNAME:" + escape(name))
+	}
+	for _, todo := range todos {
+		writeTodo(lw, todo)
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+func writeTodo(lw *lineWriter, todo models.TodoModel) {
+	lw.line("BEGIN:VTODO")
+	lw.line("UID:" + escape(UID(todo)))
+	lw.line("DTSTAMP:" + todo.UpdatedAt.UTC().Format(dateTimeUTC))
+	if !todo.CreatedAt.IsZero() {
+		lw.line("CREATED:" + todo.CreatedAt.UTC().Format(dateTimeUTC))
+	}
+	if !todo.UpdatedAt.IsZero() {
+		lw.line("LAST-MODIFIED:" + todo.UpdatedAt.UTC().Format(dateTimeUTC))
+	}
+	lw.line("SUMMARY:" + escape(todo.Title))
+	if todo.Description != "" {
+		lw.line("DESCRIPTION:" + escape(todo.Description))
+	}
+	if todo.Completed {
+		lw.line("STATUS:COMPLETED")
+		if todo.CompletedAt != nil {
+			lw.line("COMPLETED:" + todo.CompletedAt.UTC().Format(dateTimeUTC))
+		}
+		lw.line("PERCENT-COMPLETE:100")
+	} else {
+		lw.line("STATUS:NEEDS-ACTION")
+	}
+	if todo.DueAt != nil {
+		lw.line("DUE:" + todo.DueAt.UTC().Format(dateTimeUTC))
+	}
+	if p := priorityToICal(todo.Priority); p != 0 {
+		lw.line("PRIORITY:" + strconv.Itoa(p))
+	}
+	if todo.Recurrence != "" {
+		lw.line("RRULE:" + todo.Recurrence)
+	}
+	if categories := strings.Fields(todo.Projects); len(categories) > 0 {
+		for i := range categories {
+			categories[i] = escape(categories[i])
+		}
+		lw.line("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	lw.line("END:VTODO")
+}
+
+// ParseTodo lit le premier VTODO d'un objet iCalendar et retourne le todo et son UID
+func ParseTodo(r io.Reader) (models.TodoModel, string, error) {
+	var todo models.TodoModel
+	var uid string
+	lines, err := unfold(r)
+	if err != nil {
+		return todo, "", err
+	}
+
+	inTodo, found := false, false
+	for _, raw := range lines {
+		name, params, value, ok := splitProperty(raw)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			if found {
+				return todo, "", errors.New("only one VTODO per resource is supported")
+			}
+			inTodo, found = true, true
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO"):
+			inTodo = false
+			continue
+		}
+		if !inTodo {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			uid = unescape(value)
+		case "SUMMARY":
+			todo.Title = unescape(value)
+		case "DESCRIPTION":
+			todo.Description = unescape(value)
+		case "STATUS":
+			todo.Completed = strings.EqualFold(value, "COMPLETED")
+		case "COMPLETED":
+			t, err := parseDateTime(value, params)
+			if err != nil {
+				return todo, "", err
+			}
+			todo.CompletedAt = &t
+		case "DUE":
+			t, err := parseDateTime(value, params)
+			if err != nil {
+				return todo, "", err
+			}
+			todo.DueAt = &t
+		case "PRIORITY":
+			p, err := strconv.Atoi(value)
+			if err != nil {
+				return todo, "", fmt.Errorf("invalid PRIORITY %q", value)
+			}
+			todo.Priority = priorityFromICal(p)
+		case "RRULE":
+			todo.Recurrence = value
+		case "CATEGORIES":
+			var categories []string
+			for _, c := range splitEscaped(value) {
+				if c = strings.Join(strings.Fields(unescape(c)), "-"); c != "" {
+					categories = append(categories, c)
+				}
+			}
+			todo.Projects = strings.Join(categories, " ")
+		}
+	}
+
+	if !found {
+		return todo, "", errors.New("no VTODO component found")
+	}
+	if uid == "" {
+		return todo, "", errors.New("VTODO has no UID")
+	}
+	if todo.Title == "" {
+		return todo, "", errors.New("VTODO has no SUMMARY")
+	}
+	if !todo.Completed {
+		todo.CompletedAt = nil
+	}
+	return todo, uid, nil
+}
+
+// priorityToICal fait correspondre A→1, B→5, C et au-delà→9 (RFC 5545 : 1 la plus haute)
+func priorityToICal(p string) int {
+	switch {
+	case p == "":
+		return 0
+	case p == "A":
+		return 1
+	case p == "B":
+		return 5
+	}
+	return 9
+}
+
+func priorityFromICal(p int) string {
+	switch {
+	case p <= 0:
+		return ""
+	case p <= 4:
+		return "A"
+	case p == 5:
+		return "B"
+	}
+	return "C"
+}
+
+func parseDateTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		return time.Parse(dateLayout, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(dateTimeUTC, value)
+	}
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLocal, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date-time %q", value)
+	}
+	return t, nil
+}
+
+// unfold lit les lignes de contenu en recollant les lignes pliées (RFC 5545 §3.1)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty découpe "NOM;PARAM=val:VALEUR" en tenant compte des guillemets
+func splitProperty(line string) (string, map[string]string, string, bool) {
+	inQuote := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuote = !inQuote
+		} else if c == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+func splitEscaped(value string) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			cur.WriteByte(value[i])
+			cur.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(value[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// lineWriter écrit des lignes terminées par CRLF, pliées à 75 octets sans couper un caractère UTF-8
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			n = 0
+			limit = maxLineOctets - 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
@@ -0,0 +1,81 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/ical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	due := time.Date(2024, 8, 3, 17, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 8, 2, 9, 30, 0, 0, time.UTC)
+	todo := models.TodoModel{
+		ID:          12,
+		Title:       "Call mom; then dad, about the trip",
+		Completed:   true,
+		CompletedAt: &completed,
+		DueAt:       &due,
+		Priority:    "A",
+		Projects:    "family travel",
+		Recurrence:  "FREQ=MONTHLY;BYDAY=1SA",
+		UpdatedAt:   completed,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, ical.WriteCalendar(&buf, "Todos", []models.TodoModel{todo}))
+	out := buf.String()
+	assert.Contains(t, out, "UID:todo-12@go-todo1\r\n")
+	assert.Contains(t, out, `SUMMARY:Call mom\; then dad\, about the trip`)
+	assert.Contains(t, out, "STATUS:COMPLETED\r\n")
+	assert.Contains(t, out, "PRIORITY:1\r\n")
+	assert.Contains(t, out, "RRULE:FREQ=MONTHLY;BYDAY=1SA\r\n")
+
+	parsed, uid, err := ical.ParseTodo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "todo-12@go-todo1", uid)
+	assert.Equal(t, todo.Title, parsed.Title)
+	assert.True(t, parsed.Completed)
+	assert.True(t, due.Equal(*parsed.DueAt))
+	assert.True(t, completed.Equal(*parsed.CompletedAt))
+	assert.Equal(t, "A", parsed.Priority)
+	assert.Equal(t, "family travel", parsed.Projects)
+	assert.Equal(t, todo.Recurrence, parsed.Recurrence)
+
+	id, ok := ical.TodoIDFromUID(uid)
+	assert.True(t, ok)
+	assert.Equal(t, uint(12), id)
+}
+
+func TestLineFolding(t *testing.T) {
+	var buf bytes.Buffer
+	todo := models.TodoModel{ID: 1, Title: strings.Repeat("é", 100)}
+	assert.NoError(t, ical.WriteCalendar(&buf, "", []models.TodoModel{todo}))
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+
+	parsed, _, err := ical.ParseTodo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, todo.Title, parsed.Title)
+}
+
+func TestParseClientTodo(t *testing.T) {
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:2f1c7e\r\nSUMMARY:Renew\r\n  passport\r\n" +
+		"DUE;VALUE=DATE:20240901\r\nPRIORITY:5\r\nCATEGORIES:admin,home office\r\nSTATUS:NEEDS-ACTION\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	todo, uid, err := ical.ParseTodo(strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, "2f1c7e", uid)
+	assert.Equal(t, "Renew passport", todo.Title)
+	assert.Equal(t, "2024-09-01", todo.DueAt.Format("2006-01-02"))
+	assert.Equal(t, "B", todo.Priority)
+	assert.Equal(t, "admin home-office", todo.Projects)
+	assert.False(t, todo.Completed)
+
+	_, _, err = ical.ParseTodo(strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+	assert.EqualError(t, err, "no VTODO component found")
+}
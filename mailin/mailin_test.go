@@ -0,0 +1,205 @@
+package mailin_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-todo1/mailin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer démarre le serveur sur un port libre et retourne son adresse
+func startServer(t *testing.T, server *mailin.Server) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return listener.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	var mu sync.Mutex
+	var received []mailin.Envelope
+	server := &mailin.Server{
+		MaxSize: 1024,
+		Accept:  func(rcpt string) bool { return strings.EqualFold(rcpt, "todo@example.com") },
+		Handler: func(ctx context.Context, env mailin.Envelope) error {
+			if env.From == "spammer@example.com" {
+				return &mailin.Error{Code: 550, Message: "5.7.1 Unknown sender"}
+			}
+			if env.From == "flaky@example.com" {
+				return errors.New("database is down")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, env)
+			return nil
+		},
+	}
+	addr := startServer(t, server)
+
+	body := "Subject: Hello\r\n\r\nFirst line\r\n.leading dot\r\n"
+	require.NoError(t, smtp.SendMail(addr, nil, "alice@example.com", []string{"TODO@example.com"}, []byte(body)))
+	mu.Lock()
+	require.Len(t, received, 1)
+	assert.Equal(t, "alice@example.com", received[0].From)
+	assert.Equal(t, []string{"TODO@example.com"}, received[0].To)
+	assert.Equal(t, "Subject: Hello\n\nFirst line\n.leading dot\n", string(received[0].Data), "dot-stuffing is undone")
+	mu.Unlock()
+
+	err := smtp.SendMail(addr, nil, "alice@example.com", []string{"someone@example.com"}, []byte(body))
+	assert.ErrorContains(t, err, "550")
+	err = smtp.SendMail(addr, nil, "spammer@example.com", []string{"todo@example.com"}, []byte(body))
+	assert.ErrorContains(t, err, "Unknown sender")
+	err = smtp.SendMail(addr, nil, "flaky@example.com", []string{"todo@example.com"}, []byte(body))
+	assert.ErrorContains(t, err, "451")
+	err = smtp.SendMail(addr, nil, "alice@example.com", []string{"todo@example.com"}, []byte(body+strings.Repeat("x", 2048)))
+	assert.ErrorContains(t, err, "552")
+
+	mu.Lock()
+	assert.Len(t, received, 1)
+	mu.Unlock()
+}
+
+func TestServerMaxSessions(t *testing.T) {
+	addr := startServer(t, &mailin.Server{
+		MaxSessions: 1,
+		Handler:     func(ctx context.Context, env mailin.Envelope) error { return nil },
+	})
+	banner := func(conn net.Conn) string {
+		line, err := textproto.NewReader(bufio.NewReader(conn)).ReadLine()
+		require.NoError(t, err)
+		return line
+	}
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(banner(first), "220 "))
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+	assert.True(t, strings.HasPrefix(banner(second), "421 "), "the session limit is reached")
+
+	first.Close()
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		defer conn.Close()
+		line, err := textproto.NewReader(bufio.NewReader(conn)).ReadLine()
+		return err == nil && strings.HasPrefix(line, "220 ")
+	}, 2*time.Second, 10*time.Millisecond, "a closed session frees its slot")
+}
+
+const forwarded = "From: =?utf-8?q?Alice_B=C3=A9raud?= <Alice@Example.com>\r\n" +
+	"To: todo@example.com\r\n" +
+	"Subject: =?utf-8?q?Fwd:_Facture_d=C3=A9cembre?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Voir la facture ci-jointe, =E0 payer avant vendredi.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Voir la facture</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"facture.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename*=UTF-8''facture%20d%C3%A9cembre.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"JSVFT0YK\r\n" +
+	"--outer--\r\n"
+
+func TestParse(t *testing.T) {
+	msg, err := mailin.Parse(strings.NewReader(forwarded))
+	require.NoError(t, err)
+	assert.Equal(t, "Alice@Example.com", msg.From.Address)
+	assert.Equal(t, "Alice Béraud", msg.From.Name)
+	assert.Equal(t, "Fwd: Facture décembre", msg.Subject)
+	assert.Equal(t, "Voir la facture ci-jointe, à payer avant vendredi.", msg.Text, "text/plain is preferred over HTML")
+	require.Len(t, msg.Attachments, 1, "the inline logo is skipped")
+	assert.Equal(t, "facture décembre.pdf", msg.Attachments[0].Filename)
+	assert.Equal(t, "application/pdf", msg.Attachments[0].ContentType)
+	assert.Equal(t, "%PDF-1.4\n%%EOF\n", string(msg.Attachments[0].Data))
+}
+
+func TestParseHTMLOnly(t *testing.T) {
+	raw := "From: bob@example.com\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<html><head><style>p { color: red }</style></head><body>" +
+		"<p>Call   the <b>plumber</b></p><p>Tom &amp; Jerry</p><script>alert(1)</script></body></html>"
+	msg, err := mailin.Parse(strings.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "Call the plumber\nTom & Jerry", msg.Text)
+	assert.Empty(t, msg.Subject)
+}
+
+func TestParseAttachedMessage(t *testing.T) {
+	raw := "From: bob@example.com\r\n" +
+		"Subject: FW: Outage\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"See below.\r\n" +
+		"--b\r\n" +
+		"Content-Type: message/rfc822\r\n" +
+		"\r\n" +
+		"From: ops@example.com\r\n" +
+		"Subject: Outage\r\n" +
+		"\r\n" +
+		"The database is down.\r\n" +
+		"--b--\r\n"
+	msg, err := mailin.Parse(strings.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", msg.From.Address)
+	assert.Equal(t, "See below.\n\nThe database is down.", msg.Text)
+	assert.Empty(t, msg.Attachments)
+}
+
+func TestParseNestedMessagesDepth(t *testing.T) {
+	nest := func(levels int) string {
+		raw := "Subject: Innermost\r\n\r\nHello\r\n"
+		for i := 0; i < levels; i++ {
+			raw = "Subject: Fwd\r\nContent-Type: message/rfc822\r\n\r\n" + raw
+		}
+		return raw
+	}
+	msg, err := mailin.Parse(strings.NewReader(nest(5)))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", msg.Text)
+	_, err = mailin.Parse(strings.NewReader(nest(50)))
+	assert.ErrorContains(t, err, "too many nested parts", "attached messages count towards the nesting limit")
+}
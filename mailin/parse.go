@@ -0,0 +1,196 @@
+package mailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxDepth = 10 // imbrication maximale des parties multipart et des messages joints
+
+// Attachment est une pièce jointe décodée
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message est le contenu utile d'un e-mail
+type Message struct {
+	From        *mail.Address // nil si l'en-tête From est absent ou invalide
+	Subject     string
+	Text        string // parties text/plain ; à défaut, les parties HTML sans balises
+	Attachments []Attachment
+}
+
+// Parse décode un message RFC 5322 : en-têtes encodés (RFC 2047), parties multipart,
+// base64 et quoted-printable, UTF-8 et Latin-1. Un message transféré en pièce jointe
+// (message/rfc822) apporte son texte et ses pièces jointes.
+func Parse(r io.Reader) (Message, error) {
+	return parse(r, 0)
+}
+
+// parse décode un message situé à la profondeur depth : un message joint reprend
+// l'imbrication de la partie qui le contient, et non à zéro
+func parse(r io.Reader, depth int) (Message, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if from, err := m.Header.AddressList("From"); err == nil && len(from) > 0 {
+		msg.From = from[0]
+	}
+	msg.Subject = decodeHeader(m.Header.Get("Subject"))
+
+	var p parts
+	if err := p.walk(textproto.MIMEHeader(m.Header), m.Body, depth); err != nil {
+		return Message{}, err
+	}
+	msg.Text = strings.TrimSpace(strings.ReplaceAll(strings.Join(p.plain, "\n\n"), "\r\n", "\n"))
+	if msg.Text == "" {
+		msg.Text = htmlToText(strings.Join(p.html, "\n"))
+	}
+	msg.Attachments = p.attachments
+	return msg, nil
+}
+
+type parts struct {
+	plain       []string
+	html        []string
+	attachments []Attachment
+}
+
+func (p *parts) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return errors.New("mailin: too many nested parts")
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart() // le décodage est fait ici pour toutes les parties
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeBody(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case mediaType == "message/rfc822" && filename == "":
+		inner, err := parse(bytes.NewReader(data), depth+1)
+		if err != nil {
+			return err
+		}
+		if inner.Text != "" {
+			p.plain = append(p.plain, inner.Text)
+		}
+		p.attachments = append(p.attachments, inner.Attachments...)
+	case header.Get("Content-Id") != "" && disposition != "attachment":
+		// image intégrée au corps HTML (logo d'une signature…), ignorée
+	case filename != "" || disposition == "attachment":
+		if filename == "" {
+			filename = "attachment"
+		}
+		p.attachments = append(p.attachments, Attachment{Filename: filename, ContentType: mediaType, Data: data})
+	case mediaType == "text/plain":
+		p.plain = append(p.plain, toUTF8(params["charset"], data))
+	case mediaType == "text/html":
+		p.html = append(p.html, toUTF8(params["charset"], data))
+	}
+	return nil
+}
+
+func decodeBody(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body) // les fins de ligne sont ignorées
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(toUTF8(charset, data)), nil
+	},
+}
+
+// decodeHeader décode les mots encodés (=?utf-8?q?...?=) ; la valeur brute est gardée en cas d'erreur
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// toUTF8 convertit le Latin-1 ; l'UTF-8 et les jeux inconnus sont gardés, octets invalides remplacés
+func toUTF8(charset string, data []byte) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso-8859-15", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+var (
+	htmlInvisible = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBreak     = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])\s*>`)
+	htmlTag       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText garde le texte d'un corps HTML, un paragraphe par ligne
+func htmlToText(s string) string {
+	s = htmlInvisible.ReplaceAllString(s, "")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,279 @@
+// Package mailin reçoit des e-mails par SMTP et en extrait le sujet, le texte et les pièces
+// jointes. Le serveur ne relaie rien et ne propose ni TLS ni authentification : il doit
+// écouter sur une interface interne (127.0.0.1 par défaut), derrière le MTA. L'expéditeur
+// étant falsifiable, Accept doit reconnaître les destinataires, par exemple une adresse
+// secrète par utilisateur, plutôt que de se fier à From.
+package mailin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxSize     = 25 << 20
+	DefaultMaxSessions = 20 // chaque session peut garder un message de MaxSize en mémoire
+	maxRecipients      = 100
+	commandTimeout     = 5 * time.Minute
+)
+
+// Envelope est un message reçu, tel que transmis par le serveur expéditeur
+type Envelope struct {
+	From string   // expéditeur de l'enveloppe (MAIL FROM), vide pour un avis de non-remise
+	To   []string // destinataires acceptés (RCPT TO)
+	Data []byte   // message brut RFC 5322
+}
+
+// Handler traite un message reçu. Une *Error choisit la réponse SMTP ; toute autre erreur
+// donne une erreur temporaire 451 et le serveur expéditeur réessaiera plus tard.
+type Handler func(ctx context.Context, env Envelope) error
+
+// Error est une réponse SMTP d'erreur, par exemple {550, "5.7.1 Unknown sender"}
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return strconv.Itoa(e.Code) + " " + e.Message
+}
+
+type Server struct {
+	Addr        string                 // adresse d'écoute, "127.0.0.1:2525" par exemple
+	Domain      string                 // nom annoncé dans la bannière, "localhost" par défaut
+	MaxSize     int64                  // taille maximale d'un message, DefaultMaxSize par défaut
+	MaxSessions int                    // connexions simultanées, DefaultMaxSessions par défaut
+	Accept      func(rcpt string) bool // destinataires acceptés ; nil : tous
+	Handler     Handler
+}
+
+// ListenAndServe écoute sur Addr jusqu'à l'annulation de ctx
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepte les connexions de l'écouteur jusqu'à l'annulation de ctx, qui le ferme.
+// Au-delà de MaxSessions connexions simultanées, les suivantes reçoivent un refus temporaire 421.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	sessions := make(chan struct{}, s.maxSessions())
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		select {
+		case sessions <- struct{}{}:
+			go func() {
+				defer func() { <-sessions }()
+				s.serveConn(ctx, conn)
+			}()
+		default:
+			conn.SetDeadline(time.Now().Add(time.Second))
+			textproto.NewConn(conn).PrintfLine("421 4.3.2 Too many connections, try again later")
+			conn.Close()
+		}
+	}
+}
+
+// session est l'état d'une connexion SMTP
+type session struct {
+	server *Server
+	text   *textproto.Conn
+	helo   bool
+	from   *string
+	to     []string
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	sess := &session{server: s, text: textproto.NewConn(conn)}
+	sess.reply(220, s.domain()+" ESMTP ready")
+	for {
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			sess.reset()
+			sess.helo = true
+			sess.reply(250, s.domain())
+		case "EHLO":
+			sess.reset()
+			sess.helo = true
+			sess.text.PrintfLine("250-%s", s.domain())
+			sess.text.PrintfLine("250-SIZE %d", s.maxSize())
+			sess.text.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			sess.mail(arg)
+		case "RCPT":
+			sess.rcpt(arg)
+		case "DATA":
+			sess.data(ctx)
+		case "RSET":
+			sess.reset()
+			sess.reply(250, "2.0.0 OK")
+		case "NOOP":
+			sess.reply(250, "2.0.0 OK")
+		case "VRFY":
+			sess.reply(252, "2.5.0 Cannot verify user")
+		case "QUIT":
+			sess.reply(221, "2.0.0 Bye")
+			return
+		default:
+			sess.reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (sess *session) reply(code int, message string) {
+	sess.text.PrintfLine("%d %s", code, message)
+}
+
+func (sess *session) reset() {
+	sess.from, sess.to = nil, nil
+}
+
+func (sess *session) mail(arg string) {
+	if !sess.helo {
+		sess.reply(503, "5.5.1 Send HELO or EHLO first")
+		return
+	}
+	if sess.from != nil {
+		sess.reply(503, "5.5.1 Sender already specified")
+		return
+	}
+	from, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, param := range params {
+		if name, value, _ := strings.Cut(param, "="); strings.EqualFold(name, "SIZE") {
+			if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > sess.server.maxSize() {
+				sess.reply(552, "5.3.4 Message too big")
+				return
+			}
+		}
+	}
+	sess.from = &from
+	sess.reply(250, "2.1.0 OK")
+}
+
+func (sess *session) rcpt(arg string) {
+	if sess.from == nil {
+		sess.reply(503, "5.5.1 Send MAIL first")
+		return
+	}
+	to, _, ok := parsePath(arg, "TO:")
+	if !ok || to == "" {
+		sess.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+	if len(sess.to) >= maxRecipients {
+		sess.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+	if accept := sess.server.Accept; accept != nil && !accept(to) {
+		sess.reply(550, "5.1.1 No such recipient")
+		return
+	}
+	sess.to = append(sess.to, to)
+	sess.reply(250, "2.1.5 OK")
+}
+
+func (sess *session) data(ctx context.Context) {
+	if len(sess.to) == 0 {
+		sess.reply(503, "5.5.1 Send RCPT first")
+		return
+	}
+	sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+	limit := sess.server.maxSize()
+	dot := sess.text.DotReader()
+	data, err := io.ReadAll(io.LimitReader(dot, limit+1))
+	if err != nil {
+		return
+	}
+	if int64(len(data)) > limit {
+		io.Copy(io.Discard, dot)
+		sess.reset()
+		sess.reply(552, "5.3.4 Message too big")
+		return
+	}
+
+	env := Envelope{From: *sess.from, To: sess.to, Data: data}
+	sess.reset()
+	err = sess.server.Handler(ctx, env)
+	var smtpErr *Error
+	switch {
+	case err == nil:
+		sess.reply(250, "2.0.0 Message accepted")
+	case errors.As(err, &smtpErr):
+		sess.reply(smtpErr.Code, smtpErr.Message)
+	default:
+		log.Printf("Error handling incoming email from %q: %v", env.From, err)
+		sess.reply(451, "4.3.0 Temporary failure, try again later")
+	}
+}
+
+func (s *Server) domain() string {
+	if s.Domain == "" {
+		return "localhost"
+	}
+	return s.Domain
+}
+
+func (s *Server) maxSessions() int {
+	if s.MaxSessions <= 0 {
+		return DefaultMaxSessions
+	}
+	return s.MaxSessions
+}
+
+func (s *Server) maxSize() int64 {
+	if s.MaxSize <= 0 {
+		return DefaultMaxSize
+	}
+	return s.MaxSize
+}
+
+// parsePath lit "FROM:<a@example.com> SIZE=123" et retourne l'adresse et les paramètres
+func parsePath(arg, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(arg[len(prefix):])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	path := fields[0]
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", nil, false
+	}
+	return path[1 : len(path)-1], fields[1:], true
+}
@@ -17,13 +17,43 @@ const port = ":9000"
 
 func main() { // point d'entrée
 	controllers.InitRenderAndDB() // Initialise le moteur de rendu et la base de données pour les contrôleurs
-	stopChan := make(chan os.Signal)
+	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt)
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go controllers.RunEventDispatcher(workerCtx)    // Distribution des événements de l'outbox
+	go controllers.RunWebhookWorker(workerCtx)      // Livraison des webhooks en arrière-plan
+	go controllers.RunArchiveWorker(workerCtx)      // Archivage des todos terminés depuis longtemps
+	go controllers.RunNotificationMailer(workerCtx) // Envoi des notifications par e-mail
+	go controllers.RunChatDigests(workerCtx)        // Résumés quotidiens des intégrations de messagerie
+	go controllers.RunChatDeliveries(workerCtx)     // Publications des événements dans les messageries
+	go controllers.RunMailIngest(workerCtx)         // Réception des e-mails transférés, transformés en todos
+
+	// Méthodes WebDAV utilisées par les clients CalDAV
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)         // Ajoute un middleware au routeur
-	r.Get("/", homeHandler)          // Enregistre la route de la page d'accueil
+	r.Use(middleware.Logger) // Ajoute un middleware au routeur
+	r.Get("/", homeHandler)  // Enregistre la route de la page d'accueil
+	r.Get("/todo.txt", controllers.TodoTxt)
 	r.Mount("/todo", todoHandlers()) // Sous-routeur pour les TODOs
+	r.Mount("/webhooks", webhookHandlers())
+	r.Mount("/integrations", integrationHandlers())
+	r.Mount("/users", userHandlers())
+	r.Mount("/tags", tagHandlers())
+	r.Mount("/lists", listHandlers())
+	r.Mount("/templates", templateHandlers())
+	r.Mount("/comments", commentHandlers())
+	r.Mount("/notifications", notificationHandlers())
+	r.Mount("/attachments", attachmentHandlers())
+	r.Get("/team/workload", controllers.TeamWorkload)
+	r.Mount("/time", timeHandlers())
+	r.Mount("/focus", focusHandlers())
+	r.Mount("/stats", statsHandlers())
+	r.Get("/ical/{feed}", controllers.ICalFeed) // Flux iCalendar : /ical/{jeton}.ics
+	r.Mount("/caldav", caldavHandlers())
 
 	srv := &http.Server{
 		Addr:         port,
@@ -42,6 +72,7 @@ func main() { // point d'entrée
 
 	<-stopChan
 	log.Println("shutting down server...")
+	stopWorkers()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	srv.Shutdown(ctx)
@@ -57,9 +88,157 @@ func todoHandlers() http.Handler {
 		r.Post("/", controllers.CreateTodo)
 		r.Put("/{id}", controllers.UpdateTodo)
 		r.Delete("/{id}", controllers.DeleteTodo)
+		r.Post("/{id}/move", controllers.MoveTodo)
+		r.Post("/{id}/archive", controllers.ArchiveTodo)
+		r.Post("/{id}/unarchive", controllers.UnarchiveTodo)
+		r.Get("/{id}/graph", controllers.TodoGraph)
+		r.Get("/{id}/comments", controllers.ListComments)
+		r.Post("/{id}/comments", controllers.CreateComment)
+		r.Get("/{id}/activity", controllers.TodoActivity)
+		r.Get("/{id}/attachments", controllers.ListAttachments)
+		r.Post("/{id}/attachments", controllers.UploadAttachment)
+		r.Post("/{id}/timer/start", controllers.StartTimer)
+		r.Post("/{id}/timer/stop", controllers.StopTimer)
+		r.Get("/{id}/time", controllers.ListTimeEntries)
+		r.Post("/{id}/time", controllers.CreateTimeEntry)
 	})
 
 	rg.Get("/quote", controllers.GetQuoteHandler)
+	rg.Get("/search", controllers.SearchTodos)
+	rg.Get("/parse", controllers.PreviewQuickAdd)
+	rg.Get("/export", controllers.ExportTodos)
+	rg.Post("/import", controllers.ImportTodos)
+	return rg
+}
+
+func webhookHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListWebhooks)
+	rg.Post("/", controllers.CreateWebhook)
+	rg.Delete("/{id}", controllers.DeleteWebhook)
+	rg.Get("/{id}/deliveries", controllers.ListWebhookDeliveries)
+	return rg
+}
+
+func integrationHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListIntegrations)
+	rg.Post("/", controllers.CreateIntegration)
+	rg.Put("/{id}", controllers.UpdateIntegration)
+	rg.Delete("/{id}", controllers.DeleteIntegration)
+	rg.Post("/{id}/test", controllers.TestIntegration)
+	return rg
+}
+
+func userHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Post("/", controllers.CreateUser)
+	rg.Get("/me", controllers.GetCurrentUser)
+	rg.Post("/me/feed-token", controllers.RegenerateFeedToken)
+	rg.Post("/me/mail-token", controllers.RegenerateMailToken)
+	return rg
+}
+
+func tagHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListTags)
+	rg.Post("/", controllers.CreateTag)
+	rg.Put("/{id}", controllers.UpdateTag)
+	rg.Delete("/{id}", controllers.DeleteTag)
+	return rg
+}
+
+func listHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListLists)
+	rg.Post("/", controllers.CreateList)
+	rg.Put("/{id}", controllers.UpdateList)
+	rg.Delete("/{id}", controllers.DeleteList)
+	rg.Put("/{id}/workflow", controllers.SetListWorkflow)
+	rg.Put("/{id}/fields", controllers.SetListFields)
+	rg.Get("/{id}/board", controllers.ListBoard)
+	rg.Post("/{id}/template", controllers.SaveListAsTemplate)
+	rg.Post("/{id}/archive", controllers.ArchiveList)
+	rg.Post("/{id}/unarchive", controllers.UnarchiveList)
+	return rg
+}
+
+func templateHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListTemplates)
+	rg.Post("/", controllers.CreateTemplate)
+	rg.Get("/{id}", controllers.GetTemplate)
+	rg.Put("/{id}", controllers.UpdateTemplate)
+	rg.Delete("/{id}", controllers.DeleteTemplate)
+	rg.Post("/{id}/instantiate", controllers.InstantiateTemplate)
+	return rg
+}
+
+func commentHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Put("/{id}", controllers.UpdateComment)
+	rg.Delete("/{id}", controllers.DeleteComment)
+	return rg
+}
+
+func notificationHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.ListNotifications)
+	rg.Post("/read", controllers.MarkAllNotificationsRead)
+	rg.Post("/{id}/read", controllers.MarkNotificationRead)
+	rg.Get("/preferences", controllers.GetNotificationPreferences)
+	rg.Put("/preferences", controllers.SetNotificationPreferences)
+	return rg
+}
+
+func attachmentHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/{id}", controllers.DownloadAttachment)
+	rg.Delete("/{id}", controllers.DeleteAttachment)
+	return rg
+}
+
+func timeHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/totals", controllers.TimeTotals)
+	rg.Get("/timesheet.csv", controllers.Timesheet)
+	rg.Put("/{id}", controllers.UpdateTimeEntry)
+	rg.Delete("/{id}", controllers.DeleteTimeEntry)
+	return rg
+}
+
+func focusHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Post("/", controllers.StartFocus)
+	rg.Get("/current", controllers.CurrentFocus)
+	rg.Get("/stream", controllers.FocusStream)
+	rg.Get("/stats", controllers.FocusStats)
+	rg.Post("/{id}/pause", controllers.PauseFocus)
+	rg.Post("/{id}/resume", controllers.ResumeFocus)
+	rg.Post("/{id}/complete", controllers.CompleteFocus)
+	return rg
+}
+
+func statsHandlers() http.Handler {
+	rg := chi.NewRouter()
+	rg.Get("/", controllers.Stats)
+	rg.Get("/burndown", controllers.StatsBurndown)
+	return rg
+}
+
+// Serveur CalDAV minimal : une collection de VTODO par utilisateur, /caldav/{jeton}/
+func caldavHandlers() http.Handler {
+	rg := chi.NewRouter()
+	for _, pattern := range []string{"/{token}", "/{token}/"} {
+		rg.Options(pattern, controllers.CalDAVOptions)
+		rg.MethodFunc("PROPFIND", pattern, controllers.CalDAVPropfindCollection)
+		rg.MethodFunc("REPORT", pattern, controllers.CalDAVReport)
+	}
+	rg.Options("/{token}/{resource}", controllers.CalDAVOptions)
+	rg.MethodFunc("PROPFIND", "/{token}/{resource}", controllers.CalDAVPropfindResource)
+	rg.Get("/{token}/{resource}", controllers.CalDAVGet)
+	rg.Put("/{token}/{resource}", controllers.CalDAVPut)
+	rg.Delete("/{token}/{resource}", controllers.CalDAVDelete)
 	return rg
 }
 
@@ -0,0 +1,151 @@
+// Package markdown rend le sous-ensemble de markdown des commentaires en HTML sûr :
+// paragraphes, listes à puces, blocs de code délimités par ```, `code`, **gras**,
+// *italique*, liens [texte](https://…) et @mentions. Tout le reste est échappé,
+// aucune balise HTML saisie n'est conservée.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkRe    = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)]+)\)`)
+	boldRe    = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicRe  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mentionRe = regexp.MustCompile(`(^|[^\w@/.])@(\w[\w.-]*)`)
+	listRe    = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+)
+
+// ToHTML convertit src en HTML
+func ToHTML(src string) string {
+	var b strings.Builder
+	var para, items, code []string
+	inCode := false
+
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>")
+			for i, line := range para {
+				if i > 0 {
+					b.WriteString("<br>\n")
+				}
+				b.WriteString(inline(line))
+			}
+			b.WriteString("</p>\n")
+			para = nil
+		}
+		if len(items) > 0 {
+			b.WriteString("<ul>\n")
+			for _, item := range items {
+				b.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			b.WriteString("</ul>\n")
+			items = nil
+		}
+	}
+
+	for _, line := range lines(src) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code, inCode = nil, false
+			} else {
+				flush()
+				inCode = true
+			}
+			continue
+		}
+		switch {
+		case inCode:
+			code = append(code, line)
+		case trimmed == "":
+			flush()
+		case listRe.MatchString(line):
+			if len(para) > 0 {
+				flush()
+			}
+			items = append(items, listRe.FindStringSubmatch(line)[1])
+		default:
+			if len(items) > 0 {
+				flush()
+			}
+			para = append(para, trimmed)
+		}
+	}
+	if inCode {
+		// bloc non refermé : rendu tel quel jusqu'à la fin
+		b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Mentions retourne les identifiants mentionnés par @, en minuscules et sans doublon,
+// en ignorant ceux qui apparaissent dans du code
+func Mentions(src string) []string {
+	seen := map[string]bool{}
+	var handles []string
+	inCode := false
+	for _, line := range lines(src) {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		for i, part := range strings.Split(line, "`") {
+			if i%2 == 1 {
+				continue // code en ligne
+			}
+			for _, m := range mentionRe.FindAllStringSubmatch(part, -1) {
+				handle := trimHandle(m[2])
+				if handle != "" && !seen[handle] {
+					seen[handle] = true
+					handles = append(handles, handle)
+				}
+			}
+		}
+	}
+	return handles
+}
+
+func lines(src string) []string {
+	return strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+}
+
+// inline rend une ligne ; les segments entre ` sont du code et ne sont pas interprétés
+func inline(line string) string {
+	parts := strings.Split(line, "`")
+	if len(parts)%2 == 0 {
+		// ` non refermé : conservé comme texte
+		parts[len(parts)-2] += "`" + parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+	var b strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 {
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		s := html.EscapeString(part)
+		s = linkRe.ReplaceAllString(s, `<a href="$2" rel="nofollow noopener" target="_blank">$1</a>`)
+		s = boldRe.ReplaceAllString(s, "<strong>$1</strong>")
+		s = italicRe.ReplaceAllString(s, "<em>$1</em>")
+		s = mentionRe.ReplaceAllStringFunc(s, func(m string) string {
+			sub := mentionRe.FindStringSubmatch(m)
+			handle := strings.TrimRight(sub[2], ".-")
+			return sub[1] + `<span class="mention">@` + handle + `</span>` + sub[2][len(handle):]
+		})
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// trimHandle retire la ponctuation finale (« merci @bob. ») et met en minuscules
+func trimHandle(handle string) string {
+	return strings.ToLower(strings.TrimRight(handle, ".-"))
+}
@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHTML(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{"Hello **world**, *really*", "<p>Hello <strong>world</strong>, <em>really</em></p>"},
+		{"line one\nline two\n\nnext", "<p>line one<br>\nline two</p>\n<p>next</p>"},
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"see [docs](https://example.com/a?b=1&c=2)", `<p>see <a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener" target="_blank">docs</a></p>`},
+		{"[evil](javascript:alert(1))", "<p>[evil](javascript:alert(1))</p>"},
+		{"run `rm -rf **` now", "<p>run <code>rm -rf **</code> now</p>"},
+		{"todo:\n- one\n* **two**", "<p>todo:</p>\n<ul>\n<li>one</li>\n<li><strong>two</strong></li>\n</ul>"},
+		{"```\n<b>@bob</b>\n```", "<pre><code>&lt;b&gt;@bob&lt;/b&gt;</code></pre>"},
+		{"thanks @Alice.", `<p>thanks <span class="mention">@Alice</span>.</p>`},
+		{"mail bob@example.com", "<p>mail bob@example.com</p>"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, ToHTML(c.src), c.src)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	src := "@alice can you check with @Bob.smith? cc @alice\n`@carol` bob@example.com\n```\n@dave\n```\n(@erin)"
+	assert.Equal(t, []string{"alice", "bob.smith", "erin"}, Mentions(src))
+	assert.Empty(t, Mentions("no mention here"))
+}
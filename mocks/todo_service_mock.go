@@ -5,9 +5,12 @@
 package mocks
 
 import (
+	io "io"
 	reflect "reflect"
 
 	Models "github.com/go-todo1/Models"
+	exchange "github.com/go-todo1/exchange"
+	services "github.com/go-todo1/services"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -38,6 +41,21 @@ func NewMockTodoService(ctrl *gomock.Controller) *MockTodoService {
 // EXPECT returns an object that allows the caller to indicate expected use.
 
 
+// List mocks base method.
+func (m *MockTodoService) List(filter services.TodoFilter) ([]Models.TodoModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", filter)
+	ret0, _ := ret[0].([]Models.TodoModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTodoServiceMockRecorder) List(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTodoService)(nil).List), filter)
+}
+
 // Create mocks base method.
 func (m *MockTodoService) Create(todo Models.TodoModel) (Models.TodoModel, error) {
 	m.ctrl.T.Helper()
@@ -54,32 +72,62 @@ func (mr *MockTodoServiceMockRecorder) Create(todo interface{}) *gomock.Call {
 }
 
 // Delete mocks base method.
-func (m *MockTodoService) Delete(id uint) error {
+func (m *MockTodoService) Delete(userID, id uint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", userID, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockTodoServiceMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockTodoServiceMockRecorder) Delete(userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTodoService)(nil).Delete), userID, id)
+}
+
+// Move mocks base method.
+func (m *MockTodoService) Move(userID, id, before, after uint) (Models.TodoModel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", userID, id, before, after)
+	ret0, _ := ret[0].(Models.TodoModel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockTodoServiceMockRecorder) Move(userID, id, before, after interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockTodoService)(nil).Move), userID, id, before, after)
+}
+
+// Graph mocks base method.
+func (m *MockTodoService) Graph(userID, id uint) (services.DependencyGraph, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Graph", userID, id)
+	ret0, _ := ret[0].(services.DependencyGraph)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Graph indicates an expected call of Graph.
+func (mr *MockTodoServiceMockRecorder) Graph(userID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTodoService)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Graph", reflect.TypeOf((*MockTodoService)(nil).Graph), userID, id)
 }
 
 // Update mocks base method.
-func (m *MockTodoService) Update(id uint, todo Models.TodoModel) (Models.TodoModel, error) {
+func (m *MockTodoService) Update(userID, id uint, patch services.TodoPatch) (Models.TodoModel, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, todo)
+	ret := m.ctrl.Call(m, "Update", userID, id, patch)
 	ret0, _ := ret[0].(Models.TodoModel)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockTodoServiceMockRecorder) Update(id, todo interface{}) *gomock.Call {
+func (mr *MockTodoServiceMockRecorder) Update(userID, id, patch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTodoService)(nil).Update), id, todo)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTodoService)(nil).Update), userID, id, patch)
 }
 // GetQuote mocks base method.
 func (m *MockTodoService) GetQuote() (Models.QuoteResponse, error) {
@@ -96,3 +144,33 @@ func (mr *MockTodoServiceMockRecorder) GetQuote() *gomock.Call {
     return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuote", reflect.TypeOf((*MockTodoService)(nil).GetQuote))
 }
 
+
+
+// Export mocks base method.
+func (m *MockTodoService) Export(userID uint, w io.Writer, format string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", userID, w, format)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockTodoServiceMockRecorder) Export(userID, w, format interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockTodoService)(nil).Export), userID, w, format)
+}
+
+// Import mocks base method.
+func (m *MockTodoService) Import(userID uint, r io.Reader, format string, dryRun bool) (exchange.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", userID, r, format, dryRun)
+	ret0, _ := ret[0].(exchange.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockTodoServiceMockRecorder) Import(userID, r, format, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockTodoService)(nil).Import), userID, r, format, dryRun)
+}
@@ -0,0 +1,341 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxEmailAttempts = 5
+	emailBatchSize   = 50
+	baseEmailBackoff = time.Minute
+	smtpTimeout      = 30 * time.Second
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Message est un e-mail à envoyer, en texte et en HTML
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender envoie un e-mail ; SMTP en est l'implémentation
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTP envoie les e-mails par un serveur SMTP (Postfix, un relais ou MailHog en
+// développement). STARTTLS est utilisé si le serveur le propose ; l'authentification
+// PLAIN seulement si Username est renseigné.
+type SMTP struct {
+	Addr     string // hôte:port
+	Username string
+	Password string
+	From     string // adresse d'expédition, éventuellement avec un nom : "Todos <todo@example.com>"
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", s.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	data, err := buildMessage(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	host, _, _ := net.SplitHostPort(s.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage compose un message multipart/alternative, la partie texte en premier
+func buildMessage(from, to *mail.Address, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `multipart/alternative; boundary="`+body.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(strings.ReplaceAll(part.content, "\n", "\r\n"))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func messageID(from string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// EmailData est le contexte des modèles d'e-mail
+type EmailData struct {
+	Recipient      models.UserModel
+	Notification   models.NotificationModel
+	Actor          string // nom de l'auteur, "Someone" s'il est inconnu
+	Todo           models.TodoModel
+	Comment        string // commentaire à l'origine d'une mention
+	TodoURL        string
+	PreferencesURL string
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates rend les e-mails à partir des modèles embarqués templates/{type}.txt.tmpl
+// (blocs "subject" et "body") et templates/{type}.html.tmpl (bloc "content" de layout.html.tmpl)
+type Templates struct {
+	byType map[string]emailTemplate
+}
+
+func NewTemplates() (*Templates, error) {
+	t := &Templates{byType: map[string]emailTemplate{}}
+	for _, kind := range append([]string{"default"}, Types...) {
+		text, err := texttemplate.ParseFS(templateFiles, "templates/"+kind+".txt.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.ParseFS(templateFiles, "templates/layout.html.tmpl", "templates/"+kind+".html.tmpl")
+		if err != nil {
+			return nil, err
+		}
+		t.byType[kind] = emailTemplate{text: text, html: html}
+	}
+	return t, nil
+}
+
+// Render retourne l'e-mail de la notification, avec le modèle par défaut pour un type inconnu
+func (t *Templates) Render(data EmailData) (Message, error) {
+	tmpl, ok := t.byType[data.Notification.Type]
+	if !ok {
+		tmpl = t.byType["default"]
+	}
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "body", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      (&mail.Address{Name: data.Recipient.Name, Address: data.Recipient.Email}).String(),
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Mailer envoie par e-mail les notifications en attente ; un échec est retenté
+// avec un délai croissant, jusqu'à maxEmailAttempts tentatives
+type Mailer struct {
+	Db        *gorm.DB
+	Sender    Sender
+	Templates *Templates
+	BaseURL   string // adresse publique de l'application, pour les liens ; vide : pas de lien
+	Now       func() time.Time
+}
+
+func NewMailer(db *gorm.DB, sender Sender, baseURL string) (*Mailer, error) {
+	templates, err := NewTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{Db: db, Sender: sender, Templates: templates, BaseURL: strings.TrimRight(baseURL, "/"), Now: time.Now}, nil
+}
+
+// DeliverPending envoie les e-mails en attente et retourne le nombre d'envois réussis
+func (m *Mailer) DeliverPending(ctx context.Context) (int, error) {
+	var pending []models.NotificationModel
+	err := m.Db.Where("email_status = ? AND (next_email_at IS NULL OR next_email_at <= ?)", models.EmailPending, m.Now()).
+		Order("id").Limit(emailBatchSize).Find(&pending).Error
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, n := range pending {
+		err := m.deliver(ctx, n)
+		updates := map[string]interface{}{"attempts": n.Attempts + 1, "email_error": ""}
+		switch {
+		case err == nil:
+			updates["email_status"] = models.EmailSent
+			sent++
+		case n.Attempts+1 >= maxEmailAttempts:
+			updates["email_status"] = models.EmailFailed
+			updates["email_error"] = truncate(err.Error(), 255)
+		default:
+			updates["next_email_at"] = m.Now().Add(baseEmailBackoff << n.Attempts)
+			updates["email_error"] = truncate(err.Error(), 255)
+		}
+		if err != nil {
+			log.Printf("Error emailing notification %d: %v", n.ID, err)
+		}
+		if err := m.Db.Model(&n).UpdateColumns(updates).Error; err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// Run envoie périodiquement les e-mails en attente, jusqu'à l'annulation de ctx
+func (m *Mailer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.DeliverPending(ctx); err != nil {
+				log.Printf("Error delivering notification emails: %v", err)
+			}
+		}
+	}
+}
+
+func (m *Mailer) deliver(ctx context.Context, n models.NotificationModel) error {
+	data := EmailData{Notification: n, Actor: "Someone"}
+	if err := m.Db.First(&data.Recipient, n.UserID).Error; err != nil {
+		return fmt.Errorf("recipient %d: %w", n.UserID, err)
+	}
+	var actor models.UserModel
+	if n.ActorID != 0 && m.Db.First(&actor, n.ActorID).Error == nil {
+		data.Actor = actor.Name
+	}
+	// le todo ou le commentaire peuvent avoir été supprimés depuis
+	if n.TodoID != 0 {
+		m.Db.Limit(1).Find(&data.Todo, n.TodoID)
+	}
+	if n.CommentID != nil {
+		var comment models.CommentModel
+		if m.Db.Limit(1).Find(&comment, *n.CommentID).Error == nil {
+			data.Comment = comment.Body
+		}
+	}
+	if data.Todo.ID == 0 {
+		data.Todo.Title = n.Message
+	} else if m.BaseURL != "" {
+		data.TodoURL = fmt.Sprintf("%s/#todo-%d", m.BaseURL, data.Todo.ID)
+	}
+	if m.BaseURL != "" {
+		data.PreferencesURL = m.BaseURL + "/#notifications"
+	}
+
+	msg, err := m.Templates.Render(data)
+	if err != nil {
+		return err
+	}
+	return m.Sender.Send(ctx, msg)
+}
+
+// truncate coupe s à n octets sans couper un caractère
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,162 @@
+// Package notifications livre les notifications des utilisateurs (mentions, assignations) :
+// elles sont enregistrées par la transaction qui les provoque, apparaissent dans la boîte
+// de réception et sont envoyées par e-mail en arrière-plan, selon les préférences de chacun.
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+// Types est la liste des types de notification pour lesquels une préférence peut être choisie
+var Types = []string{models.NotificationMention, models.NotificationAssigned}
+
+// DefaultLimit est le nombre de notifications retournées par défaut par List
+const DefaultLimit = 50
+
+var (
+	ErrNotFound          = errors.New("notification not found")
+	ErrInvalidPreference = errors.New("invalid notification preference")
+)
+
+// Record enregistre la notification avec la transaction tx selon les préférences du
+// destinataire : rien n'est créé si tous ses canaux sont désactivés pour ce type
+func Record(tx *gorm.DB, n models.NotificationModel) error {
+	pref, err := preference(tx, n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+	if !pref.InApp && !pref.Email {
+		return nil
+	}
+	n.Hidden = !pref.InApp
+	if pref.Email {
+		n.EmailStatus = models.EmailPending
+	}
+	return tx.Create(&n).Error
+}
+
+// preference retourne la préférence enregistrée, ou tous les canaux actifs
+func preference(tx *gorm.DB, userID uint, kind string) (models.NotificationPreference, error) {
+	var prefs []models.NotificationPreference
+	if err := tx.Where("user_id = ? AND type = ?", userID, kind).Limit(1).Find(&prefs).Error; err != nil {
+		return models.NotificationPreference{}, err
+	}
+	if len(prefs) == 0 {
+		return models.NotificationPreference{UserID: userID, Type: kind, InApp: true, Email: true}, nil
+	}
+	return prefs[0], nil
+}
+
+// Inbox est la boîte de réception des utilisateurs et leurs préférences
+type Inbox struct {
+	Db  *gorm.DB
+	Now func() time.Time // horloge, remplaçable dans les tests
+}
+
+func NewInbox(db *gorm.DB) *Inbox {
+	return &Inbox{Db: db, Now: time.Now}
+}
+
+// List retourne les notifications les plus récentes de l'utilisateur, les non lues seulement si unread est vrai
+func (i *Inbox) List(userID uint, unread bool, limit int) ([]models.NotificationModel, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	query := i.visible(userID)
+	if unread {
+		query = query.Where("read_at IS NULL")
+	}
+	notifications := []models.NotificationModel{}
+	err := query.Order("created_at DESC").Order("id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// Unread compte les notifications non lues de l'utilisateur
+func (i *Inbox) Unread(userID uint) (int64, error) {
+	var count int64
+	err := i.visible(userID).Where("read_at IS NULL").Count(&count).Error
+	return count, err
+}
+
+// MarkRead marque une notification comme lue ; la marquer de nouveau ne change pas la date de lecture
+func (i *Inbox) MarkRead(userID, id uint) (models.NotificationModel, error) {
+	var n models.NotificationModel
+	if err := i.visible(userID).First(&n, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NotificationModel{}, ErrNotFound
+		}
+		return models.NotificationModel{}, err
+	}
+	if n.ReadAt != nil {
+		return n, nil
+	}
+	now := i.Now()
+	if err := i.Db.Model(&n).UpdateColumn("read_at", now).Error; err != nil {
+		return models.NotificationModel{}, err
+	}
+	n.ReadAt = &now
+	return n, nil
+}
+
+// MarkAllRead marque toutes les notifications de l'utilisateur comme lues et retourne leur nombre
+func (i *Inbox) MarkAllRead(userID uint) (int64, error) {
+	result := i.visible(userID).Where("read_at IS NULL").UpdateColumn("read_at", i.Now())
+	return result.RowsAffected, result.Error
+}
+
+// Preferences retourne la préférence effective de l'utilisateur pour chaque type, dans l'ordre de Types
+func (i *Inbox) Preferences(userID uint) ([]models.NotificationPreference, error) {
+	prefs := make([]models.NotificationPreference, 0, len(Types))
+	for _, kind := range Types {
+		pref, err := preference(i.Db, userID, kind)
+		if err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// SetPreferences enregistre les préférences fournies ; les types absents sont inchangés
+func (i *Inbox) SetPreferences(userID uint, prefs []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	for _, pref := range prefs {
+		if !validType(pref.Type) {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidPreference, pref.Type)
+		}
+	}
+	err := i.Db.Transaction(func(tx *gorm.DB) error {
+		for _, pref := range prefs {
+			existing, err := preference(tx, userID, pref.Type)
+			if err != nil {
+				return err
+			}
+			existing.InApp, existing.Email = pref.InApp, pref.Email
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return i.Preferences(userID)
+}
+
+func (i *Inbox) visible(userID uint) *gorm.DB {
+	return i.Db.Model(&models.NotificationModel{}).Where("user_id = ? AND hidden = ?", userID, false)
+}
+
+func validType(kind string) bool {
+	for _, t := range Types {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
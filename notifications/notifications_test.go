@@ -0,0 +1,296 @@
+package notifications_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/notifications"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func initSQLiteDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.TodoModel{}, &models.OutboxEvent{}, &models.UserModel{}, &models.CommentModel{}, &models.TodoActivity{}, &models.NotificationModel{}, &models.NotificationPreference{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// fakeSMTP est un serveur SMTP minimal qui garde les messages reçus, à la manière de MailHog
+type fakeSMTP struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []string
+	reject   bool // répondre 554 à DATA
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{listener: listener}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"), strings.HasPrefix(cmd, "RSET"), strings.HasPrefix(cmd, "NOOP"):
+			reply("250 OK")
+		case cmd == "DATA":
+			if s.reject {
+				reply("554 Transaction failed")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// parts retourne le sujet décodé et le contenu de chaque partie par type MIME
+func parts(t *testing.T, raw string) (string, map[string]string) {
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+	contents := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart() // décode le quoted-printable
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		contents[contentType] = string(body)
+	}
+	return subject, contents
+}
+
+func TestInbox(t *testing.T) {
+	db := initSQLiteDB(t)
+	inbox := notifications.NewInbox(db)
+
+	for _, n := range []models.NotificationModel{
+		{UserID: 1, Type: models.NotificationMention, Message: "first"},
+		{UserID: 1, Type: models.NotificationAssigned, Message: "second"},
+		{UserID: 2, Type: models.NotificationMention, Message: "other user"},
+	} {
+		require.NoError(t, notifications.Record(db, n))
+	}
+
+	list, err := inbox.List(1, false, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "second", list[0].Message, "most recent first")
+	assert.Equal(t, models.EmailPending, list[0].EmailStatus)
+
+	read, err := inbox.MarkRead(1, list[1].ID)
+	require.NoError(t, err)
+	assert.NotNil(t, read.ReadAt)
+	_, err = inbox.MarkRead(2, list[0].ID)
+	assert.ErrorIs(t, err, notifications.ErrNotFound)
+	unread, err := inbox.List(1, true, 0)
+	require.NoError(t, err)
+	require.Len(t, unread, 1)
+	assert.Equal(t, "second", unread[0].Message)
+
+	count, err := inbox.MarkAllRead(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	n, err := inbox.Unread(1)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, _ = inbox.Unread(2)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestPreferences(t *testing.T) {
+	db := initSQLiteDB(t)
+	inbox := notifications.NewInbox(db)
+
+	prefs, err := inbox.Preferences(1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.NotificationPreference{
+		{UserID: 1, Type: models.NotificationMention, InApp: true, Email: true},
+		{UserID: 1, Type: models.NotificationAssigned, InApp: true, Email: true},
+	}, prefs)
+
+	_, err = inbox.SetPreferences(1, []models.NotificationPreference{{Type: "digest", Email: true}})
+	assert.ErrorIs(t, err, notifications.ErrInvalidPreference)
+
+	prefs, err = inbox.SetPreferences(1, []models.NotificationPreference{
+		{Type: models.NotificationMention, InApp: true, Email: false},
+		{Type: models.NotificationAssigned, InApp: false, Email: false},
+	})
+	require.NoError(t, err)
+	assert.False(t, prefs[0].Email)
+	assert.False(t, prefs[1].InApp)
+	_, err = inbox.SetPreferences(1, []models.NotificationPreference{{Type: models.NotificationMention, InApp: false, Email: true}})
+	require.NoError(t, err)
+
+	require.NoError(t, notifications.Record(db, models.NotificationModel{UserID: 1, Type: models.NotificationAssigned, Message: "muted"}))
+	require.NoError(t, notifications.Record(db, models.NotificationModel{UserID: 1, Type: models.NotificationMention, Message: "email only"}))
+	var all []models.NotificationModel
+	db.Find(&all)
+	require.Len(t, all, 1, "no notification is stored when every channel is off")
+	assert.True(t, all[0].Hidden)
+	assert.Equal(t, models.EmailPending, all[0].EmailStatus)
+	list, _ := inbox.List(1, false, 0)
+	assert.Empty(t, list)
+}
+
+func TestMailer(t *testing.T) {
+	db := initSQLiteDB(t)
+	server := newFakeSMTP(t)
+	alice := models.UserModel{Name: "Alice", Email: "alice@example.com", Handle: "alice", FeedToken: "a", MailToken: "a"}
+	bob := models.UserModel{Name: "Bob Éclair", Email: "bob@example.com", Handle: "bob", FeedToken: "b", MailToken: "b"}
+	require.NoError(t, db.Create(&alice).Error)
+	require.NoError(t, db.Create(&bob).Error)
+
+	todo, err := services.NewTodoServiceImp(db, "").Create(models.TodoModel{UserID: alice.ID, Title: "Release <notes>"})
+	require.NoError(t, err)
+	_, err = services.NewCommentServiceImp(db).Create(models.CommentModel{TodoID: todo.ID, AuthorID: alice.ID, Body: "@bob can you **review**?"})
+	require.NoError(t, err)
+
+	sender := &notifications.SMTP{Addr: server.listener.Addr().String(), From: "Todos <todo@example.com>"}
+	mailer, err := notifications.NewMailer(db, sender, "https://todo.example.com/")
+	require.NoError(t, err)
+	sent, err := mailer.DeliverPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0], "To: =?utf-8?q?Bob_=C3=89clair?= <bob@example.com>\r\n")
+	subject, contents := parts(t, messages[0])
+	assert.Equal(t, `Alice mentioned you on "Release <notes>"`, subject)
+	assert.Contains(t, contents["text/plain"], "Hi Bob Éclair,")
+	assert.Contains(t, contents["text/plain"], "@bob can you **review**?")
+	assert.Contains(t, contents["text/plain"], "https://todo.example.com/#todo-1")
+	assert.Contains(t, contents["text/html"], "<strong>Release &lt;notes&gt;</strong>", "the HTML part is escaped")
+	assert.Contains(t, contents["text/html"], `href="https://todo.example.com/#notifications"`)
+
+	var n models.NotificationModel
+	require.NoError(t, db.First(&n).Error)
+	assert.Equal(t, models.EmailSent, n.EmailStatus)
+	sent, _ = mailer.DeliverPending(context.Background())
+	assert.Zero(t, sent, "an email is sent once")
+}
+
+type failingSender struct{ calls int }
+
+func (f *failingSender) Send(ctx context.Context, msg notifications.Message) error {
+	f.calls++
+	return errors.New("connection refused")
+}
+
+func TestMailerRetries(t *testing.T) {
+	db := initSQLiteDB(t)
+	user := models.UserModel{Name: "Bob", Email: "bob@example.com", Handle: "bob", FeedToken: "b", MailToken: "b"}
+	require.NoError(t, db.Create(&user).Error)
+	require.NoError(t, notifications.Record(db, models.NotificationModel{UserID: user.ID, Type: models.NotificationAssigned, Message: "Alice assigned you"}))
+
+	sender := &failingSender{}
+	mailer, err := notifications.NewMailer(db, sender, "")
+	require.NoError(t, err)
+	now := time.Now()
+	mailer.Now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		_, err := mailer.DeliverPending(context.Background())
+		require.NoError(t, err)
+		_, err = mailer.DeliverPending(context.Background()) // attend le délai avant de réessayer
+		require.NoError(t, err)
+		now = now.Add(time.Hour)
+	}
+	assert.Equal(t, 5, sender.calls)
+	var n models.NotificationModel
+	require.NoError(t, db.First(&n).Error)
+	assert.Equal(t, models.EmailFailed, n.EmailStatus)
+	assert.Equal(t, "connection refused", n.EmailError)
+}
+
+func TestSMTPRejection(t *testing.T) {
+	server := newFakeSMTP(t)
+	server.reject = true
+	sender := &notifications.SMTP{Addr: server.listener.Addr().String(), From: "todo@example.com"}
+	err := sender.Send(context.Background(), notifications.Message{To: "bob@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>"})
+	assert.ErrorContains(t, err, "554")
+	assert.Empty(t, server.Messages())
+}
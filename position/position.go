@@ -0,0 +1,102 @@
+// Package position génère des clés d'ordre fractionnaires : des chaînes comparées
+// lexicographiquement entre lesquelles on peut toujours insérer une nouvelle clé,
+// ce qui permet de déplacer un élément sans renuméroter les autres.
+package position
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Les ajouts en fin de liste incrémentent un entier de width chiffres suivi de
+// "i" : la longueur des clés reste constante au lieu de croître à chaque ajout.
+const width = 8
+
+// Base 36 en minuscules : l'ordre ASCII des chaînes suit l'ordre des clés, y compris
+// avec une collation MySQL insensible à la casse.
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRange = errors.New("position: lower bound must sort before upper bound")
+
+// Between retourne une clé strictement comprise entre a et b. a vide signifie
+// « début de liste », b vide « fin de liste ».
+func Between(a, b string) (string, error) {
+	if !valid(a) || !valid(b) {
+		return "", errors.New("position: invalid key")
+	}
+	if b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+	return midpoint(a, b), nil
+}
+
+// After retourne une clé placée après a, pour ajouter en fin de liste
+func After(a string) (string, error) {
+	if !valid(a) {
+		return "", errors.New("position: invalid key")
+	}
+	head := a
+	if len(head) > width {
+		head = head[:width]
+	}
+	head += strings.Repeat(digits[:1], width-len(head))
+	n, err := strconv.ParseUint(head, 36, 64)
+	if err != nil || strings.Trim(head, "z") == "" {
+		return midpoint(a, ""), nil
+	}
+	next := strconv.FormatUint(n+1, 36)
+	return strings.Repeat(digits[:1], width-len(next)) + next + "i", nil
+}
+
+// midpoint suppose a < b (b vide = infini) et qu'aucune clé ne se termine par '0'
+func midpoint(a, b string) string {
+	if b != "" {
+		// préfixe commun, a étant complété par des zéros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	da := strings.IndexByte(digits, digitAt(a, 0))
+	db := len(digits)
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db)/2])
+	}
+	// chiffres consécutifs
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[da]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, digits[:1])
+}
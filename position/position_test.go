@@ -0,0 +1,87 @@
+package position
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBetween(t *testing.T) {
+	cases := []struct{ a, b, want string }{
+		{"", "", "i"},
+		{"i", "", "r"},
+		{"", "i", "9"},
+		{"a", "b", "ai"},
+		{"a", "a1", "a0i"},
+		{"az", "b", "azi"},
+		{"z", "", "zi"},
+		{"", "1", "0i"},
+	}
+	for _, c := range cases {
+		got, err := Between(c.a, c.b)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, "Between(%q, %q)", c.a, c.b)
+		assert.True(t, c.a < got && (c.b == "" || got < c.b))
+	}
+
+	_, err := Between("b", "a")
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = Between("a", "a")
+	assert.ErrorIs(t, err, ErrInvalidRange)
+	_, err = Between("a0", "")
+	assert.Error(t, err)
+	_, err = Between("A", "")
+	assert.Error(t, err)
+}
+
+func TestAfter(t *testing.T) {
+	cases := []struct{ a, want string }{
+		{"", "00000001i"},
+		{"00000001i", "00000002i"},
+		{"0000000zi", "00000010i"},
+		{"00000001", "00000002i"},
+		{"i", "i0000001i"},
+		{"zzzzzzzzi", "zzzzzzzzr"},
+	}
+	for _, c := range cases {
+		got, err := After(c.a)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, "After(%q)", c.a)
+		assert.True(t, c.a < got)
+	}
+
+	key := ""
+	for i := 0; i < 5000; i++ {
+		next, err := After(key)
+		assert.NoError(t, err)
+		assert.True(t, key < next)
+		key = next
+	}
+	assert.Len(t, key, width+1)
+}
+
+func TestRandomInsertionsKeepOrder(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(keys) + 1)
+		var lo, hi string
+		if at > 0 {
+			lo = keys[at-1]
+		}
+		if at < len(keys) {
+			hi = keys[at]
+		}
+		key, err := Between(lo, hi)
+		if !assert.NoError(t, err) {
+			return
+		}
+		keys = append(keys[:at], append([]string{key}, keys[at:]...)...)
+	}
+	assert.True(t, sort.StringsAreSorted(keys))
+	for i := 1; i < len(keys); i++ {
+		assert.NotEqual(t, keys[i-1], keys[i])
+	}
+}
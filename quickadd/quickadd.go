@@ -0,0 +1,483 @@
+// Package quickadd analyse une saisie rapide en anglais ou en français :
+//
+//	Pay invoice tomorrow 5pm #finance !high every month
+//	Payer la facture demain à 17h #finance !haute tous les mois
+//
+// et en extrait le titre, l'échéance, les étiquettes, la priorité et la récurrence.
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+)
+
+const (
+	English = "en"
+	French  = "fr"
+)
+
+// Heure retenue quand seule une date est donnée
+const defaultHour = 9
+
+type Result struct {
+	Title      string     `json:"title"`
+	DueAt      *time.Time `json:"due_at"`
+	Tags       []string   `json:"tags"`
+	Projects   []string   `json:"projects"`
+	Contexts   []string   `json:"contexts"`
+	Priority   string     `json:"priority"`
+	Recurrence string     `json:"recurrence"`
+}
+
+var (
+	timeAmPm   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	timeColon  = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	timeFrench = regexp.MustCompile(`^(\d{1,2})h(\d{2})?$`)
+	isoDate    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	number     = regexp.MustCompile(`^\d{1,3}$`)
+	priorityRe = regexp.MustCompile(`^![a-z]$`)
+)
+
+// vocabulaire, par langue
+var (
+	weekdays = map[string]map[string]time.Weekday{
+		English: {"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+			"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday},
+		French: {"dimanche": time.Sunday, "lundi": time.Monday, "mardi": time.Tuesday, "mercredi": time.Wednesday,
+			"jeudi": time.Thursday, "vendredi": time.Friday, "samedi": time.Saturday},
+	}
+	months = map[string]map[string]time.Month{
+		English: {"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6, "july": 7,
+			"august": 8, "september": 9, "october": 10, "november": 11, "december": 12},
+		French: {"janvier": 1, "février": 2, "fevrier": 2, "mars": 3, "avril": 4, "mai": 5, "juin": 6, "juillet": 7,
+			"août": 8, "aout": 8, "septembre": 9, "octobre": 10, "novembre": 11, "décembre": 12, "decembre": 12},
+	}
+	// jours relatifs, éventuellement sur plusieurs mots
+	relativeDays = map[string]map[string]int{
+		English: {"today": 0, "tomorrow": 1, "day after tomorrow": 2},
+		French:  {"aujourd'hui": 0, "demain": 1, "après-demain": 2, "apres-demain": 2},
+	}
+	units = map[string]map[string]string{
+		English: {"day": "DAILY", "days": "DAILY", "week": "WEEKLY", "weeks": "WEEKLY", "month": "MONTHLY", "months": "MONTHLY"},
+		French:  {"jour": "DAILY", "jours": "DAILY", "semaine": "WEEKLY", "semaines": "WEEKLY", "mois": "MONTHLY"},
+	}
+	priorities = map[string]map[string]string{
+		English: {"!high": "A", "!urgent": "A", "!medium": "B", "!normal": "B", "!low": "C"},
+		French:  {"!haute": "A", "!urgent": "A", "!moyenne": "B", "!normale": "B", "!basse": "C"},
+	}
+	// mots de liaison ignorés devant une date ou une heure
+	connectors = map[string][]string{
+		English: {"at", "on", "by", "due"},
+		French:  {"à", "le", "pour", "pour le"},
+	}
+	// expressions de récurrence sans paramètre
+	fixedRecurrences = map[string]map[string]string{
+		English: {"daily": "FREQ=DAILY", "weekly": "FREQ=WEEKLY", "monthly": "FREQ=MONTHLY",
+			"every weekday": "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		French: {"quotidien": "FREQ=DAILY", "hebdomadaire": "FREQ=WEEKLY", "mensuel": "FREQ=MONTHLY",
+			"tous les jours ouvrés": "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", "en semaine": "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+	}
+	every = map[string][]string{
+		English: {"every"},
+		French:  {"chaque", "tous les", "toutes les"},
+	}
+	in = map[string][]string{
+		English: {"in"},
+		French:  {"dans"},
+	}
+	next = map[string][]string{
+		English: {"next", "this"},
+		French:  {"ce", "cette"},
+	}
+	nextSuffix = map[string][]string{
+		French: {"prochain", "prochaine"},
+	}
+	nextWeek = map[string][]string{
+		English: {"next week"},
+		French:  {"la semaine prochaine", "semaine prochaine"},
+	}
+	noon = map[string][]string{
+		English: {"noon"},
+		French:  {"midi"},
+	}
+)
+
+var dayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+type parser struct {
+	langs  []string
+	words  []string // saisie d'origine
+	lower  []string
+	now    time.Time
+	day    *time.Time // date reconnue, à minuit
+	clock  *[2]int    // heure et minute reconnues
+	result Result
+}
+
+// Parse analyse la saisie ; lang vaut English, French ou "" pour les deux.
+// now sert de référence aux dates relatives et fixe le fuseau horaire.
+func Parse(input string, now time.Time, lang string) Result {
+	p := &parser{now: now, words: strings.Fields(input)}
+	switch lang {
+	case English, French:
+		p.langs = []string{lang}
+	default:
+		p.langs = []string{English, French}
+	}
+	for _, w := range p.words {
+		p.lower = append(p.lower, strings.ToLower(w))
+	}
+
+	var title []string
+	for i := 0; i < len(p.words); {
+		if n := p.match(i); n > 0 {
+			i += n
+			continue
+		}
+		title = append(title, p.words[i])
+		i++
+	}
+	p.result.Title = strings.Join(title, " ")
+	if p.result.Title == "" {
+		p.result.Title = strings.Join(p.words, " ")
+	}
+	p.resolveDue()
+	return p.result
+}
+
+// Apply recopie le résultat dans un todo ; les étiquettes sont laissées à l'appelant
+func (r Result) Apply(todo *models.TodoModel) {
+	todo.Title = r.Title
+	if r.DueAt != nil {
+		todo.DueAt = r.DueAt
+	}
+	if r.Priority != "" {
+		todo.Priority = r.Priority
+	}
+	if r.Recurrence != "" {
+		todo.Recurrence = r.Recurrence
+	}
+	if len(r.Projects) > 0 {
+		todo.Projects = strings.Join(r.Projects, " ")
+	}
+	if len(r.Contexts) > 0 {
+		todo.Contexts = strings.Join(r.Contexts, " ")
+	}
+}
+
+// match essaie chaque motif à la position i et retourne le nombre de mots consommés
+func (p *parser) match(i int) int {
+	w := p.lower[i]
+	switch {
+	case len(w) > 1 && w[0] == '#':
+		p.result.Tags = appendUnique(p.result.Tags, p.words[i][1:])
+		return 1
+	case len(w) > 1 && w[0] == '+':
+		p.result.Projects = appendUnique(p.result.Projects, p.words[i][1:])
+		return 1
+	case len(w) > 1 && w[0] == '@':
+		p.result.Contexts = appendUnique(p.result.Contexts, p.words[i][1:])
+		return 1
+	case priorityRe.MatchString(w):
+		p.result.Priority = strings.ToUpper(w[1:])
+		return 1
+	}
+	for _, lang := range p.langs {
+		if prio, ok := priorities[lang][w]; ok {
+			p.result.Priority = prio
+			return 1
+		}
+	}
+
+	if n := p.matchRecurrence(i); n > 0 {
+		return n
+	}
+	if n := p.matchWhen(i); n > 0 {
+		return n
+	}
+	// un mot de liaison n'est retiré que s'il précède une date ou une heure
+	for _, lang := range p.langs {
+		for _, c := range connectors[lang] {
+			if n := p.phrase(i, c); n > 0 && i+n < len(p.words) {
+				if m := p.matchWhen(i + n); m > 0 {
+					return n + m
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// matchWhen reconnaît une date ou une heure
+func (p *parser) matchWhen(i int) int {
+	w := p.lower[i]
+	if t, ok := parseClock(w); ok {
+		p.clock = &t
+		return 1
+	}
+	if isoDate.MatchString(w) {
+		if d, err := time.ParseInLocation("2006-01-02", w, p.now.Location()); err == nil {
+			p.day = &d
+			return 1
+		}
+	}
+
+	for _, lang := range p.langs {
+		for _, phrase := range noon[lang] {
+			if p.phrase(i, phrase) > 0 {
+				p.clock = &[2]int{12, 0}
+				return 1
+			}
+		}
+		for phrase, days := range relativeDays[lang] {
+			if n := p.phrase(i, phrase); n > 0 {
+				p.setDay(p.today().AddDate(0, 0, days))
+				return n
+			}
+		}
+		for _, phrase := range nextWeek[lang] {
+			if n := p.phrase(i, phrase); n > 0 {
+				p.setDay(p.nextWeekday(time.Monday))
+				return n
+			}
+		}
+		// "in 3 days", "dans 2 semaines"
+		for _, prefix := range in[lang] {
+			if n := p.phrase(i, prefix); n > 0 && i+n+1 < len(p.words) && number.MatchString(p.lower[i+n]) {
+				count, _ := strconv.Atoi(p.lower[i+n])
+				switch units[lang][p.lower[i+n+1]] {
+				case "DAILY":
+					p.setDay(p.today().AddDate(0, 0, count))
+				case "WEEKLY":
+					p.setDay(p.today().AddDate(0, 0, 7*count))
+				case "MONTHLY":
+					p.setDay(p.today().AddDate(0, count, 0))
+				default:
+					continue
+				}
+				return n + 2
+			}
+		}
+		// "next friday", "ce vendredi", "vendredi prochain", "friday"
+		for _, prefix := range next[lang] {
+			if n := p.phrase(i, prefix); n > 0 && i+n < len(p.words) {
+				if wd, ok := weekdays[lang][p.lower[i+n]]; ok {
+					p.setDay(p.nextWeekday(wd))
+					return n + 1
+				}
+			}
+		}
+		if wd, ok := weekdays[lang][w]; ok {
+			p.setDay(p.nextWeekday(wd))
+			if i+1 < len(p.words) && contains(nextSuffix[lang], p.lower[i+1]) {
+				return 2
+			}
+			return 1
+		}
+		// "15 august", "august 15", "15 août"
+		if i+1 < len(p.words) {
+			if month, ok := months[lang][p.lower[i+1]]; ok && number.MatchString(w) {
+				return p.setMonthDay(month, w, 2)
+			}
+			if month, ok := months[lang][w]; ok && number.MatchString(p.lower[i+1]) {
+				return p.setMonthDay(month, p.lower[i+1], 2)
+			}
+		}
+	}
+	return 0
+}
+
+// matchRecurrence reconnaît "every month", "every 2 weeks", "every monday", "tous les lundis"...
+func (p *parser) matchRecurrence(i int) int {
+	for _, lang := range p.langs {
+		for phrase, rule := range fixedRecurrences[lang] {
+			if n := p.phrase(i, phrase); n > 0 {
+				p.result.Recurrence = rule
+				return n
+			}
+		}
+		for _, prefix := range every[lang] {
+			n := p.phrase(i, prefix)
+			if n == 0 || i+n >= len(p.words) {
+				continue
+			}
+			j := i + n
+			interval := 1
+			if number.MatchString(p.lower[j]) && j+1 < len(p.words) {
+				interval, _ = strconv.Atoi(p.lower[j])
+				j++
+			}
+			word := p.lower[j]
+			if freq, ok := units[lang][word]; ok {
+				p.result.Recurrence = "FREQ=" + freq
+				if interval > 1 {
+					p.result.Recurrence += ";INTERVAL=" + strconv.Itoa(interval)
+				}
+				// "every week on monday", "toutes les semaines le lundi"
+				if freq == "WEEKLY" {
+					if wd, n := p.weekdayAfter(j+1, lang); n > 0 {
+						p.byDay(wd)
+						return j + 1 + n - i
+					}
+				}
+				return j + 1 - i
+			}
+			// "lundis" au pluriel en français
+			if wd, ok := weekdays[lang][strings.TrimSuffix(word, "s")]; ok {
+				p.result.Recurrence = "FREQ=WEEKLY"
+				if interval > 1 {
+					p.result.Recurrence += ";INTERVAL=" + strconv.Itoa(interval)
+				}
+				p.byDay(wd)
+				return j + 1 - i
+			}
+		}
+	}
+	return 0
+}
+
+// weekdayAfter reconnaît un jour de la semaine à la position i, précédé ou non
+// d'un mot de liaison, et retourne le nombre de mots consommés
+func (p *parser) weekdayAfter(i int, lang string) (time.Weekday, int) {
+	if i >= len(p.words) {
+		return 0, 0
+	}
+	for _, c := range connectors[lang] {
+		if n := p.phrase(i, c); n > 0 && i+n < len(p.words) {
+			if wd, ok := weekdays[lang][strings.TrimSuffix(p.lower[i+n], "s")]; ok {
+				return wd, n + 1
+			}
+		}
+	}
+	if wd, ok := weekdays[lang][strings.TrimSuffix(p.lower[i], "s")]; ok {
+		return wd, 1
+	}
+	return 0, 0
+}
+
+// byDay fixe le jour de la récurrence ; il ne sert d'échéance qu'à défaut de
+// date explicite, qui l'emporte qu'elle soit avant ou après
+func (p *parser) byDay(wd time.Weekday) {
+	p.result.Recurrence += ";BYDAY=" + dayCodes[wd]
+	if p.day == nil {
+		p.setDay(p.nextWeekday(wd))
+	}
+}
+
+// phrase retourne le nombre de mots de phrase si elle commence à la position i
+func (p *parser) phrase(i int, phrase string) int {
+	parts := strings.Fields(phrase)
+	if i+len(parts) > len(p.lower) {
+		return 0
+	}
+	for k, part := range parts {
+		if p.lower[i+k] != part {
+			return 0
+		}
+	}
+	return len(parts)
+}
+
+func (p *parser) setDay(d time.Time) {
+	p.day = &d
+}
+
+func (p *parser) setMonthDay(month time.Month, day string, consumed int) int {
+	d, _ := strconv.Atoi(day)
+	t := time.Date(p.now.Year(), month, d, 0, 0, 0, 0, p.now.Location())
+	if t.Month() != month {
+		return 0 // jour invalide, ex. 31 avril
+	}
+	if t.Before(p.today()) {
+		t = t.AddDate(1, 0, 0)
+	}
+	p.setDay(t)
+	return consumed
+}
+
+func (p *parser) today() time.Time {
+	y, m, d := p.now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, p.now.Location())
+}
+
+// nextWeekday retourne le prochain jour de ce nom, aujourd'hui exclu
+func (p *parser) nextWeekday(wd time.Weekday) time.Time {
+	days := (int(wd) - int(p.now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return p.today().AddDate(0, 0, days)
+}
+
+// resolveDue combine date et heure ; une heure seule déjà passée vise le lendemain
+func (p *parser) resolveDue() {
+	if p.day == nil && p.clock == nil {
+		return
+	}
+	hour, minute := defaultHour, 0
+	if p.clock != nil {
+		hour, minute = p.clock[0], p.clock[1]
+	}
+	day := p.today()
+	if p.day != nil {
+		day = *p.day
+	}
+	due := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, p.now.Location())
+	if p.day == nil && !due.After(p.now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	p.result.DueAt = &due
+}
+
+func parseClock(w string) ([2]int, bool) {
+	var hour, minute int
+	if m := timeAmPm.FindStringSubmatch(w); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			minute, _ = strconv.Atoi(m[2])
+		}
+		if hour < 1 || hour > 12 {
+			return [2]int{}, false
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	} else if m := timeColon.FindStringSubmatch(w); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+	} else if m := timeFrench.FindStringSubmatch(w); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			minute, _ = strconv.Atoi(m[2])
+		}
+	} else {
+		return [2]int{}, false
+	}
+	if hour > 23 || minute > 59 {
+		return [2]int{}, false
+	}
+	return [2]int{hour, minute}, true
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return list
+		}
+	}
+	return append(list, v)
+}
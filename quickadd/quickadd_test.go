@@ -0,0 +1,73 @@
+package quickadd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mercredi 7 août 2024, 10h
+var now = time.Date(2024, 8, 7, 10, 0, 0, 0, time.UTC)
+
+func at(day, hour, minute int) *time.Time {
+	return date(2024, 8, day, hour, minute)
+}
+
+func date(year int, month time.Month, day, hour, minute int) *time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input string
+		lang  string
+		want  Result
+	}{
+		{"Pay invoice tomorrow 5pm #finance !high every month", English, Result{
+			Title: "Pay invoice", DueAt: at(8, 17, 0), Tags: []string{"finance"}, Priority: "A", Recurrence: "FREQ=MONTHLY",
+		}},
+		{"Payer la facture demain à 17h #finance !haute tous les mois", French, Result{
+			Title: "Payer la facture", DueAt: at(8, 17, 0), Tags: []string{"finance"}, Priority: "A", Recurrence: "FREQ=MONTHLY",
+		}},
+		{"Standup prep every weekday at 9:30", "", Result{
+			Title: "Standup prep", DueAt: at(8, 9, 30), Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		}},
+		{"Rapport hebdo tous les lundis +travail @bureau !b", "", Result{
+			Title: "Rapport hebdo", DueAt: at(12, 9, 0), Projects: []string{"travail"}, Contexts: []string{"bureau"},
+			Priority: "B", Recurrence: "FREQ=WEEKLY;BYDAY=MO",
+		}},
+		{"Call Bob next friday at noon", English, Result{Title: "Call Bob", DueAt: at(9, 12, 0)}},
+		{"Appeler Paul vendredi prochain", French, Result{Title: "Appeler Paul", DueAt: at(9, 9, 0)}},
+		{"Renew passport in 2 weeks every 2 weeks", English, Result{
+			Title: "Renew passport", DueAt: at(21, 9, 0), Recurrence: "FREQ=WEEKLY;INTERVAL=2",
+		}},
+		{"Réviser dans 3 jours", French, Result{Title: "Réviser", DueAt: at(10, 9, 0)}},
+		{"Team lunch 15 august", English, Result{Title: "Team lunch", DueAt: at(15, 9, 0)}},
+		{"Vacances le 20 août !basse", French, Result{Title: "Vacances", DueAt: at(20, 9, 0), Priority: "C"}},
+		{"Backup 2024-08-30 23:00", "", Result{Title: "Backup", DueAt: at(30, 23, 0)}},
+		// la date explicite l'emporte sur le jour de la récurrence
+		{"Meet on 2026-11-03 every week on monday", English, Result{
+			Title: "Meet", DueAt: date(2026, 11, 3, 9, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO",
+		}},
+		{"Meet every monday on 2026-11-03", English, Result{
+			Title: "Meet", DueAt: date(2026, 11, 3, 9, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO",
+		}},
+		{"Sport toutes les semaines le jeudi", French, Result{
+			Title: "Sport", DueAt: at(8, 9, 0), Recurrence: "FREQ=WEEKLY;BYDAY=TH",
+		}},
+		// heure déjà passée : le lendemain
+		{"Coffee 8am", English, Result{Title: "Coffee", DueAt: at(8, 8, 0)}},
+		// sans date, les mots de liaison restent dans le titre
+		{"Meet at the station", English, Result{Title: "Meet at the station"}},
+		// le vocabulaire d'une autre langue n'est pas interprété
+		{"Acheter du pain demain", English, Result{Title: "Acheter du pain demain"}},
+		{"#only", "", Result{Title: "#only", Tags: []string{"only"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			assert.Equal(t, c.want, Parse(c.input, now, c.lang))
+		})
+	}
+}
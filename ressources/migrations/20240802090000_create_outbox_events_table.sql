@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE outbox_events (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    todo_id BIGINT(20),
+    payload LONGTEXT NOT NULL,
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at DATETIME(3),
+    dispatched_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_outbox_events_todo_id (todo_id),
+    INDEX idx_outbox_events_dispatched_at (dispatched_at)
+);
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN event_id BIGINT(20) AFTER subscription_id,
+    ADD INDEX idx_webhook_deliveries_event_id (event_id);
+
+-- +goose Down
+ALTER TABLE webhook_deliveries DROP COLUMN event_id;
+DROP TABLE outbox_events;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE todo_models
+    ADD COLUMN priority VARCHAR(1) AFTER completed,
+    ADD COLUMN due_at DATETIME(3) NULL AFTER priority,
+    ADD COLUMN completed_at DATETIME(3) NULL AFTER due_at,
+    ADD COLUMN projects VARCHAR(255) AFTER completed_at,
+    ADD COLUMN contexts VARCHAR(255) AFTER projects;
+
+-- +goose Down
+ALTER TABLE todo_models
+    DROP COLUMN contexts,
+    DROP COLUMN projects,
+    DROP COLUMN completed_at,
+    DROP COLUMN due_at,
+    DROP COLUMN priority;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE user_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    feed_token VARCHAR(64) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    UNIQUE INDEX idx_user_models_email (email),
+    UNIQUE INDEX idx_user_models_feed_token (feed_token)
+);
+
+ALTER TABLE todo_models
+    ADD COLUMN user_id BIGINT(20) AFTER id,
+    ADD COLUMN uid VARCHAR(255) AFTER user_id,
+    ADD INDEX idx_todo_models_user_id (user_id),
+    ADD INDEX idx_todo_models_uid (uid);
+
+-- +goose Down
+ALTER TABLE todo_models DROP COLUMN uid, DROP COLUMN user_id;
+DROP TABLE user_models;
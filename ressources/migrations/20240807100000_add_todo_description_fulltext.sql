@@ -0,0 +1,6 @@
+-- +goose Up
+-- l'index FULLTEXT (title, description) est créé au démarrage par SearchService.EnsureIndex
+ALTER TABLE todo_models ADD COLUMN description TEXT AFTER title;
+
+-- +goose Down
+ALTER TABLE todo_models DROP COLUMN description;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE tag_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20),
+    name VARCHAR(64) NOT NULL,
+    color VARCHAR(7) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    UNIQUE INDEX idx_tag_models_user_name (user_id, name)
+);
+
+CREATE TABLE todo_tags (
+    todo_id BIGINT(20) NOT NULL,
+    tag_id BIGINT(20) NOT NULL,
+    PRIMARY KEY (todo_id, tag_id),
+    INDEX idx_todo_tags_tag_id (tag_id),
+    CONSTRAINT fk_todo_tags_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE,
+    CONSTRAINT fk_todo_tags_tag FOREIGN KEY (tag_id) REFERENCES tag_models (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE todo_tags;
+DROP TABLE tag_models;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE todo_models ADD COLUMN position VARCHAR(255) NOT NULL DEFAULT '' AFTER contexts;
+-- même format que position.After : 8 chiffres en base 36 suivis de "i"
+UPDATE todo_models SET position = CONCAT(LPAD(LOWER(CONV(id, 10, 36)), 8, '0'), 'i');
+CREATE INDEX idx_todo_models_position ON todo_models (position);
+
+-- +goose Down
+DROP INDEX idx_todo_models_position ON todo_models;
+ALTER TABLE todo_models DROP COLUMN position;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE todo_models
+    ADD COLUMN parent_id BIGINT(20) NULL AFTER position,
+    ADD INDEX idx_todo_models_parent_id (parent_id),
+    ADD CONSTRAINT fk_todo_models_parent FOREIGN KEY (parent_id) REFERENCES todo_models (id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE todo_models
+    DROP FOREIGN KEY fk_todo_models_parent,
+    DROP INDEX idx_todo_models_parent_id,
+    DROP COLUMN parent_id;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE todo_models
+    ADD COLUMN recurrence VARCHAR(255) AFTER parent_id,
+    ADD COLUMN series_id BIGINT(20) NULL AFTER recurrence,
+    ADD INDEX idx_todo_models_series_id (series_id);
+
+-- +goose Down
+ALTER TABLE todo_models
+    DROP INDEX idx_todo_models_series_id,
+    DROP COLUMN series_id,
+    DROP COLUMN recurrence;
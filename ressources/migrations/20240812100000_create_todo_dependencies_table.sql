@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE todo_dependencies (
+    todo_id BIGINT(20) NOT NULL,
+    depends_on_id BIGINT(20) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    PRIMARY KEY (todo_id, depends_on_id),
+    INDEX idx_todo_dependencies_depends_on_id (depends_on_id),
+    CONSTRAINT fk_todo_dependencies_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE,
+    CONSTRAINT fk_todo_dependencies_depends_on FOREIGN KEY (depends_on_id) REFERENCES todo_models (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE todo_dependencies;
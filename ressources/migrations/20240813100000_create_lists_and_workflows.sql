@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE list_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20),
+    name VARCHAR(128) NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_list_models_user_id (user_id)
+);
+
+CREATE TABLE workflow_statuses (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    list_id BIGINT(20) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    label VARCHAR(64),
+    position INT NOT NULL DEFAULT 0,
+    done TINYINT(1) NOT NULL DEFAULT 0,
+    transitions VARCHAR(255),
+    UNIQUE INDEX idx_workflow_statuses_list_name (list_id, name),
+    CONSTRAINT fk_workflow_statuses_list FOREIGN KEY (list_id) REFERENCES list_models (id) ON DELETE CASCADE
+);
+
+ALTER TABLE todo_models
+    ADD COLUMN list_id BIGINT(20) NULL AFTER series_id,
+    ADD COLUMN status VARCHAR(32) AFTER list_id,
+    ADD INDEX idx_todo_models_list_id (list_id),
+    ADD INDEX idx_todo_models_status (status),
+    ADD CONSTRAINT fk_todo_models_list FOREIGN KEY (list_id) REFERENCES list_models (id) ON DELETE SET NULL;
+
+-- les todos existants suivent le flux par défaut
+UPDATE todo_models SET status = IF(completed, 'done', 'todo');
+
+-- +goose Down
+ALTER TABLE todo_models
+    DROP FOREIGN KEY fk_todo_models_list,
+    DROP INDEX idx_todo_models_status,
+    DROP INDEX idx_todo_models_list_id,
+    DROP COLUMN status,
+    DROP COLUMN list_id;
+
+DROP TABLE workflow_statuses;
+DROP TABLE list_models;
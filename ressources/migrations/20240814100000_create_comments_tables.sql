@@ -0,0 +1,53 @@
+-- +goose Up
+-- l'index FULLTEXT (body) est créé au démarrage par SearchService.EnsureIndex
+CREATE TABLE comment_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    todo_id BIGINT(20) NOT NULL,
+    author_id BIGINT(20),
+    body TEXT NOT NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_comment_models_todo_id (todo_id),
+    INDEX idx_comment_models_author_id (author_id),
+    CONSTRAINT fk_comment_models_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE
+);
+
+CREATE TABLE todo_activities (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    todo_id BIGINT(20) NOT NULL,
+    actor_id BIGINT(20),
+    action VARCHAR(32) NOT NULL,
+    changes TEXT,
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_todo_activities_todo_id (todo_id),
+    CONSTRAINT fk_todo_activities_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE
+);
+
+CREATE TABLE notification_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    actor_id BIGINT(20),
+    todo_id BIGINT(20),
+    comment_id BIGINT(20) NULL,
+    message VARCHAR(255),
+    read_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_notification_models_user_id (user_id),
+    INDEX idx_notification_models_todo_id (todo_id),
+    INDEX idx_notification_models_read_at (read_at)
+);
+
+-- identifiant de mention : partie locale de l'email, suffixée de l'id en cas de doublon
+ALTER TABLE user_models ADD COLUMN handle VARCHAR(64) NOT NULL DEFAULT '' AFTER email;
+UPDATE user_models u
+    JOIN (SELECT LOWER(SUBSTRING_INDEX(email, '@', 1)) AS local, COUNT(*) AS n FROM user_models GROUP BY local) d
+        ON d.local = LOWER(SUBSTRING_INDEX(u.email, '@', 1))
+    SET u.handle = LEFT(IF(d.n = 1, d.local, CONCAT(d.local, u.id)), 64);
+ALTER TABLE user_models ADD UNIQUE INDEX idx_user_models_handle (handle);
+
+-- +goose Down
+ALTER TABLE user_models DROP INDEX idx_user_models_handle, DROP COLUMN handle;
+DROP TABLE notification_models;
+DROP TABLE todo_activities;
+DROP TABLE comment_models;
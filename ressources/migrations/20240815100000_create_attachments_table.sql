@@ -0,0 +1,19 @@
+-- +goose Up
+-- pas de clé étrangère vers todo_models : les lignes survivent à la suppression du todo
+-- jusqu'à ce que l'abonné "attachments" efface les fichiers du blobstore
+CREATE TABLE attachment_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    todo_id BIGINT(20) NOT NULL,
+    uploader_id BIGINT(20),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(128),
+    size BIGINT(20) NOT NULL DEFAULT 0,
+    storage_key VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64),
+    created_at DATETIME(3) NOT NULL,
+    INDEX idx_attachment_models_todo_id (todo_id),
+    UNIQUE INDEX idx_attachment_models_storage_key (storage_key)
+);
+
+-- +goose Down
+DROP TABLE attachment_models;
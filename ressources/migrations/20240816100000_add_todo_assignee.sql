@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE todo_models ADD COLUMN assignee_id BIGINT(20) NULL AFTER status;
+-- index composite : le tableau de charge groupe les todos ouverts par responsable
+CREATE INDEX idx_todo_models_assignee_id ON todo_models (assignee_id);
+CREATE INDEX idx_todo_models_completed_assignee ON todo_models (completed, assignee_id);
+
+-- +goose Down
+DROP INDEX idx_todo_models_completed_assignee ON todo_models;
+DROP INDEX idx_todo_models_assignee_id ON todo_models;
+ALTER TABLE todo_models DROP COLUMN assignee_id;
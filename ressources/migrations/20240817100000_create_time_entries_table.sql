@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE todo_models ADD COLUMN estimate_minutes BIGINT(20) NULL AFTER assignee_id;
+
+CREATE TABLE time_entries (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    todo_id BIGINT(20) NOT NULL,
+    user_id BIGINT(20),
+    started_at DATETIME(3) NOT NULL,
+    ended_at DATETIME(3) NULL,
+    seconds BIGINT(20) NOT NULL DEFAULT 0,
+    note VARCHAR(255),
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_time_entries_todo_id (todo_id),
+    INDEX idx_time_entries_user_id (user_id),
+    INDEX idx_time_entries_started_at (started_at),
+    CONSTRAINT fk_time_entries_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE time_entries;
+ALTER TABLE todo_models DROP COLUMN estimate_minutes;
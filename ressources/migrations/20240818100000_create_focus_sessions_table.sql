@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE focus_sessions (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20),
+    todo_id BIGINT(20) NULL,
+    kind VARCHAR(16) NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    duration_seconds BIGINT(20) NOT NULL,
+    started_at DATETIME(3) NOT NULL,
+    paused_at DATETIME(3) NULL,
+    paused_seconds BIGINT(20) NOT NULL DEFAULT 0,
+    focus_seconds BIGINT(20) NOT NULL DEFAULT 0,
+    interruptions INT NOT NULL DEFAULT 0,
+    ended_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_focus_sessions_user_id (user_id),
+    INDEX idx_focus_sessions_todo_id (todo_id),
+    INDEX idx_focus_sessions_state (state)
+);
+
+-- +goose Down
+DROP TABLE focus_sessions;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- les todos terminés avant l'ajout de completed_at prennent leur dernière modification comme date de fin
+UPDATE todo_models SET completed_at = updated_at WHERE completed = 1 AND completed_at IS NULL;
+CREATE INDEX idx_todo_models_completed_at ON todo_models (completed_at);
+
+-- +goose Down
+DROP INDEX idx_todo_models_completed_at ON todo_models;
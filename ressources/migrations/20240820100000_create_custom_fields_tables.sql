@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE custom_fields (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    list_id BIGINT(20) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    label VARCHAR(64),
+    type VARCHAR(16) NOT NULL,
+    options TEXT,
+    required TINYINT(1) NOT NULL DEFAULT 0,
+    position INT NOT NULL DEFAULT 0,
+    UNIQUE INDEX idx_custom_fields_list_name (list_id, name),
+    CONSTRAINT fk_custom_fields_list FOREIGN KEY (list_id) REFERENCES list_models (id) ON DELETE CASCADE
+);
+
+-- une colonne par type de valeur, pour filtrer et trier sans conversion
+CREATE TABLE custom_field_values (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    todo_id BIGINT(20) NOT NULL,
+    field_id BIGINT(20) NOT NULL,
+    text_value VARCHAR(255) NULL,
+    number_value DOUBLE NULL,
+    date_value DATETIME(3) NULL,
+    bool_value TINYINT(1) NULL,
+    UNIQUE INDEX idx_custom_field_values_todo_field (todo_id, field_id),
+    INDEX idx_custom_field_values_field_id (field_id),
+    CONSTRAINT fk_custom_field_values_todo FOREIGN KEY (todo_id) REFERENCES todo_models (id) ON DELETE CASCADE,
+    CONSTRAINT fk_custom_field_values_field FOREIGN KEY (field_id) REFERENCES custom_fields (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE custom_field_values;
+DROP TABLE custom_fields;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- les todos du modèle sont un document JSON, sous-tâches imbriquées
+CREATE TABLE template_models (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20),
+    name VARCHAR(128) NOT NULL,
+    description TEXT,
+    items MEDIUMTEXT,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_template_models_user_id (user_id)
+);
+
+-- +goose Down
+DROP TABLE template_models;
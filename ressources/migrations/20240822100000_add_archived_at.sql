@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE todo_models ADD COLUMN archived_at DATETIME(3) NULL AFTER estimate_minutes;
+CREATE INDEX idx_todo_models_archived_at ON todo_models (archived_at);
+ALTER TABLE list_models ADD COLUMN archived_at DATETIME(3) NULL AFTER name;
+CREATE INDEX idx_list_models_archived_at ON list_models (archived_at);
+
+-- +goose Down
+DROP INDEX idx_list_models_archived_at ON list_models;
+ALTER TABLE list_models DROP COLUMN archived_at;
+DROP INDEX idx_todo_models_archived_at ON todo_models;
+ALTER TABLE todo_models DROP COLUMN archived_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE notification_models
+    ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE AFTER read_at,
+    ADD COLUMN email_status VARCHAR(16) NOT NULL DEFAULT '' AFTER hidden,
+    ADD COLUMN attempts BIGINT(20) NOT NULL DEFAULT 0 AFTER email_status,
+    ADD COLUMN next_email_at DATETIME(3) NULL AFTER attempts,
+    ADD COLUMN email_error VARCHAR(255) NOT NULL DEFAULT '' AFTER next_email_at;
+CREATE INDEX idx_notification_models_email_status ON notification_models (email_status);
+
+CREATE TABLE notification_preferences (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    email BOOLEAN NOT NULL DEFAULT TRUE,
+    UNIQUE INDEX idx_notification_preferences_user_type (user_id, type)
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP INDEX idx_notification_models_email_status ON notification_models;
+ALTER TABLE notification_models
+    DROP COLUMN email_error,
+    DROP COLUMN next_email_at,
+    DROP COLUMN attempts,
+    DROP COLUMN email_status,
+    DROP COLUMN hidden;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE chat_integrations (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT(20),
+    name VARCHAR(128) NOT NULL,
+    format VARCHAR(16) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    list_id BIGINT(20) NULL,
+    events VARCHAR(255),
+    digest_time VARCHAR(5),
+    timezone VARCHAR(64),
+    last_event_id BIGINT(20) NOT NULL DEFAULT 0,
+    last_digest_at DATETIME(3) NULL,
+    last_error VARCHAR(255),
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_chat_integrations_user_id (user_id),
+    INDEX idx_chat_integrations_list_id (list_id)
+);
+
+-- +goose Down
+DROP TABLE chat_integrations;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE chat_deliveries (
+    id BIGINT(20) AUTO_INCREMENT PRIMARY KEY,
+    integration_id BIGINT(20) NOT NULL,
+    event_id BIGINT(20),
+    event VARCHAR(64) NOT NULL,
+    payload LONGTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT DEFAULT 0,
+    next_attempt_at DATETIME(3),
+    last_error TEXT,
+    delivered_at DATETIME(3) NULL,
+    created_at DATETIME(3) NOT NULL,
+    updated_at DATETIME(3) NOT NULL,
+    INDEX idx_chat_deliveries_integration_id (integration_id),
+    INDEX idx_chat_deliveries_event_id (event_id),
+    INDEX idx_chat_deliveries_status (status),
+    INDEX idx_chat_deliveries_next_attempt_at (next_attempt_at)
+);
+
+-- +goose Down
+DROP TABLE chat_deliveries;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- jeton secret de l'adresse de réception des e-mails : 32 caractères hexadécimaux aléatoires
+ALTER TABLE user_models ADD COLUMN mail_token VARCHAR(64) NOT NULL DEFAULT '' AFTER feed_token;
+UPDATE user_models SET mail_token = LEFT(SHA2(CONCAT(UUID(), RAND(), id), 256), 32);
+ALTER TABLE user_models ADD UNIQUE INDEX idx_user_models_mail_token (mail_token);
+
+-- +goose Down
+ALTER TABLE user_models DROP INDEX idx_user_models_mail_token, DROP COLUMN mail_token;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- les abonnements existants, sans propriétaire, ne reçoivent plus rien
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN user_id BIGINT(20) NOT NULL DEFAULT 0 AFTER id,
+    ADD INDEX idx_webhook_subscriptions_user_id (user_id);
+
+-- +goose Down
+ALTER TABLE webhook_subscriptions DROP INDEX idx_webhook_subscriptions_user_id, DROP COLUMN user_id;
@@ -0,0 +1,320 @@
+// Package rrule gère un sous-ensemble des règles de récurrence RFC 5545 (RRULE) :
+//
+//	FREQ=DAILY;INTERVAL=2
+//	FREQ=WEEKLY;BYDAY=MO,WE,FR
+//	FREQ=MONTHLY;BYMONTHDAY=1,-1
+//	FREQ=MONTHLY;BYDAY=-1FR;COUNT=6
+//
+// Les semaines commencent le lundi (WKST=MO).
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+const (
+	untilDate     = "20060102"
+	untilDateTime = "20060102T150405Z"
+	maxPeriods    = 10000 // garde-fou pour les règles sans occurrence atteignable
+)
+
+var dayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum est un élément de BYDAY ; N vaut 0 pour tous les jours de ce nom,
+// sinon le rang dans le mois (1 le premier, -1 le dernier).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	Count      int       // nombre d'occurrences restantes, 0 = illimité
+	Until      time.Time // dernière date possible, zéro = illimitée
+}
+
+// Parse lit une règle, avec ou sans le préfixe "RRULE:"
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("empty rule")
+	}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		value = strings.ToUpper(value)
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = value
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, e := parseWeekdayNum(v)
+				if e != nil {
+					err = e
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				d, e := strconv.Atoi(v)
+				if e != nil || d == 0 || d < -31 || d > 31 {
+					err = fmt.Errorf("invalid BYMONTHDAY %q", v)
+					break
+				}
+				r.ByMonthDay = append(r.ByMonthDay, d)
+			}
+		case "WKST":
+			if value != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %q", key)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, r.validate()
+}
+
+func (r Rule) validate() error {
+	switch r.Freq {
+	case Daily:
+		if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+			return errors.New("BYDAY and BYMONTHDAY are not supported with FREQ=DAILY")
+		}
+	case Weekly:
+		if len(r.ByMonthDay) > 0 {
+			return errors.New("BYMONTHDAY is not supported with FREQ=WEEKLY")
+		}
+		for _, wd := range r.ByDay {
+			if wd.N != 0 {
+				return errors.New("numbered BYDAY is only supported with FREQ=MONTHLY")
+			}
+		}
+	case Monthly:
+		if len(r.ByDay) > 0 && len(r.ByMonthDay) > 0 {
+			return errors.New("BYDAY and BYMONTHDAY cannot be combined")
+		}
+	case "":
+		return errors.New("FREQ is required")
+	default:
+		return fmt.Errorf("unsupported FREQ %q, expected DAILY, WEEKLY or MONTHLY", r.Freq)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+	return nil
+}
+
+// String retourne la règle sous forme normalisée, sans préfixe
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = dayNames[wd.Day]
+			if wd.N != 0 {
+				days[i] = strconv.Itoa(wd.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilDateTime))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next retourne la première occurrence strictement postérieure à after, pour une
+// série commençant à start. L'heure des occurrences est celle de start.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	for k := 0; k < maxPeriods; k++ {
+		for _, c := range r.period(start, k*interval) {
+			if c.Before(start) || !c.After(after) {
+				continue
+			}
+			if !r.Until.IsZero() && c.After(r.Until) {
+				return time.Time{}, false
+			}
+			return c, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Advance calcule l'occurrence qui suit start et la règle restante pour la série,
+// dont COUNT est décrémenté. ok est faux si la série est terminée.
+func (r Rule) Advance(start time.Time) (next time.Time, rest Rule, ok bool) {
+	if r.Count == 1 {
+		return time.Time{}, r, false
+	}
+	next, ok = r.Next(start, start)
+	rest = r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, rest, ok
+}
+
+// period retourne les occurrences candidates, triées, de la période décalée de
+// offset unités (jours, semaines ou mois) par rapport à celle de start.
+func (r Rule) period(start time.Time, offset int) []time.Time {
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+	y, m, d := start.Date()
+
+	switch r.Freq {
+	case Daily:
+		return []time.Time{at(y, m, d+offset)}
+	case Weekly:
+		monday := d - (int(start.Weekday())+6)%7 + 7*offset
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Day: start.Weekday()}}
+		}
+		var out []time.Time
+		for _, wd := range days {
+			out = append(out, at(y, m, monday+(int(wd.Day)+6)%7))
+		}
+		return sortUnique(out)
+	}
+
+	first := time.Date(y, m+time.Month(offset), 1, 0, 0, 0, 0, start.Location())
+	fy, fm, _ := first.Date()
+	length := time.Date(fy, fm+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	var out []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = length + md + 1
+			}
+			if md >= 1 && md <= length {
+				out = append(out, at(fy, fm, md))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			// jours du mois portant ce nom
+			var matches []int
+			for md := 1 + (int(wd.Day)-int(first.Weekday())+7)%7; md <= length; md += 7 {
+				matches = append(matches, md)
+			}
+			switch {
+			case wd.N == 0:
+				for _, md := range matches {
+					out = append(out, at(fy, fm, md))
+				}
+			case wd.N > 0 && wd.N <= len(matches):
+				out = append(out, at(fy, fm, matches[wd.N-1]))
+			case wd.N < 0 && -wd.N <= len(matches):
+				out = append(out, at(fy, fm, matches[len(matches)+wd.N]))
+			}
+		}
+	default:
+		// un 31 est ignoré les mois plus courts (RFC 5545 §3.3.10)
+		if d <= length {
+			out = append(out, at(fy, fm, d))
+		}
+	}
+	return sortUnique(out)
+}
+
+func sortUnique(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	out := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	name, num := v[len(v)-2:], v[:len(v)-2]
+	wd := WeekdayNum{Day: -1}
+	for i, n := range dayNames {
+		if n == name {
+			wd.Day = time.Weekday(i)
+		}
+	}
+	if wd.Day < 0 {
+		return wd, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	if num != "" {
+		n, err := strconv.Atoi(num)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return wd, fmt.Errorf("invalid BYDAY %q", v)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	if t, err := time.Parse(untilDateTime, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(untilDate, v)
+	if err != nil {
+		return t, fmt.Errorf("invalid UNTIL %q", v)
+	}
+	// une date seule inclut toute la journée
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+func positive(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number %q", v)
+	}
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// occurrences retourne les n premières occurrences d'une série
+func occurrences(t *testing.T, rule string, start string, n int) []string {
+	r, err := Parse(rule)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	cur := date(start)
+	out := []string{cur.Format("2006-01-02 Mon")}
+	for len(out) < n {
+		next, rest, ok := r.Advance(cur)
+		if !ok {
+			break
+		}
+		out = append(out, next.Format("2006-01-02 Mon"))
+		cur, r = next, rest
+	}
+	return out
+}
+
+func TestOccurrences(t *testing.T) {
+	assert.Equal(t, []string{"2024-08-01 Thu", "2024-08-03 Sat", "2024-08-05 Mon"},
+		occurrences(t, "FREQ=DAILY;INTERVAL=2", "2024-08-01 09:00", 3))
+	assert.Equal(t, []string{"2024-08-01 Thu", "2024-08-02 Fri", "2024-08-05 Mon", "2024-08-07 Wed"},
+		occurrences(t, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR", "2024-08-01 09:00", 4))
+	assert.Equal(t, []string{"2024-08-07 Wed", "2024-08-19 Mon", "2024-08-21 Wed", "2024-09-02 Mon"},
+		occurrences(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "2024-08-07 09:00", 4))
+	assert.Equal(t, []string{"2024-01-31 Wed", "2024-03-31 Sun", "2024-05-31 Fri"},
+		occurrences(t, "FREQ=MONTHLY", "2024-01-31 09:00", 3))
+	assert.Equal(t, []string{"2024-01-15 Mon", "2024-01-31 Wed", "2024-02-15 Thu", "2024-02-29 Thu"},
+		occurrences(t, "FREQ=MONTHLY;BYMONTHDAY=15,-1", "2024-01-15 09:00", 4))
+	assert.Equal(t, []string{"2024-01-26 Fri", "2024-02-23 Fri", "2024-03-29 Fri"},
+		occurrences(t, "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "2024-01-26 09:00", 10))
+	assert.Equal(t, []string{"2024-08-05 Mon", "2024-11-04 Mon"},
+		occurrences(t, "FREQ=MONTHLY;INTERVAL=3;BYDAY=1MO;UNTIL=20250101", "2024-08-05 09:00", 3)[:2])
+	assert.Equal(t, []string{"2024-08-30 Fri", "2024-08-31 Sat"},
+		occurrences(t, "FREQ=DAILY;UNTIL=20240831", "2024-08-30 09:00", 5))
+}
+
+func TestNextKeepsTimeOfDay(t *testing.T) {
+	r, _ := Parse("FREQ=WEEKLY;BYDAY=TU")
+	next, ok := r.Next(date("2024-08-01 07:30"), date("2024-08-10 12:00"))
+	assert.True(t, ok)
+	assert.Equal(t, date("2024-08-13 07:30"), next)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+		"FREQ=DAILY;BYHOUR=9",
+	} {
+		_, err := Parse(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestString(t *testing.T) {
+	r, err := Parse("freq=monthly;byday=-1fr,2mo;count=4;interval=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR,2MO;COUNT=4", r.String())
+	r, _ = Parse("FREQ=DAILY;UNTIL=20240831T100000Z")
+	assert.Equal(t, "FREQ=DAILY;UNTIL=20240831T100000Z", r.String())
+}
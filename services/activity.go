@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const (
+	TimelineComment = "comment"
+	TimelineChange  = "change"
+)
+
+// TimelineEntry est un élément du fil d'un todo : un commentaire ou une modification
+type TimelineEntry struct {
+	Kind     string               `json:"kind"` // TimelineComment ou TimelineChange
+	At       time.Time            `json:"at"`
+	ActorID  uint                 `json:"actor_id"`
+	Comment  *models.CommentModel `json:"comment,omitempty"`
+	Activity *models.TodoActivity `json:"activity,omitempty"`
+}
+
+// recordActivity ajoute une entrée à l'historique ; une mise à jour sans changement n'est pas enregistrée
+func recordActivity(tx *gorm.DB, todoID, actorID uint, action string, changes models.Changes) error {
+	if action == models.ActivityUpdated && len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&models.TodoActivity{TodoID: todoID, ActorID: actorID, Action: action, Changes: changes}).Error
+}
+
+// todoChanges compare les champs modifiables d'un todo avant et après une mise à jour
+func todoChanges(before, after models.TodoModel) models.Changes {
+	changes := models.Changes{}
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes[field] = models.Change{From: from, To: to}
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("priority", before.Priority, after.Priority)
+	add("due_at", timeValue(before.DueAt), timeValue(after.DueAt))
+	add("status", before.Status, after.Status)
+	add("completed", before.Completed, after.Completed)
+	add("projects", before.Projects, after.Projects)
+	add("contexts", before.Contexts, after.Contexts)
+	add("recurrence", before.Recurrence, after.Recurrence)
+	add("parent_id", idValue(before.ParentID), idValue(after.ParentID))
+	add("list_id", idValue(before.ListID), idValue(after.ListID))
+	add("assignee_id", idValue(before.AssigneeID), idValue(after.AssigneeID))
+	add("estimate_minutes", intValue(before.EstimateMinutes), intValue(after.EstimateMinutes))
+	add("tags", tagNames(before.Tags), tagNames(after.Tags))
+	for name, value := range before.Fields {
+		add("fields."+name, value, after.Fields[name])
+	}
+	for name, value := range after.Fields {
+		add("fields."+name, before.Fields[name], value)
+	}
+	return changes
+}
+
+// activityAction résume une mise à jour : terminer ou rouvrir prime sur une modification
+func activityAction(before, after models.TodoModel) string {
+	switch {
+	case after.Completed && !before.Completed:
+		return models.ActivityCompleted
+	case !after.Completed && before.Completed:
+		return models.ActivityReopened
+	}
+	return models.ActivityUpdated
+}
+
+// timeline fusionne commentaires et historique par ordre chronologique
+func timeline(comments []models.CommentModel, activities []models.TodoActivity) []TimelineEntry {
+	entries := make([]TimelineEntry, 0, len(comments)+len(activities))
+	for i := range comments {
+		c := &comments[i]
+		entries = append(entries, TimelineEntry{Kind: TimelineComment, At: c.CreatedAt, ActorID: c.AuthorID, Comment: c})
+	}
+	for i := range activities {
+		a := &activities[i]
+		entries = append(entries, TimelineEntry{Kind: TimelineChange, At: a.CreatedAt, ActorID: a.ActorID, Activity: a})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.Before(entries[j].At) })
+	return entries
+}
+
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func idValue(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+func intValue(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
+
+func tagNames(tags []models.TagModel) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
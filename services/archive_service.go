@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+// DefaultArchiveAfter est le délai après lequel un todo terminé est archivé automatiquement
+const DefaultArchiveAfter = 30 * 24 * time.Hour
+
+// archiveBatch est le nombre de todos archivés par transaction par ArchiveCompleted
+const archiveBatch = 500
+
+// ArchiveService range les todos et les listes à part, sans les supprimer : ils
+// disparaissent des listes par défaut mais gardent leur historique
+type ArchiveService interface {
+	ArchiveTodo(userID, id uint) (models.TodoModel, error)
+	UnarchiveTodo(userID, id uint) (models.TodoModel, error)
+	ArchiveList(userID, id uint) (models.ListModel, error)
+	UnarchiveList(userID, id uint) (models.ListModel, error)
+	ArchiveCompleted(before time.Time) (int64, error)
+}
+
+func NewArchiveServiceImp(db *gorm.DB) *ArchiveServiceImp {
+	return &ArchiveServiceImp{Db: db, Now: time.Now}
+}
+
+type ArchiveServiceImp struct {
+	Db  *gorm.DB
+	Now func() time.Time // horloge, remplaçable dans les tests
+}
+
+// ArchiveTodo archive le todo avec ses sous-tâches
+func (s *ArchiveServiceImp) ArchiveTodo(userID, id uint) (models.TodoModel, error) {
+	var todo models.TodoModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if todo, err = findUserTodo(tx, userID, id); err != nil {
+			return err
+		}
+		subtasks, err := descendants(tx, id)
+		if err != nil {
+			return err
+		}
+		return archiveTodos(tx, append([]models.TodoModel{todo}, subtasks...), s.now(), userID)
+	})
+	if err != nil {
+		return models.TodoModel{}, err
+	}
+	return findTodo(s.Db, id)
+}
+
+// UnarchiveTodo restaure le todo, ses sous-tâches et ses parents, pour qu'il réapparaisse à sa place
+func (s *ArchiveServiceImp) UnarchiveTodo(userID, id uint) (models.TodoModel, error) {
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		todo, err := findUserTodo(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		todos, err := descendants(tx, id)
+		if err != nil {
+			return err
+		}
+		todos = append(todos, todo)
+		for parentID := todo.ParentID; parentID != nil; {
+			parent, err := findTodo(tx, *parentID)
+			if err != nil {
+				return err
+			}
+			todos = append(todos, parent)
+			parentID = parent.ParentID
+		}
+		return unarchiveTodos(tx, todos, userID)
+	})
+	if err != nil {
+		return models.TodoModel{}, err
+	}
+	return findTodo(s.Db, id)
+}
+
+// ArchiveList archive la liste et ceux de ses todos qui ne l'étaient pas encore
+func (s *ArchiveServiceImp) ArchiveList(userID, id uint) (models.ListModel, error) {
+	var list models.ListModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if list, err = NewListServiceImp(tx).find(tx, userID, id); err != nil {
+			return err
+		}
+		if list.ArchivedAt != nil {
+			return nil
+		}
+		now := s.now()
+		var todos []models.TodoModel
+		if err := tx.Where("list_id = ? AND archived_at IS NULL", id).Find(&todos).Error; err != nil {
+			return err
+		}
+		if err := archiveTodos(tx, todos, now, userID); err != nil {
+			return err
+		}
+		list.ArchivedAt = &now
+		return tx.Model(&list).UpdateColumn("archived_at", now).Error
+	})
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+// UnarchiveList restaure la liste et les todos archivés avec elle ; ceux archivés
+// auparavant, un par un, le restent
+func (s *ArchiveServiceImp) UnarchiveList(userID, id uint) (models.ListModel, error) {
+	var list models.ListModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if list, err = NewListServiceImp(tx).find(tx, userID, id); err != nil {
+			return err
+		}
+		if list.ArchivedAt == nil {
+			return nil
+		}
+		var todos []models.TodoModel
+		if err := tx.Where("list_id = ? AND archived_at >= ?", id, *list.ArchivedAt).Find(&todos).Error; err != nil {
+			return err
+		}
+		if err := unarchiveTodos(tx, todos, userID); err != nil {
+			return err
+		}
+		list.ArchivedAt = nil
+		return tx.Model(&list).UpdateColumn("archived_at", nil).Error
+	})
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+// ArchiveCompleted archive les todos terminés avant before et retourne leur nombre. Chaque
+// lot est archivé dans sa propre transaction : en cas d'erreur, les lots précédents le restent.
+func (s *ArchiveServiceImp) ArchiveCompleted(before time.Time) (int64, error) {
+	var archived int64
+	var batch []models.TodoModel
+	now := s.now()
+	res := s.Db.Where("completed = ? AND completed_at < ? AND archived_at IS NULL", true, before).
+		FindInBatches(&batch, archiveBatch, func(*gorm.DB, int) error {
+			err := s.Db.Transaction(func(tx *gorm.DB) error {
+				return archiveTodos(tx, batch, now, 0)
+			})
+			if err != nil {
+				return err
+			}
+			archived += int64(len(batch))
+			return nil
+		})
+	return archived, res.Error
+}
+
+// Run archive périodiquement les todos terminés depuis plus de after, jusqu'à l'annulation de ctx
+func (s *ArchiveServiceImp) Run(ctx context.Context, interval, after time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.ArchiveCompleted(s.now().Add(-after)); err != nil {
+			log.Printf("Error archiving completed todos: %v", err)
+		} else if n > 0 {
+			log.Printf("Archived %d completed todos", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// now est tronquée à la milliseconde, la précision des colonnes DATETIME(3)
+func (s *ArchiveServiceImp) now() time.Time {
+	return s.Now().Truncate(time.Millisecond)
+}
+
+func archiveTodos(tx *gorm.DB, todos []models.TodoModel, now time.Time, actorID uint) error {
+	var ids []uint
+	for _, todo := range todos {
+		if todo.ArchivedAt == nil {
+			ids = append(ids, todo.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.TodoModel{}).Where("id IN ?", ids).UpdateColumn("archived_at", now).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := recordActivity(tx, id, actorID, models.ActivityArchived, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unarchiveTodos(tx *gorm.DB, todos []models.TodoModel, actorID uint) error {
+	var ids []uint
+	for _, todo := range todos {
+		if todo.ArchivedAt != nil {
+			ids = append(ids, todo.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(&models.TodoModel{}).Where("id IN ?", ids).UpdateColumn("archived_at", nil).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := recordActivity(tx, id, actorID, models.ActivityUnarchived, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/exchange"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveTodo(t *testing.T) {
+	db := initSQLiteDB(t)
+	todos := services.NewTodoServiceImp(db, "")
+	service := services.NewArchiveServiceImp(db)
+
+	parent, err := todos.Create(models.TodoModel{UserID: 1, Title: "Release"})
+	require.NoError(t, err)
+	child, err := todos.Create(models.TodoModel{UserID: 1, Title: "Changelog", ParentID: &parent.ID})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Other"})
+	require.NoError(t, err)
+
+	archived, err := service.ArchiveTodo(1, parent.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, archived.ArchivedAt)
+	visible, err := todos.List(services.TodoFilter{UserID: 1})
+	require.NoError(t, err)
+	require.Len(t, visible, 1)
+	assert.Equal(t, "Other", visible[0].Title)
+	all, err := todos.List(services.TodoFilter{UserID: 1, Archived: true})
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	// restaurer une sous-tâche restaure aussi son parent
+	restored, err := service.UnarchiveTodo(1, child.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.ArchivedAt)
+	visible, _ = todos.List(services.TodoFilter{UserID: 1})
+	assert.Len(t, visible, 3)
+
+	var actions []string
+	db.Model(&models.TodoActivity{}).Where("todo_id = ?", child.ID).Order("id").Pluck("action", &actions)
+	assert.Equal(t, []string{models.ActivityCreated, models.ActivityArchived, models.ActivityUnarchived}, actions)
+
+	_, err = service.ArchiveTodo(1, 99)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+}
+
+func TestArchiveList(t *testing.T) {
+	db := initSQLiteDB(t)
+	todos := services.NewTodoServiceImp(db, "")
+	lists := services.NewListServiceImp(db)
+	service := services.NewArchiveServiceImp(db)
+	now := time.Date(2024, 8, 22, 10, 0, 0, 0, time.Local)
+	service.Now = func() time.Time { return now }
+
+	list, err := lists.Create(models.ListModel{UserID: 1, Name: "Q2"})
+	require.NoError(t, err)
+	early, err := todos.Create(models.TodoModel{UserID: 1, Title: "Kickoff", ListID: &list.ID})
+	require.NoError(t, err)
+	late, err := todos.Create(models.TodoModel{UserID: 1, Title: "Retro", ListID: &list.ID})
+	require.NoError(t, err)
+	_, err = service.ArchiveTodo(1, early.ID)
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	archived, err := service.ArchiveList(1, list.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, archived.ArchivedAt)
+	mine, _ := lists.List(1, false)
+	assert.Empty(t, mine)
+	mine, _ = lists.List(1, true)
+	assert.Len(t, mine, 1)
+	board, err := lists.Board(1, list.ID)
+	require.NoError(t, err)
+	for _, column := range board.Columns {
+		assert.Empty(t, column.Todos)
+	}
+
+	// seuls les todos archivés avec la liste sont restaurés
+	_, err = service.UnarchiveList(1, list.ID)
+	require.NoError(t, err)
+	visible, _ := todos.List(services.TodoFilter{UserID: 1, ListID: &list.ID})
+	require.Len(t, visible, 1)
+	assert.Equal(t, late.ID, visible[0].ID)
+	mine, _ = lists.List(1, false)
+	assert.Len(t, mine, 1)
+
+	_, err = service.ArchiveList(2, list.ID)
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+}
+
+func TestArchiveCompleted(t *testing.T) {
+	db := initSQLiteDB(t)
+	todos := services.NewTodoServiceImp(db, "")
+	service := services.NewArchiveServiceImp(db)
+
+	old, err := todos.Create(models.TodoModel{UserID: 1, Title: "Old", Completed: true})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Recent", Completed: true})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Open"})
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.TodoModel{}).Where("id = ?", old.ID).
+		UpdateColumn("completed_at", time.Now().AddDate(0, 0, -40)).Error)
+
+	n, err := service.ArchiveCompleted(time.Now().Add(-services.DefaultArchiveAfter))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	visible, _ := todos.List(services.TodoFilter{UserID: 1})
+	require.Len(t, visible, 2)
+	assert.Equal(t, "Recent", visible[0].Title)
+
+	n, err = service.ArchiveCompleted(time.Now().Add(-services.DefaultArchiveAfter))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// les todos archivés ne sont ni exportés ni publiés dans le calendrier
+	var exported strings.Builder
+	require.NoError(t, todos.Export(1, &exported, exchange.FormatTodoTxt))
+	assert.NotContains(t, exported.String(), "Old")
+	assert.Equal(t, 2, strings.Count(exported.String(), "\n"))
+	feed, err := services.NewCalendarServiceImp(db, todos).Todos(1)
+	require.NoError(t, err)
+	assert.Len(t, feed, 2)
+
+	// plusieurs lots
+	longAgo := time.Now().AddDate(0, 0, -40)
+	many := make([]models.TodoModel, 501)
+	for i := range many {
+		many[i] = models.TodoModel{UserID: 2, Title: "Batch", Completed: true, CompletedAt: &longAgo}
+	}
+	require.NoError(t, db.Create(&many).Error)
+	n, err = service.ArchiveCompleted(time.Now().Add(-services.DefaultArchiveAfter))
+	require.NoError(t, err)
+	assert.Equal(t, int64(501), n)
+	var left int64
+	db.Model(&models.TodoModel{}).Where("user_id = ? AND archived_at IS NULL", 2).Count(&left)
+	assert.Zero(t, left)
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/blobstore"
+	"github.com/go-todo1/events"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	ErrAttachmentType     = errors.New("attachment type is not allowed")
+	ErrEmptyAttachment    = errors.New("attachment is empty")
+)
+
+// allowedAttachmentTypes sont les types détectés (http.DetectContentType) acceptés à l'envoi
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+type AttachmentService interface {
+	List(userID, todoID uint) ([]models.AttachmentModel, error)
+	Upload(todoID, uploaderID uint, fileName string, r io.Reader, size int64) (models.AttachmentModel, error)
+	Open(userID, id uint) (models.AttachmentModel, io.ReadSeekCloser, error)
+	Delete(userID, id uint) error
+	Purge(evt events.Event) error
+}
+
+func NewAttachmentServiceImp(db *gorm.DB, store blobstore.Store, maxSize int64) *AttachmentServiceImp {
+	return &AttachmentServiceImp{Db: db, Store: store, MaxSize: maxSize}
+}
+
+type AttachmentServiceImp struct {
+	Db      *gorm.DB
+	Store   blobstore.Store
+	MaxSize int64 // taille maximale d'un fichier en octets
+}
+
+func (s *AttachmentServiceImp) List(userID, todoID uint) ([]models.AttachmentModel, error) {
+	if _, err := findMemberTodo(s.Db, userID, todoID); err != nil {
+		return nil, err
+	}
+	var attachments []models.AttachmentModel
+	if err := s.Db.Where("todo_id = ?", todoID).Order("created_at").Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Upload vérifie la taille et le type réel du contenu (et non l'extension), écrit le
+// fichier dans le blobstore puis enregistre ses métadonnées
+func (s *AttachmentServiceImp) Upload(todoID, uploaderID uint, fileName string, r io.Reader, size int64) (models.AttachmentModel, error) {
+	if size == 0 {
+		return models.AttachmentModel{}, ErrEmptyAttachment
+	}
+	if size < 0 || size > s.MaxSize {
+		return models.AttachmentModel{}, ErrAttachmentTooLarge
+	}
+	if _, err := findMemberTodo(s.Db, uploaderID, todoID); err != nil {
+		return models.AttachmentModel{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return models.AttachmentModel{}, err
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedAttachmentTypes[contentType] {
+		return models.AttachmentModel{}, fmt.Errorf("%w: %s", ErrAttachmentType, contentType)
+	}
+
+	suffix, err := randomHex(16)
+	if err != nil {
+		return models.AttachmentModel{}, err
+	}
+	attachment := models.AttachmentModel{
+		TodoID:      todoID,
+		UploaderID:  uploaderID,
+		FileName:    attachmentName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  fmt.Sprintf("todos/%d/%s", todoID, suffix),
+	}
+
+	ctx := context.Background()
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), r), hash)
+	if err := s.Store.Put(ctx, attachment.StorageKey, body, size, contentType); err != nil {
+		return models.AttachmentModel{}, err
+	}
+	attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+	if err := s.Db.Create(&attachment).Error; err != nil {
+		if delErr := s.Store.Delete(ctx, attachment.StorageKey); delErr != nil {
+			log.Printf("Failed to delete orphan blob %s: %v", attachment.StorageKey, delErr)
+		}
+		return models.AttachmentModel{}, err
+	}
+	return attachment, nil
+}
+
+// Open retourne les métadonnées et le contenu, à fermer par l'appelant
+func (s *AttachmentServiceImp) Open(userID, id uint) (models.AttachmentModel, io.ReadSeekCloser, error) {
+	attachment, err := s.find(userID, id)
+	if err != nil {
+		return models.AttachmentModel{}, nil, err
+	}
+	f, err := s.Store.Open(context.Background(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return models.AttachmentModel{}, nil, ErrAttachmentNotFound
+		}
+		return models.AttachmentModel{}, nil, err
+	}
+	return attachment, f, nil
+}
+
+// Delete supprime la ligne puis le fichier : un échec du blobstore laisse au pire un fichier orphelin.
+// L'assigné ne peut supprimer que les pièces jointes qu'il a envoyées.
+func (s *AttachmentServiceImp) Delete(userID, id uint) error {
+	attachment, err := s.find(userID, id)
+	if err != nil {
+		return err
+	}
+	if attachment.UploaderID != userID {
+		if _, err := findUserTodo(s.Db, userID, attachment.TodoID); err != nil {
+			return ErrAttachmentNotFound
+		}
+	}
+	if err := s.Db.Delete(&attachment).Error; err != nil {
+		return err
+	}
+	return s.Store.Delete(context.Background(), attachment.StorageKey)
+}
+
+// Purge est abonné au dispatcher : à la suppression d'un todo, ses pièces jointes sont
+// effacées du blobstore puis de la base. Rejouer l'événement est sans effet.
+func (s *AttachmentServiceImp) Purge(evt events.Event) error {
+	if evt.Type != events.TodoDeleted {
+		return nil
+	}
+	var attachments []models.AttachmentModel
+	if err := s.Db.Where("todo_id = ?", evt.TodoID).Find(&attachments).Error; err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := s.Store.Delete(context.Background(), attachment.StorageKey); err != nil {
+			return err
+		}
+		if err := s.Db.Delete(&attachment).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find retourne une pièce jointe d'un todo que l'utilisateur possède ou qui lui est assigné
+func (s *AttachmentServiceImp) find(userID, id uint) (models.AttachmentModel, error) {
+	var attachment models.AttachmentModel
+	err := s.Db.Joins("JOIN todo_models ON todo_models.id = attachment_models.todo_id").
+		Where("attachment_models.id = ? AND (todo_models.user_id = ? OR todo_models.assignee_id = ?)", id, userID, userID).First(&attachment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.AttachmentModel{}, ErrAttachmentNotFound
+		}
+		return models.AttachmentModel{}, err
+	}
+	return attachment, nil
+}
+
+// attachmentName garde le nom de base du fichier envoyé, sans caractères de contrôle
+func attachmentName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return truncate(name, 255)
+}
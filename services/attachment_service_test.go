@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/blobstore"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentService(t *testing.T) {
+	db := initSQLiteDB(t)
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	attachments := services.NewAttachmentServiceImp(db, store, 1024)
+	todos := services.NewTodoServiceImp(db, "")
+
+	todo, err := todos.Create(models.TodoModel{UserID: 7, Title: "Bug report"})
+	require.NoError(t, err)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 600)...)
+	shot, err := attachments.Upload(todo.ID, 7, `C:\Users\me\screen shot.png`, bytes.NewReader(png), int64(len(png)))
+	require.NoError(t, err)
+	assert.Equal(t, "screen shot.png", shot.FileName)
+	assert.Equal(t, "image/png", shot.ContentType)
+	assert.Equal(t, int64(len(png)), shot.Size)
+	sum := sha256.Sum256(png)
+	assert.Equal(t, hex.EncodeToString(sum[:]), shot.Checksum)
+
+	_, err = attachments.Upload(todo.ID, 7, "notes.txt", strings.NewReader("hello"), 5)
+	assert.NoError(t, err)
+
+	_, err = attachments.Upload(todo.ID, 7, "evil.png", strings.NewReader("<html><script>alert(1)</script>"), 31)
+	assert.ErrorIs(t, err, services.ErrAttachmentType, "the type is sniffed from the content")
+	_, err = attachments.Upload(todo.ID, 7, "big.txt", strings.NewReader(strings.Repeat("a", 2000)), 2000)
+	assert.ErrorIs(t, err, services.ErrAttachmentTooLarge)
+	_, err = attachments.Upload(todo.ID, 7, "empty.txt", strings.NewReader(""), 0)
+	assert.ErrorIs(t, err, services.ErrEmptyAttachment)
+	_, err = attachments.Upload(999, 7, "notes.txt", strings.NewReader("hello"), 5)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = attachments.Upload(todo.ID, 8, "notes.txt", strings.NewReader("hello"), 5)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound, "only on the user's own todos")
+	_, _, err = attachments.Open(8, shot.ID)
+	assert.ErrorIs(t, err, services.ErrAttachmentNotFound)
+
+	list, err := attachments.List(7, todo.ID)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	meta, content, err := attachments.Open(7, shot.ID)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, png, data)
+	assert.Equal(t, shot.StorageKey, meta.StorageKey)
+
+	assert.NoError(t, attachments.Delete(7, list[1].ID))
+	assert.ErrorIs(t, attachments.Delete(7, list[1].ID), services.ErrAttachmentNotFound)
+
+	// la suppression du todo passe par l'outbox : l'abonné efface les fichiers
+	dispatcher := events.NewDispatcher(db)
+	dispatcher.Subscribe("attachments", attachments.Purge)
+	require.NoError(t, todos.Delete(7, todo.ID))
+	_, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+
+	var left int64
+	db.Model(&models.AttachmentModel{}).Count(&left)
+	assert.Zero(t, left)
+	_, err = store.Open(context.Background(), shot.StorageKey)
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+	assert.NoError(t, attachments.Purge(events.Event{Type: events.TodoDeleted, TodoID: todo.ID}), "replaying the event is harmless")
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/ical"
+	"gorm.io/gorm"
+)
+
+// CalendarService adresse les todos d'un utilisateur par leur UID iCalendar ;
+// les écritures passent par TodoService pour conserver ses règles et ses événements.
+type CalendarService interface {
+	Todos(userID uint) ([]models.TodoModel, error)
+	Find(userID uint, uid string) (models.TodoModel, error)
+	Put(userID uint, uid string, todo models.TodoModel) (models.TodoModel, bool, error)
+	Remove(userID uint, uid string) error
+}
+
+func NewCalendarServiceImp(db *gorm.DB, todos TodoService) *CalendarServiceImp {
+	return &CalendarServiceImp{Db: db, Service: todos}
+}
+
+type CalendarServiceImp struct {
+	Db      *gorm.DB
+	Service TodoService
+}
+
+func (s *CalendarServiceImp) Todos(userID uint) ([]models.TodoModel, error) {
+	var todos []models.TodoModel
+	err := s.Db.Where("user_id = ? AND archived_at IS NULL", userID).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+func (s *CalendarServiceImp) Find(userID uint, uid string) (models.TodoModel, error) {
+	var todo models.TodoModel
+	query := s.Db.Where("user_id = ?", userID)
+	if id, ok := ical.TodoIDFromUID(uid); ok {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("uid = ?", uid)
+	}
+	if err := query.First(&todo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TodoModel{}, ErrTodoNotFound
+		}
+		return models.TodoModel{}, err
+	}
+	return todo, nil
+}
+
+// Put crée ou met à jour le todo portant cet UID ; le booléen indique une création
+func (s *CalendarServiceImp) Put(userID uint, uid string, todo models.TodoModel) (models.TodoModel, bool, error) {
+	existing, err := s.Find(userID, uid)
+	switch {
+	case err == nil:
+		// un VTODO remplace le todo en entier, son statut compris
+		updated, err := s.Service.Update(userID, existing.ID, TodoPatch{TodoModel: todo, Completed: &todo.Completed, Replace: true})
+		return updated, false, err
+	case errors.Is(err, ErrTodoNotFound):
+		todo.ID = 0
+		todo.UserID = userID
+		todo.UID = uid
+		created, err := s.Service.Create(todo)
+		return created, true, err
+	default:
+		return models.TodoModel{}, false, err
+	}
+}
+
+func (s *CalendarServiceImp) Remove(userID uint, uid string) error {
+	todo, err := s.Find(userID, uid)
+	if err != nil {
+		return err
+	}
+	return s.Service.Delete(userID, todo.ID)
+}
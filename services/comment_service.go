@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/markdown"
+	"github.com/go-todo1/notifications"
+	"gorm.io/gorm"
+)
+
+const maxCommentLength = 10000
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrNotAuthor       = errors.New("only the author can change a comment")
+	ErrNoAuthor        = errors.New("the author is required")
+)
+
+type CommentService interface {
+	List(userID, todoID uint) ([]models.CommentModel, error)
+	Create(comment models.CommentModel) (models.CommentModel, error)
+	Update(authorID, id uint, body string) (models.CommentModel, error)
+	Delete(authorID, id uint) error
+	Timeline(userID, todoID uint) ([]TimelineEntry, error)
+}
+
+func NewCommentServiceImp(db *gorm.DB) *CommentServiceImp {
+	return &CommentServiceImp{Db: db}
+}
+
+type CommentServiceImp struct {
+	Db *gorm.DB
+}
+
+// List retourne les commentaires d'un todo dont l'utilisateur est le propriétaire ou l'assigné
+func (s *CommentServiceImp) List(userID, todoID uint) ([]models.CommentModel, error) {
+	if _, err := findMemberTodo(s.Db, userID, todoID); err != nil {
+		return nil, err
+	}
+	var comments []models.CommentModel
+	if err := s.Db.Where("todo_id = ?", todoID).Order("created_at").Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		comments[i].BodyHTML = markdown.ToHTML(comments[i].Body)
+	}
+	return comments, nil
+}
+
+// Create enregistre le commentaire et notifie les utilisateurs mentionnés ;
+// l'auteur doit être le propriétaire du todo ou son assigné
+func (s *CommentServiceImp) Create(comment models.CommentModel) (models.CommentModel, error) {
+	if comment.ID != 0 {
+		return models.CommentModel{}, errors.New("invalid ID")
+	}
+	if comment.AuthorID == 0 {
+		return models.CommentModel{}, ErrNoAuthor
+	}
+	if err := normalizeComment(&comment); err != nil {
+		return models.CommentModel{}, err
+	}
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		todo, err := findMemberTodo(tx, comment.AuthorID, comment.TodoID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return notifyMentions(tx, todo, comment, nil)
+	})
+	if err != nil {
+		return models.CommentModel{}, err
+	}
+	comment.BodyHTML = markdown.ToHTML(comment.Body)
+	return comment, nil
+}
+
+// Update modifie le corps ; seules les nouvelles mentions sont notifiées
+func (s *CommentServiceImp) Update(authorID, id uint, body string) (models.CommentModel, error) {
+	var comment models.CommentModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if comment, err = s.authored(tx, authorID, id); err != nil {
+			return err
+		}
+		previous := markdown.Mentions(comment.Body)
+		comment.Body = body
+		if err := normalizeComment(&comment); err != nil {
+			return err
+		}
+		if err := tx.Model(&comment).Updates(map[string]interface{}{"body": comment.Body, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		todo, err := findTodo(tx, comment.TodoID)
+		if err != nil {
+			return err
+		}
+		return notifyMentions(tx, todo, comment, previous)
+	})
+	if err != nil {
+		return models.CommentModel{}, err
+	}
+	comment.BodyHTML = markdown.ToHTML(comment.Body)
+	return comment, nil
+}
+
+func (s *CommentServiceImp) Delete(authorID, id uint) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		comment, err := s.authored(tx, authorID, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&models.NotificationModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&comment).Error
+	})
+}
+
+// Timeline retourne les commentaires et l'historique du todo, du plus ancien au plus récent
+func (s *CommentServiceImp) Timeline(userID, todoID uint) ([]TimelineEntry, error) {
+	comments, err := s.List(userID, todoID)
+	if err != nil {
+		return nil, err
+	}
+	var activities []models.TodoActivity
+	if err := s.Db.Where("todo_id = ?", todoID).Order("created_at").Order("id").Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	return timeline(comments, activities), nil
+}
+
+func (s *CommentServiceImp) authored(tx *gorm.DB, authorID, id uint) (models.CommentModel, error) {
+	var comment models.CommentModel
+	if err := tx.First(&comment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.CommentModel{}, ErrCommentNotFound
+		}
+		return models.CommentModel{}, err
+	}
+	if comment.AuthorID != authorID {
+		return models.CommentModel{}, ErrNotAuthor
+	}
+	return comment, nil
+}
+
+// notifyMentions crée une notification pour chaque utilisateur mentionné dans le commentaire,
+// sauf l'auteur et ceux déjà mentionnés dans previous
+func notifyMentions(tx *gorm.DB, todo models.TodoModel, comment models.CommentModel, previous []string) error {
+	already := map[string]bool{}
+	for _, handle := range previous {
+		already[handle] = true
+	}
+	var handles []string
+	for _, handle := range markdown.Mentions(comment.Body) {
+		if !already[handle] {
+			handles = append(handles, handle)
+		}
+	}
+	if len(handles) == 0 {
+		return nil
+	}
+
+	var users []models.UserModel
+	if err := tx.Where("handle IN ? AND id <> ?", handles, comment.AuthorID).Find(&users).Error; err != nil {
+		return err
+	}
+	author := "Someone"
+	var user models.UserModel
+	if err := tx.First(&user, comment.AuthorID).Error; err == nil {
+		author = user.Name
+	}
+	message := truncate(fmt.Sprintf("%s mentioned you on %q", author, todo.Title), 255)
+	for _, mentioned := range users {
+		commentID := comment.ID
+		err := notifications.Record(tx, models.NotificationModel{
+			UserID:    mentioned.ID,
+			Type:      models.NotificationMention,
+			ActorID:   comment.AuthorID,
+			TodoID:    todo.ID,
+			CommentID: &commentID,
+			Message:   message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeComment(comment *models.CommentModel) error {
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" {
+		return errors.New("the body is required")
+	}
+	if utf8.RuneCountInString(comment.Body) > maxCommentLength {
+		return fmt.Errorf("the body is too long, %d characters max", maxCommentLength)
+	}
+	return nil
+}
+
+func findTodo(tx *gorm.DB, id uint) (models.TodoModel, error) {
+	var todo models.TodoModel
+	if err := tx.First(&todo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TodoModel{}, ErrTodoNotFound
+		}
+		return models.TodoModel{}, err
+	}
+	return todo, nil
+}
+
+// findUserTodo est findTodo limité aux todos de l'utilisateur : ceux des autres sont introuvables
+func findUserTodo(tx *gorm.DB, userID, id uint) (models.TodoModel, error) {
+	return findTodo(tx.Where("user_id = ?", userID), id)
+}
+
+// findMemberTodo est findTodo limité aux todos que l'utilisateur possède ou qui lui sont assignés
+func findMemberTodo(tx *gorm.DB, userID, id uint) (models.TodoModel, error) {
+	return findTodo(tx.Where("(user_id = ? OR assignee_id = ?)", userID, userID), id)
+}
+
+// truncate coupe s à n octets sans couper un caractère
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentService(t *testing.T) {
+	db := initSQLiteDB(t)
+	users := services.NewUserServiceImp(db)
+	comments := services.NewCommentServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+
+	alice, err := users.Create(models.UserModel{Name: "Alice", Email: "Alice.Martin@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice.martin", alice.Handle)
+	bob, err := users.Create(models.UserModel{Name: "Bob", Email: "bob@example.com"})
+	assert.NoError(t, err)
+	bob2, err := users.Create(models.UserModel{Name: "Other Bob", Email: "bob@example.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, "bob2", bob2.Handle)
+	_, err = users.Create(models.UserModel{Name: "Dup", Email: "dup@example.com", Handle: "@Bob"})
+	assert.Error(t, err, "handle already taken")
+
+	todo, err := todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Release notes"})
+	assert.NoError(t, err)
+
+	_, err = comments.Create(models.CommentModel{TodoID: todo.ID, AuthorID: alice.ID, Body: "   "})
+	assert.Error(t, err)
+	_, err = comments.Create(models.CommentModel{TodoID: 999, AuthorID: alice.ID, Body: "hello"})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = comments.Create(models.CommentModel{TodoID: todo.ID, Body: "anonymous"})
+	assert.ErrorIs(t, err, services.ErrNoAuthor)
+	// bob n'est ni propriétaire ni assigné
+	_, err = comments.Create(models.CommentModel{TodoID: todo.ID, AuthorID: bob.ID, Body: "hello"})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = comments.List(bob.ID, todo.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+
+	first, err := comments.Create(models.CommentModel{TodoID: todo.ID, AuthorID: alice.ID, Body: "@bob can you **review**? cc @alice.martin @nobody"})
+	assert.NoError(t, err)
+	assert.Contains(t, first.BodyHTML, "<strong>review</strong>")
+
+	var notifications []models.NotificationModel
+	db.Order("id").Find(&notifications)
+	assert.Len(t, notifications, 1, "the author is not notified of their own mention")
+	assert.Equal(t, bob.ID, notifications[0].UserID)
+	assert.Equal(t, models.NotificationMention, notifications[0].Type)
+	assert.Equal(t, first.ID, *notifications[0].CommentID)
+	assert.Equal(t, `Alice mentioned you on "Release notes"`, notifications[0].Message)
+
+	_, err = comments.Update(bob.ID, first.ID, "hijacked")
+	assert.ErrorIs(t, err, services.ErrNotAuthor)
+	edited, err := comments.Update(alice.ID, first.ID, "@bob and @bob2 can you review?")
+	assert.NoError(t, err)
+	assert.Equal(t, "@bob and @bob2 can you review?", edited.Body)
+	db.Order("id").Find(&notifications)
+	assert.Len(t, notifications, 2, "only the new mention is notified")
+	assert.Equal(t, bob2.ID, notifications[1].UserID)
+
+	_, err = todos.Update(alice.ID, todo.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: bob.ID, Title: "Release notes v2", Status: "in_review"}})
+	assert.NoError(t, err)
+	db.Model(&models.TodoModel{}).Where("id = ?", todo.ID).Update("assignee_id", bob.ID)
+	second, err := comments.Create(models.CommentModel{TodoID: todo.ID, AuthorID: bob.ID, Body: "done"})
+	assert.NoError(t, err)
+
+	entries, err := comments.Timeline(bob.ID, todo.ID)
+	assert.NoError(t, err)
+	var kinds []string
+	for _, e := range entries {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []string{"change", "comment", "change", "comment"}, kinds)
+	assert.Equal(t, models.ActivityCreated, entries[0].Activity.Action)
+	assert.Equal(t, alice.ID, entries[0].ActorID)
+	change := entries[2].Activity
+	assert.Equal(t, models.ActivityUpdated, change.Action)
+	assert.Equal(t, bob.ID, change.ActorID)
+	assert.Equal(t, models.Change{From: "Release notes", To: "Release notes v2"}, change.Changes["title"])
+	assert.Equal(t, models.Change{From: "todo", To: "in_review"}, change.Changes["status"])
+	assert.Len(t, change.Changes, 2)
+
+	assert.ErrorIs(t, comments.Delete(alice.ID, second.ID), services.ErrNotAuthor)
+	assert.NoError(t, comments.Delete(alice.ID, first.ID))
+	db.Find(&notifications)
+	assert.Empty(t, notifications)
+	list, err := comments.List(alice.ID, todo.ID)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	assert.NoError(t, todos.Delete(alice.ID, todo.ID))
+	_, err = comments.Timeline(alice.ID, todo.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	var left int64
+	db.Model(&models.CommentModel{}).Count(&left)
+	assert.Zero(t, left)
+}
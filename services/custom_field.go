@@ -0,0 +1,469 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCustomFields = 30
+	maxFieldOptions = 50
+
+	// SortFieldPrefix trie sur un champ personnalisé : "field.story_points", "-field.story_points" en décroissant
+	SortFieldPrefix = "field."
+)
+
+var (
+	ErrInvalidField       = errors.New("invalid custom field")
+	ErrInvalidFieldValue  = errors.New("invalid custom field value")
+	ErrInvalidFieldFilter = errors.New("invalid custom field filter")
+	ErrFieldInUse         = errors.New("a changed custom field has values that no longer fit")
+)
+
+// FieldCondition filtre les todos sur un champ personnalisé ; Op vide : le champ a une valeur
+type FieldCondition struct {
+	Name  string
+	Op    string // =, !=, <, <=, >, >= ; les comparaisons d'ordre sont réservées aux nombres et aux dates
+	Value string
+}
+
+var fieldOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// ParseFieldCondition lit une condition "story_points>=3", "env=prod" ou "customer"
+func ParseFieldCondition(s string) (FieldCondition, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "=!<>")
+	if i < 0 {
+		i = len(s)
+	}
+	cond := FieldCondition{Name: s[:i]}
+	if !statusNameRe.MatchString(cond.Name) {
+		return FieldCondition{}, fmt.Errorf("%w: invalid field name %q", ErrInvalidFieldFilter, cond.Name)
+	}
+	if i == len(s) {
+		return cond, nil
+	}
+	for _, op := range fieldOps {
+		if strings.HasPrefix(s[i:], op) {
+			cond.Op, cond.Value = op, s[i+len(op):]
+			return cond, nil
+		}
+	}
+	return FieldCondition{}, fmt.Errorf("%w: invalid operator in %q", ErrInvalidFieldFilter, s)
+}
+
+func orderedFields(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// SetFields remplace les champs personnalisés de la liste. Les champs sont reconnus par leur nom :
+// un champ conservé garde ses valeurs, un champ retiré les perd. Changer le type d'un champ
+// renseigné ou retirer une option encore utilisée est refusé.
+func (s *ListServiceImp) SetFields(userID, id uint, fields []models.CustomField) (models.ListModel, error) {
+	fields, err := normalizeFields(fields)
+	if err != nil {
+		return models.ListModel{}, err
+	}
+
+	var list models.ListModel
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if list, err = s.find(tx, userID, id); err != nil {
+			return err
+		}
+		existing := make(map[string]models.CustomField, len(list.Fields))
+		for _, field := range list.Fields {
+			existing[field.Name] = field
+		}
+		for i, field := range fields {
+			field.ListID = id
+			old, ok := existing[field.Name]
+			if !ok {
+				if err := tx.Create(&field).Error; err != nil {
+					return err
+				}
+				fields[i] = field
+				continue
+			}
+			delete(existing, field.Name)
+			if err := checkFieldChange(tx, old, field); err != nil {
+				return err
+			}
+			field.ID = old.ID
+			err := tx.Model(&old).Updates(map[string]interface{}{
+				"label": field.Label, "type": field.Type, "options": field.Options, "required": field.Required, "position": field.Position,
+			}).Error
+			if err != nil {
+				return err
+			}
+			fields[i] = field
+		}
+		for _, removed := range existing {
+			if err := tx.Where("field_id = ?", removed.ID).Delete(&models.CustomFieldValue{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&removed).Error; err != nil {
+				return err
+			}
+		}
+		list.Fields = fields
+		return nil
+	})
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+// checkFieldChange refuse une modification qui rendrait des valeurs existantes invalides
+func checkFieldChange(tx *gorm.DB, old, field models.CustomField) error {
+	values := tx.Model(&models.CustomFieldValue{}).Where("field_id = ?", old.ID)
+	var count int64
+	switch {
+	case old.Type != field.Type:
+		if err := values.Count(&count).Error; err != nil {
+			return err
+		}
+	case field.Type == models.FieldEnum:
+		if err := values.Where("text_value NOT IN ?", []string(field.Options)).Count(&count).Error; err != nil {
+			return err
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrFieldInUse, field.Name)
+	}
+	return nil
+}
+
+// normalizeFields vérifie les champs envoyés et renumérote leurs positions
+func normalizeFields(fields []models.CustomField) ([]models.CustomField, error) {
+	if len(fields) > maxCustomFields {
+		return nil, fmt.Errorf("%w: at most %d fields expected", ErrInvalidField, maxCustomFields)
+	}
+	names := map[string]bool{}
+	out := make([]models.CustomField, len(fields))
+	for i, field := range fields {
+		field.Name = strings.TrimSpace(field.Name)
+		if !statusNameRe.MatchString(field.Name) {
+			return nil, fmt.Errorf("%w: invalid field name %q", ErrInvalidField, field.Name)
+		}
+		if names[field.Name] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidField, field.Name)
+		}
+		names[field.Name] = true
+		field.Label = strings.TrimSpace(field.Label)
+		if field.Label == "" {
+			field.Label = field.Name
+		}
+		if len(field.Label) > 64 {
+			return nil, fmt.Errorf("%w: label of %q is too long", ErrInvalidField, field.Name)
+		}
+		switch field.Type {
+		case models.FieldText, models.FieldNumber, models.FieldDate, models.FieldCheckbox:
+			field.Options = nil
+		case models.FieldEnum:
+			options, err := normalizeOptions(field)
+			if err != nil {
+				return nil, err
+			}
+			field.Options = options
+		default:
+			return nil, fmt.Errorf("%w: type of %q must be text, number, date, enum or checkbox", ErrInvalidField, field.Name)
+		}
+		field.ID, field.ListID, field.Position = 0, 0, i
+		out[i] = field
+	}
+	return out, nil
+}
+
+func normalizeOptions(field models.CustomField) (models.FieldOptions, error) {
+	if len(field.Options) == 0 || len(field.Options) > maxFieldOptions {
+		return nil, fmt.Errorf("%w: enum %q needs between 1 and %d options", ErrInvalidField, field.Name, maxFieldOptions)
+	}
+	seen := map[string]bool{}
+	options := make(models.FieldOptions, 0, len(field.Options))
+	for _, option := range field.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len(option) > 255 || seen[option] {
+			return nil, fmt.Errorf("%w: invalid or duplicate option %q for %q", ErrInvalidField, option, field.Name)
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// setFieldValues enregistre les valeurs envoyées, par nom de champ de la liste du todo ; nil ou ""
+// efface la valeur. À la création, tous les champs obligatoires doivent être renseignés.
+func setFieldValues(tx *gorm.DB, todo *models.TodoModel, values map[string]interface{}, creating bool) error {
+	if todo.ListID == nil {
+		if len(values) > 0 {
+			return fmt.Errorf("%w: todos without a list have no custom fields", ErrInvalidFieldValue)
+		}
+		return nil
+	}
+	var fields []models.CustomField
+	if err := tx.Where("list_id = ?", *todo.ListID).Find(&fields).Error; err != nil {
+		return err
+	}
+	byName := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	// nouvelle map : l'ancienne peut être partagée avec l'état avant modification
+	output := make(map[string]interface{}, len(todo.Fields)+len(values))
+	for name, value := range todo.Fields {
+		output[name] = value
+	}
+	for name, raw := range values {
+		field, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidFieldValue, name)
+		}
+		if text, ok := raw.(string); ok && strings.TrimSpace(text) == "" {
+			raw = nil
+		}
+		if err := tx.Where("todo_id = ? AND field_id = ?", todo.ID, field.ID).Delete(&models.CustomFieldValue{}).Error; err != nil {
+			return err
+		}
+		if raw == nil {
+			if field.Required {
+				return fmt.Errorf("%w: %q is required", ErrInvalidFieldValue, name)
+			}
+			delete(output, name)
+			continue
+		}
+		value, err := parseFieldValue(field, raw)
+		if err != nil {
+			return err
+		}
+		value.TodoID, value.FieldID = todo.ID, field.ID
+		if err := tx.Create(&value).Error; err != nil {
+			return err
+		}
+		output[name] = fieldOutput(field.Type, value)
+	}
+	if creating {
+		for _, field := range fields {
+			if field.Required && output[field.Name] == nil {
+				return fmt.Errorf("%w: %q is required", ErrInvalidFieldValue, field.Name)
+			}
+		}
+	}
+	todo.Fields = nil
+	if len(output) > 0 {
+		todo.Fields = output
+	}
+	return nil
+}
+
+// dropStaleFieldValues supprime les valeurs des champs qui n'appartiennent pas à la nouvelle liste du todo
+func dropStaleFieldValues(tx *gorm.DB, todo models.TodoModel) error {
+	query := tx.Where("todo_id = ?", todo.ID)
+	if todo.ListID != nil {
+		query = query.Where("field_id NOT IN (?)", tx.Model(&models.CustomField{}).Select("id").Where("list_id = ?", *todo.ListID))
+	}
+	return query.Delete(&models.CustomFieldValue{}).Error
+}
+
+// parseFieldValue convertit une valeur JSON dans la colonne du type du champ
+func parseFieldValue(field models.CustomField, raw interface{}) (models.CustomFieldValue, error) {
+	invalid := func(expected string) (models.CustomFieldValue, error) {
+		return models.CustomFieldValue{}, fmt.Errorf("%w: %q expects %s", ErrInvalidFieldValue, field.Name, expected)
+	}
+	var value models.CustomFieldValue
+	switch field.Type {
+	case models.FieldText:
+		text, ok := raw.(string)
+		if !ok || len(text) > 255 {
+			return invalid("a text of at most 255 bytes")
+		}
+		value.TextValue = &text
+	case models.FieldEnum:
+		text, ok := raw.(string)
+		if !ok || !field.Options.Contains(text) {
+			return invalid("one of " + strings.Join(field.Options, ", "))
+		}
+		value.TextValue = &text
+	case models.FieldNumber:
+		number, ok := raw.(float64)
+		if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+			return invalid("a number")
+		}
+		value.NumberValue = &number
+	case models.FieldDate:
+		text, _ := raw.(string)
+		date, err := time.Parse("2006-01-02", text)
+		if err != nil {
+			return invalid("a date formatted as 2006-01-02")
+		}
+		value.DateValue = &date
+	case models.FieldCheckbox:
+		checked, ok := raw.(bool)
+		if !ok {
+			return invalid("true or false")
+		}
+		value.BoolValue = &checked
+	}
+	return value, nil
+}
+
+// fieldOutput retourne la valeur telle qu'elle est envoyée en JSON
+func fieldOutput(fieldType string, value models.CustomFieldValue) interface{} {
+	switch fieldType {
+	case models.FieldNumber:
+		return *value.NumberValue
+	case models.FieldDate:
+		return value.DateValue.UTC().Format("2006-01-02")
+	case models.FieldCheckbox:
+		return *value.BoolValue
+	}
+	return *value.TextValue
+}
+
+// loadFields renseigne les valeurs des champs personnalisés d'un todo rangé dans une liste
+func loadFields(tx *gorm.DB, todo *models.TodoModel) error {
+	if todo.ListID == nil {
+		return nil
+	}
+	todos := []models.TodoModel{*todo}
+	if err := withFields(tx, todos); err != nil {
+		return err
+	}
+	todo.Fields = todos[0].Fields
+	return nil
+}
+
+// withFields renseigne les valeurs des champs personnalisés des todos
+func withFields(db *gorm.DB, todos []models.TodoModel) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	index := make(map[uint]int, len(todos))
+	for i, todo := range todos {
+		ids[i], index[todo.ID] = todo.ID, i
+	}
+	var rows []struct {
+		models.CustomFieldValue
+		Name string
+		Type string
+	}
+	err := db.Table("custom_field_values").
+		Select("custom_field_values.*, custom_fields.name, custom_fields.type").
+		Joins("JOIN custom_fields ON custom_fields.id = custom_field_values.field_id").
+		Where("custom_field_values.todo_id IN ?", ids).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		todo := &todos[index[row.TodoID]]
+		if todo.Fields == nil {
+			todo.Fields = map[string]interface{}{}
+		}
+		todo.Fields[row.Name] = fieldOutput(row.Type, row.CustomFieldValue)
+	}
+	return nil
+}
+
+// fieldColumn retourne le type d'un champ nommé et la colonne de ses valeurs. Sans liste filtrée,
+// le nom doit désigner des champs du même type dans toutes les listes de l'utilisateur.
+func fieldColumn(db *gorm.DB, filter TodoFilter, name string) ([]uint, string, string, error) {
+	query := db.Model(&models.CustomField{}).
+		Joins("JOIN list_models ON list_models.id = custom_fields.list_id").
+		Where("custom_fields.name = ? AND list_models.user_id = ?", name, filter.UserID)
+	if filter.ListID != nil {
+		query = query.Where("custom_fields.list_id = ?", *filter.ListID)
+	}
+	var fields []models.CustomField
+	if err := query.Select("custom_fields.id, custom_fields.type").Find(&fields).Error; err != nil {
+		return nil, "", "", err
+	}
+	if len(fields) == 0 {
+		return nil, "", "", fmt.Errorf("%w: unknown field %q", ErrInvalidFieldFilter, name)
+	}
+	ids := make([]uint, len(fields))
+	for i, field := range fields {
+		if field.Type != fields[0].Type {
+			return nil, "", "", fmt.Errorf("%w: %q has different types across lists, filter by list", ErrInvalidFieldFilter, name)
+		}
+		ids[i] = field.ID
+	}
+	columns := map[string]string{
+		models.FieldText: "text_value", models.FieldEnum: "text_value", models.FieldNumber: "number_value",
+		models.FieldDate: "date_value", models.FieldCheckbox: "bool_value",
+	}
+	return ids, fields[0].Type, columns[fields[0].Type], nil
+}
+
+// fieldFilter retourne la sous-requête des todos qui remplissent la condition
+func fieldFilter(db *gorm.DB, filter TodoFilter, cond FieldCondition) (*gorm.DB, error) {
+	ids, fieldType, column, err := fieldColumn(db, filter, cond.Name)
+	if err != nil {
+		return nil, err
+	}
+	query := db.Model(&models.CustomFieldValue{}).Select("todo_id").Where("field_id IN ?", ids)
+	if cond.Op == "" {
+		return query, nil
+	}
+	ordered := fieldType == models.FieldNumber || fieldType == models.FieldDate
+	if !ordered && cond.Op != "=" && cond.Op != "!=" {
+		return nil, fmt.Errorf("%w: %s fields only support = and !=", ErrInvalidFieldFilter, fieldType)
+	}
+	arg, err := filterArg(fieldType, cond.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q expects %v", ErrInvalidFieldFilter, cond.Name, err)
+	}
+	return query.Where(column+" "+cond.Op+" ?", arg), nil
+}
+
+// sortByField trie sur la valeur d'un champ, les todos sans valeur en dernier
+func sortByField(db, query *gorm.DB, filter TodoFilter) (*gorm.DB, error) {
+	name, desc := strings.TrimPrefix(filter.Sort, "-"), strings.HasPrefix(filter.Sort, "-")
+	ids, _, column, err := fieldColumn(db, filter, strings.TrimPrefix(name, SortFieldPrefix))
+	if err != nil {
+		return nil, err
+	}
+	column = "sort_values." + column
+	query = query.Select("todo_models.*").
+		Joins("LEFT JOIN custom_field_values AS sort_values ON sort_values.todo_id = todo_models.id AND sort_values.field_id IN ?", ids).
+		Order("CASE WHEN " + column + " IS NULL THEN 1 ELSE 0 END")
+	if desc {
+		return query.Order(column + " DESC"), nil
+	}
+	return query.Order(column), nil
+}
+
+// IsFieldSort indique si le tri porte sur un champ personnalisé
+func IsFieldSort(sort string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(sort, "-"), SortFieldPrefix)
+}
+
+// filterArg convertit la valeur d'une condition dans le type de la colonne comparée
+func filterArg(fieldType, value string) (interface{}, error) {
+	switch fieldType {
+	case models.FieldNumber:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number, nil
+		}
+		return nil, errors.New("a number")
+	case models.FieldDate:
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return date, nil
+		}
+		return nil, errors.New("a date formatted as 2006-01-02")
+	case models.FieldCheckbox:
+		if value == "true" || value == "false" {
+			return value == "true", nil
+		}
+		return nil, errors.New("true or false")
+	}
+	return value, nil
+}
@@ -0,0 +1,180 @@
+package services_test
+
+import (
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldCondition(t *testing.T) {
+	for input, expected := range map[string]services.FieldCondition{
+		"story_points>=3":   {Name: "story_points", Op: ">=", Value: "3"},
+		"env=prod":          {Name: "env", Op: "=", Value: "prod"},
+		"env!=dev":          {Name: "env", Op: "!=", Value: "dev"},
+		"review<2024-09-01": {Name: "review", Op: "<", Value: "2024-09-01"},
+		"customer":          {Name: "customer"},
+	} {
+		cond, err := services.ParseFieldCondition(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, cond, input)
+	}
+	for _, input := range []string{"", "Story=3", "env!prod", "=prod"} {
+		_, err := services.ParseFieldCondition(input)
+		assert.ErrorIs(t, err, services.ErrInvalidFieldFilter, input)
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	db := initSQLiteDB(t)
+	lists := services.NewListServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+
+	list, err := lists.Create(models.ListModel{UserID: 1, Name: "Sprint"})
+	require.NoError(t, err)
+	for _, fields := range [][]models.CustomField{
+		{{Name: "Story Points", Type: models.FieldNumber}},
+		{{Name: "env", Type: "color"}},
+		{{Name: "env", Type: models.FieldEnum}},
+		{{Name: "env", Type: models.FieldText}, {Name: "env", Type: models.FieldText}},
+	} {
+		_, err := lists.SetFields(1, list.ID, fields)
+		assert.ErrorIs(t, err, services.ErrInvalidField)
+	}
+	_, err = lists.SetFields(2, list.ID, nil)
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+
+	list, err = lists.SetFields(1, list.ID, []models.CustomField{
+		{Name: "story_points", Label: "Story points", Type: models.FieldNumber},
+		{Name: "env", Type: models.FieldEnum, Options: models.FieldOptions{"dev", " prod "}, Required: true},
+		{Name: "customer", Type: models.FieldText},
+		{Name: "review", Type: models.FieldDate},
+		{Name: "urgent", Type: models.FieldCheckbox},
+	})
+	require.NoError(t, err)
+	require.Len(t, list.Fields, 5)
+	assert.Equal(t, models.FieldOptions{"dev", "prod"}, list.Fields[1].Options)
+	assert.Equal(t, "env", list.Fields[1].Label)
+
+	create := func(title string, fields map[string]interface{}) (models.TodoModel, error) {
+		return todos.Create(models.TodoModel{UserID: 1, Title: title, ListID: &list.ID, Fields: fields})
+	}
+	for _, fields := range []map[string]interface{}{
+		{"story_points": 3.0},
+		{"env": "staging"},
+		{"env": "prod", "story_points": "three"},
+		{"env": "prod", "review": "next week"},
+		{"env": "prod", "urgent": "yes"},
+		{"env": "prod", "unknown": 1.0},
+	} {
+		_, err := create("invalid", fields)
+		assert.ErrorIs(t, err, services.ErrInvalidFieldValue, fields)
+	}
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "inbox", Fields: map[string]interface{}{"env": "dev"}})
+	assert.ErrorIs(t, err, services.ErrInvalidFieldValue)
+
+	login, err := create("Login page", map[string]interface{}{"env": "prod", "story_points": 5.0, "customer": "Acme", "review": "2024-08-20", "urgent": true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "story_points": 5.0, "customer": "Acme", "review": "2024-08-20", "urgent": true}, login.Fields)
+	search, err := create("Search", map[string]interface{}{"env": "dev", "story_points": 2.0, "review": "2024-09-10", "customer": ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"env": "dev", "story_points": 2.0, "review": "2024-09-10"}, search.Fields)
+	logout, err := create("Logout", map[string]interface{}{"env": "prod", "urgent": false})
+	require.NoError(t, err)
+
+	titles := func(filter services.TodoFilter) []string {
+		filter.UserID = 1
+		found, err := todos.List(filter)
+		require.NoError(t, err)
+		names := []string{}
+		for _, todo := range found {
+			names = append(names, todo.Title)
+		}
+		return names
+	}
+	where := func(conditions ...string) services.TodoFilter {
+		var filter services.TodoFilter
+		for _, c := range conditions {
+			cond, err := services.ParseFieldCondition(c)
+			require.NoError(t, err)
+			filter.Fields = append(filter.Fields, cond)
+		}
+		return filter
+	}
+	assert.Equal(t, []string{"Login page"}, titles(where("story_points>=3")))
+	assert.Equal(t, []string{"Login page", "Logout"}, titles(where("env=prod")))
+	assert.Equal(t, []string{"Search"}, titles(where("env!=prod")))
+	assert.Equal(t, []string{"Logout"}, titles(where("urgent=false")))
+	assert.Equal(t, []string{"Login page"}, titles(where("customer")))
+	assert.Equal(t, []string{"Login page"}, titles(where("review<2024-09-01", "env=prod")))
+
+	sorted := where()
+	sorted.Sort = "field.story_points"
+	assert.Equal(t, []string{"Search", "Login page", "Logout"}, titles(sorted), "todos without a value come last")
+	sorted.Sort = "-field.story_points"
+	assert.Equal(t, []string{"Login page", "Search", "Logout"}, titles(sorted))
+	found, err := todos.List(services.TodoFilter{UserID: 1, Sort: "-field.story_points"})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, found[0].Fields["story_points"], "sorting keeps the todo columns")
+	assert.Equal(t, login.ID, found[0].ID)
+
+	for _, filter := range []services.TodoFilter{where("owner=me"), where("env>prod"), where("story_points>=many"), {Sort: "field.owner"}} {
+		filter.UserID = 1
+		_, err := todos.List(filter)
+		assert.ErrorIs(t, err, services.ErrInvalidFieldFilter)
+	}
+
+	// mise à jour : les champs absents sont inchangés, nil efface, un champ obligatoire ne peut être effacé
+	updated, err := todos.Update(1, login.ID, services.TodoPatch{TodoModel: models.TodoModel{UserID: 1, Fields: map[string]interface{}{"story_points": 8.0, "customer": nil}}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "story_points": 8.0, "review": "2024-08-20", "urgent": true}, updated.Fields)
+	_, err = todos.Update(1, login.ID, services.TodoPatch{TodoModel: models.TodoModel{UserID: 1, Fields: map[string]interface{}{"env": nil}}})
+	assert.ErrorIs(t, err, services.ErrInvalidFieldValue)
+	var activity models.TodoActivity
+	require.NoError(t, db.Where("todo_id = ?", login.ID).Order("id DESC").First(&activity).Error)
+	assert.Equal(t, models.Change{From: 5.0, To: 8.0}, activity.Changes["fields.story_points"])
+	assert.Equal(t, models.Change{From: "Acme", To: nil}, activity.Changes["fields.customer"])
+
+	// une option utilisée ne peut être retirée, ni le type d'un champ renseigné changé
+	_, err = lists.SetFields(1, list.ID, []models.CustomField{{Name: "env", Type: models.FieldEnum, Options: models.FieldOptions{"prod"}}})
+	assert.ErrorIs(t, err, services.ErrFieldInUse)
+	_, err = lists.SetFields(1, list.ID, []models.CustomField{{Name: "story_points", Type: models.FieldText}})
+	assert.ErrorIs(t, err, services.ErrFieldInUse)
+	list, err = lists.SetFields(1, list.ID, []models.CustomField{
+		{Name: "env", Type: models.FieldEnum, Options: models.FieldOptions{"dev", "prod", "staging"}},
+		{Name: "story_points", Label: "Points", Type: models.FieldNumber},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Points", list.Fields[1].Label)
+	found, err = todos.List(services.TodoFilter{UserID: 1, ListID: &list.ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"env": "prod", "story_points": 8.0}, found[0].Fields, "removed fields lose their values")
+
+	// un todo qui quitte la liste perd ses valeurs
+	inbox := uint(0)
+	moved, err := todos.Update(1, logout.ID, services.TodoPatch{TodoModel: models.TodoModel{UserID: 1, ListID: &inbox}})
+	require.NoError(t, err)
+	assert.Nil(t, moved.Fields)
+	var count int64
+	db.Model(&models.CustomFieldValue{}).Where("todo_id = ?", logout.ID).Count(&count)
+	assert.Zero(t, count)
+
+	// l'occurrence suivante d'un todo récurrent reprend ses valeurs
+	weekly, err := create("Weekly demo", map[string]interface{}{"env": "dev", "story_points": 1.0})
+	require.NoError(t, err)
+	_, err = todos.Update(1, weekly.ID, services.TodoPatch{TodoModel: models.TodoModel{UserID: 1, Recurrence: "FREQ=WEEKLY"}})
+	require.NoError(t, err)
+	done, err := todos.Update(1, weekly.ID, services.TodoPatch{TodoModel: models.TodoModel{UserID: 1}, Completed: boolPtr(true)})
+	require.NoError(t, err)
+	require.NotNil(t, done.Next)
+	found, err = todos.List(services.TodoFilter{UserID: 1, Fields: where("story_points=1").Fields})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+
+	require.NoError(t, todos.Delete(1, search.ID))
+	require.NoError(t, lists.Delete(1, list.ID))
+	db.Model(&models.CustomFieldValue{}).Count(&count)
+	assert.Zero(t, count)
+}
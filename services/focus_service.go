@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrFocusNotFound     = errors.New("focus session not found")
+	ErrFocusActive       = errors.New("a focus session is already in progress")
+	ErrInvalidFocus      = errors.New("invalid focus session, expected kind work or break and 1 to 180 minutes")
+	ErrFocusStateChanged = errors.New("the focus session cannot do this in its current state")
+)
+
+// Durées par défaut d'un pomodoro
+const (
+	DefaultWorkMinutes  = 25
+	DefaultBreakMinutes = 5
+	maxFocusMinutes     = 180
+)
+
+// FocusTodoStats est le temps de concentration passé sur un todo ; TodoID 0 : sessions sans todo
+type FocusTodoStats struct {
+	TodoID        uint   `json:"todo_id"`
+	Title         string `json:"title"`
+	Seconds       int64  `json:"seconds"`
+	Sessions      int64  `json:"sessions"`
+	Interruptions int64  `json:"interruptions"`
+}
+
+// FocusStats résume les sessions de travail terminées sur une période
+type FocusStats struct {
+	Seconds       int64            `json:"seconds"`
+	Sessions      int64            `json:"sessions"`
+	Interruptions int64            `json:"interruptions"`
+	Todos         []FocusTodoStats `json:"todos"`
+}
+
+type FocusService interface {
+	Start(session models.FocusSession) (models.FocusSession, error)
+	Pause(userID, id uint) (models.FocusSession, error)
+	Resume(userID, id uint) (models.FocusSession, error)
+	Complete(userID, id uint) (models.FocusSession, error)
+	Current(userID uint) (*models.FocusSession, error)
+	Stats(userID uint, from, to time.Time) (FocusStats, error)
+	Subscribe(userID uint) (<-chan models.FocusSession, func())
+}
+
+func NewFocusServiceImp(db *gorm.DB) *FocusServiceImp {
+	return &FocusServiceImp{Db: db, Now: time.Now, subscribers: map[uint]map[chan models.FocusSession]bool{}}
+}
+
+// FocusServiceImp prévient les abonnés (flux SSE) de chaque changement d'état d'une session
+type FocusServiceImp struct {
+	Db  *gorm.DB
+	Now func() time.Time // horloge, remplaçable dans les tests
+
+	mu          sync.Mutex
+	subscribers map[uint]map[chan models.FocusSession]bool
+}
+
+// Start démarre une session ; une seule session active par utilisateur
+func (s *FocusServiceImp) Start(session models.FocusSession) (models.FocusSession, error) {
+	if session.Kind == "" {
+		session.Kind = models.FocusWork
+	}
+	if session.DurationSeconds == 0 {
+		session.DurationSeconds = DefaultWorkMinutes * 60
+		if session.Kind == models.FocusBreak {
+			session.DurationSeconds = DefaultBreakMinutes * 60
+		}
+	}
+	if (session.Kind != models.FocusWork && session.Kind != models.FocusBreak) ||
+		session.DurationSeconds < 60 || session.DurationSeconds > maxFocusMinutes*60 {
+		return models.FocusSession{}, ErrInvalidFocus
+	}
+	if session.TodoID != nil && *session.TodoID == 0 {
+		session.TodoID = nil
+	}
+
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if session.TodoID != nil {
+			// le todo doit appartenir à l'utilisateur ou lui être assigné
+			if _, err := findMemberTodo(tx, session.UserID, *session.TodoID); err != nil {
+				return err
+			}
+		}
+		current, err := s.current(tx, session.UserID)
+		if err != nil {
+			return err
+		}
+		if current != nil {
+			return ErrFocusActive
+		}
+		session.ID, session.State, session.StartedAt = 0, models.FocusRunning, s.Now()
+		session.PausedAt, session.EndedAt = nil, nil
+		session.PausedSeconds, session.FocusSeconds, session.Interruptions = 0, 0, 0
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return models.FocusSession{}, err
+	}
+	return s.changed(session), nil
+}
+
+// Pause suspend le décompte ; pendant une session de travail, elle compte comme une interruption
+func (s *FocusServiceImp) Pause(userID, id uint) (models.FocusSession, error) {
+	return s.transition(userID, id, func(session *models.FocusSession, now time.Time) (map[string]interface{}, error) {
+		if session.State != models.FocusRunning {
+			return nil, ErrFocusStateChanged
+		}
+		session.State, session.PausedAt = models.FocusPaused, &now
+		if session.Kind == models.FocusWork {
+			session.Interruptions++
+		}
+		return map[string]interface{}{"state": session.State, "paused_at": now, "interruptions": session.Interruptions}, nil
+	})
+}
+
+func (s *FocusServiceImp) Resume(userID, id uint) (models.FocusSession, error) {
+	return s.transition(userID, id, func(session *models.FocusSession, now time.Time) (map[string]interface{}, error) {
+		if session.State != models.FocusPaused {
+			return nil, ErrFocusStateChanged
+		}
+		session.PausedSeconds += int64(now.Sub(*session.PausedAt).Seconds())
+		session.State, session.PausedAt = models.FocusRunning, nil
+		return map[string]interface{}{"state": session.State, "paused_at": nil, "paused_seconds": session.PausedSeconds}, nil
+	})
+}
+
+// Complete termine la session, avant la fin prévue si besoin ; seul le temps effectif est compté
+func (s *FocusServiceImp) Complete(userID, id uint) (models.FocusSession, error) {
+	return s.transition(userID, id, func(session *models.FocusSession, now time.Time) (map[string]interface{}, error) {
+		if session.State == models.FocusCompleted {
+			return nil, ErrFocusStateChanged
+		}
+		return finish(session, now), nil
+	})
+}
+
+// Current retourne la session active de l'utilisateur, nil s'il n'y en a pas. Une session
+// arrivée à son terme est terminée à la lecture.
+func (s *FocusServiceImp) Current(userID uint) (*models.FocusSession, error) {
+	var session *models.FocusSession
+	var ended bool
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		session, err = s.current(tx, userID)
+		if err != nil || session == nil {
+			return err
+		}
+		if session.State == models.FocusRunning && remaining(*session, s.Now()) <= 0 {
+			// la session s'est terminée à l'heure prévue, pas à l'heure de lecture
+			end := session.StartedAt.Add(time.Duration(session.DurationSeconds+session.PausedSeconds) * time.Second)
+			ended = true
+			return tx.Model(session).Updates(finish(session, end)).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, nil
+	}
+	if ended {
+		s.changed(*session)
+		return nil, nil
+	}
+	session.RemainingSeconds = remaining(*session, s.Now())
+	return session, nil
+}
+
+// Stats additionne les sessions de travail terminées de l'utilisateur entre from et to (bornes facultatives), par todo
+func (s *FocusServiceImp) Stats(userID uint, from, to time.Time) (FocusStats, error) {
+	query := s.Db.Table("focus_sessions").
+		Select("COALESCE(focus_sessions.todo_id, 0) AS todo_id, COALESCE(todo_models.title, '') AS title, "+
+			"SUM(focus_sessions.focus_seconds) AS seconds, COUNT(*) AS sessions, SUM(focus_sessions.interruptions) AS interruptions").
+		Joins("LEFT JOIN todo_models ON todo_models.id = focus_sessions.todo_id").
+		Where("focus_sessions.user_id = ? AND focus_sessions.state = ? AND focus_sessions.kind = ?", userID, models.FocusCompleted, models.FocusWork).
+		Group("focus_sessions.todo_id, todo_models.title").
+		Order("seconds DESC")
+	if !from.IsZero() {
+		query = query.Where("focus_sessions.started_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("focus_sessions.started_at < ?", to)
+	}
+	stats := FocusStats{Todos: []FocusTodoStats{}}
+	if err := query.Scan(&stats.Todos).Error; err != nil {
+		return FocusStats{}, err
+	}
+	for _, t := range stats.Todos {
+		stats.Seconds += t.Seconds
+		stats.Sessions += t.Sessions
+		stats.Interruptions += t.Interruptions
+	}
+	return stats, nil
+}
+
+// Subscribe retourne les changements de session de l'utilisateur ; cancel libère l'abonnement
+func (s *FocusServiceImp) Subscribe(userID uint) (<-chan models.FocusSession, func()) {
+	ch := make(chan models.FocusSession, 8)
+	s.mu.Lock()
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = map[chan models.FocusSession]bool{}
+	}
+	s.subscribers[userID][ch] = true
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers[userID], ch)
+		if len(s.subscribers[userID]) == 0 {
+			delete(s.subscribers, userID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// changed calcule le temps restant et prévient les abonnés sans bloquer sur un client lent
+func (s *FocusServiceImp) changed(session models.FocusSession) models.FocusSession {
+	session.RemainingSeconds = remaining(session, s.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers[session.UserID] {
+		select {
+		case ch <- session:
+		default:
+		}
+	}
+	return session
+}
+
+func (s *FocusServiceImp) transition(userID, id uint, apply func(*models.FocusSession, time.Time) (map[string]interface{}, error)) (models.FocusSession, error) {
+	var session models.FocusSession
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).First(&session, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrFocusNotFound
+			}
+			return err
+		}
+		updates, err := apply(&session, s.Now())
+		if err != nil {
+			return err
+		}
+		return tx.Model(&session).Updates(updates).Error
+	})
+	if err != nil {
+		return models.FocusSession{}, err
+	}
+	return s.changed(session), nil
+}
+
+func (s *FocusServiceImp) current(tx *gorm.DB, userID uint) (*models.FocusSession, error) {
+	var session models.FocusSession
+	err := tx.Where("user_id = ? AND state <> ?", userID, models.FocusCompleted).Order("id DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// elapsed est le temps de concentration écoulé à l'instant now, pauses exclues
+func elapsed(session models.FocusSession, now time.Time) int64 {
+	if session.State == models.FocusCompleted {
+		return session.FocusSeconds
+	}
+	if session.PausedAt != nil {
+		now = *session.PausedAt
+	}
+	return int64(now.Sub(session.StartedAt).Seconds()) - session.PausedSeconds
+}
+
+func remaining(session models.FocusSession, now time.Time) int64 {
+	if session.State == models.FocusCompleted {
+		return 0
+	}
+	if left := session.DurationSeconds - elapsed(session, now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// finish fixe le temps effectif, plafonné à la durée prévue, et retourne les colonnes modifiées
+func finish(session *models.FocusSession, now time.Time) map[string]interface{} {
+	if session.PausedAt != nil {
+		session.PausedSeconds += int64(now.Sub(*session.PausedAt).Seconds())
+		session.PausedAt = nil
+	}
+	focus := int64(now.Sub(session.StartedAt).Seconds()) - session.PausedSeconds
+	if focus > session.DurationSeconds {
+		focus = session.DurationSeconds
+	}
+	if focus < 0 {
+		focus = 0
+	}
+	session.State, session.FocusSeconds, session.EndedAt = models.FocusCompleted, focus, &now
+	return map[string]interface{}{
+		"state":          session.State,
+		"paused_at":      nil,
+		"paused_seconds": session.PausedSeconds,
+		"focus_seconds":  session.FocusSeconds,
+		"ended_at":       now,
+	}
+}
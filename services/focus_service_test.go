@@ -0,0 +1,114 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFocusService(t *testing.T) {
+	db := initSQLiteDB(t)
+	todos := services.NewTodoServiceImp(db, "")
+	focus := services.NewFocusServiceImp(db)
+	now := time.Date(2024, 8, 12, 9, 0, 0, 0, time.UTC)
+	focus.Now = func() time.Time { return now }
+
+	todo, err := todos.Create(models.TodoModel{UserID: 1, Title: "Write spec"})
+	require.NoError(t, err)
+
+	_, err = focus.Start(models.FocusSession{UserID: 1, Kind: "nap"})
+	assert.ErrorIs(t, err, services.ErrInvalidFocus)
+	_, err = focus.Start(models.FocusSession{UserID: 1, DurationSeconds: 4 * 3600})
+	assert.ErrorIs(t, err, services.ErrInvalidFocus)
+	_, err = focus.Start(models.FocusSession{UserID: 1, TodoID: &[]uint{999}[0]})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = focus.Start(models.FocusSession{UserID: 2, TodoID: &todo.ID})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound, "only on the user's own todos")
+
+	updates, cancel := focus.Subscribe(1)
+	defer cancel()
+
+	session, err := focus.Start(models.FocusSession{UserID: 1, TodoID: &todo.ID})
+	require.NoError(t, err)
+	assert.Equal(t, models.FocusWork, session.Kind)
+	assert.Equal(t, int64(25*60), session.RemainingSeconds)
+	assert.Equal(t, session.ID, (<-updates).ID)
+	_, err = focus.Start(models.FocusSession{UserID: 1})
+	assert.ErrorIs(t, err, services.ErrFocusActive)
+
+	// 10 minutes de travail, 5 de pause, puis le reste
+	now = now.Add(10 * time.Minute)
+	paused, err := focus.Pause(1, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FocusPaused, paused.State)
+	assert.Equal(t, 1, paused.Interruptions)
+	assert.Equal(t, int64(15*60), paused.RemainingSeconds)
+	assert.Equal(t, models.FocusPaused, (<-updates).State)
+	_, err = focus.Pause(1, session.ID)
+	assert.ErrorIs(t, err, services.ErrFocusStateChanged)
+	_, err = focus.Pause(2, session.ID)
+	assert.ErrorIs(t, err, services.ErrFocusNotFound, "sessions belong to their user")
+
+	now = now.Add(5 * time.Minute)
+	current, err := focus.Current(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15*60), current.RemainingSeconds, "the countdown is frozen during a pause")
+	resumed, err := focus.Resume(1, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), resumed.PausedSeconds)
+	<-updates
+
+	// la session arrive à son terme sans appel explicite : elle est terminée à la lecture
+	now = now.Add(20 * time.Minute)
+	current, err = focus.Current(1)
+	assert.NoError(t, err)
+	assert.Nil(t, current)
+	done := <-updates
+	assert.Equal(t, models.FocusCompleted, done.State)
+	assert.Equal(t, int64(25*60), done.FocusSeconds)
+	assert.Equal(t, time.Date(2024, 8, 12, 9, 30, 0, 0, time.UTC), done.EndedAt.UTC())
+
+	// une pause courte, puis une session arrêtée avant la fin
+	pause, err := focus.Start(models.FocusSession{UserID: 1, Kind: models.FocusBreak})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5*60), pause.DurationSeconds)
+	now = now.Add(5 * time.Minute)
+	_, err = focus.Complete(1, pause.ID)
+	assert.NoError(t, err)
+	short, err := focus.Start(models.FocusSession{UserID: 1, TodoID: &todo.ID, DurationSeconds: 50 * 60})
+	require.NoError(t, err)
+	now = now.Add(12 * time.Minute)
+	short, err = focus.Complete(1, short.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12*60), short.FocusSeconds)
+	_, err = focus.Complete(1, short.ID)
+	assert.ErrorIs(t, err, services.ErrFocusStateChanged)
+	_, err = focus.Start(models.FocusSession{UserID: 1, DurationSeconds: 60})
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = focus.Current(1)
+	assert.NoError(t, err)
+
+	stats, err := focus.Stats(1, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(37*60+60), stats.Seconds, "breaks are not focus time")
+	assert.Equal(t, int64(3), stats.Sessions)
+	assert.Equal(t, int64(1), stats.Interruptions)
+	assert.Equal(t, []services.FocusTodoStats{
+		{TodoID: todo.ID, Title: "Write spec", Seconds: 37 * 60, Sessions: 2, Interruptions: 1},
+		{TodoID: 0, Title: "", Seconds: 60, Sessions: 1},
+	}, stats.Todos)
+	stats, err = focus.Stats(0, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, stats.Todos, "0 is not every user")
+
+	// les sessions restent comptées après la suppression du todo
+	require.NoError(t, todos.Delete(1, todo.ID))
+	stats, err = focus.Stats(1, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, []services.FocusTodoStats{{TodoID: 0, Seconds: 38 * 60, Sessions: 3, Interruptions: 1}}, stats.Todos)
+}
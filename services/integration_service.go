@@ -0,0 +1,566 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/chat"
+	"github.com/go-todo1/events"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDigestTime = "09:00"
+	digestSectionSize = 10 // todos listés par section du résumé
+	chatBatchSize     = 50 // publications envoyées par passage de DeliverPending
+)
+
+// Publications par défaut : les todos terminés et le résumé quotidien
+var defaultChatEvents = models.EventTodoCompleted + "," + models.EventDigest
+
+var (
+	ErrIntegrationNotFound = errors.New("integration not found")
+	ErrInvalidIntegration  = errors.New("invalid integration")
+)
+
+type IntegrationService interface {
+	List(userID uint) ([]models.ChatIntegration, error)
+	Create(integration models.ChatIntegration) (models.ChatIntegration, error)
+	Update(userID, id uint, integration models.ChatIntegration) (models.ChatIntegration, error)
+	Delete(userID, id uint) error
+	Test(userID, id uint) error
+	Announce(evt events.Event) error
+	DeliverPending() (int, error)
+	SendDigests() (int, error)
+}
+
+func NewIntegrationServiceImp(db *gorm.DB, baseURL string) *IntegrationServiceImp {
+	return &IntegrationServiceImp{
+		Db:      db,
+		Client:  resty.New().SetTimeout(10 * time.Second),
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Now:     time.Now,
+	}
+}
+
+type IntegrationServiceImp struct {
+	Db      *gorm.DB
+	Client  *resty.Client
+	BaseURL string           // adresse publique de l'application, pour les liens ; vide : pas de lien
+	Now     func() time.Time // horloge, remplaçable dans les tests
+}
+
+// chatResponseError est une réponse du webhook hors 2xx
+type chatResponseError struct {
+	code int
+}
+
+func (e chatResponseError) Error() string {
+	return fmt.Sprintf("unexpected response code %d", e.code)
+}
+
+func (s *IntegrationServiceImp) List(userID uint) ([]models.ChatIntegration, error) {
+	var integrations []models.ChatIntegration
+	err := s.Db.Where("user_id = ?", userID).Order("id").Find(&integrations).Error
+	return integrations, err
+}
+
+// Create enregistre l'intégration ; le premier résumé est publié à la prochaine heure prévue
+func (s *IntegrationServiceImp) Create(integration models.ChatIntegration) (models.ChatIntegration, error) {
+	if integration.ID != 0 {
+		return models.ChatIntegration{}, errors.New("invalid ID")
+	}
+	if err := s.normalize(&integration); err != nil {
+		return models.ChatIntegration{}, err
+	}
+	// les événements déjà dans l'outbox ne sont pas publiés
+	var last models.OutboxEvent
+	if err := s.Db.Order("id desc").Limit(1).Find(&last).Error; err != nil {
+		return models.ChatIntegration{}, err
+	}
+	now := s.Now()
+	integration.LastEventID, integration.LastDigestAt, integration.LastError = last.ID, &now, ""
+	if err := s.Db.Create(&integration).Error; err != nil {
+		return models.ChatIntegration{}, err
+	}
+	return integration, nil
+}
+
+// Update remplace la configuration de l'intégration
+func (s *IntegrationServiceImp) Update(userID, id uint, integration models.ChatIntegration) (models.ChatIntegration, error) {
+	existing, err := s.find(userID, id)
+	if err != nil {
+		return models.ChatIntegration{}, err
+	}
+	integration.UserID = userID
+	if err := s.normalize(&integration); err != nil {
+		return models.ChatIntegration{}, err
+	}
+	existing.Name, existing.Format, existing.URL, existing.ListID = integration.Name, integration.Format, integration.URL, integration.ListID
+	existing.Events, existing.DigestTime, existing.Timezone = integration.Events, integration.DigestTime, integration.Timezone
+	err = s.Db.Model(&existing).Updates(map[string]interface{}{
+		"name": existing.Name, "format": existing.Format, "url": existing.URL, "list_id": existing.ListID,
+		"events": existing.Events, "digest_time": existing.DigestTime, "timezone": existing.Timezone, "updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return models.ChatIntegration{}, err
+	}
+	return existing, nil
+}
+
+func (s *IntegrationServiceImp) Delete(userID, id uint) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&models.ChatIntegration{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrIntegrationNotFound
+		}
+		return tx.Where("integration_id = ?", id).Delete(&models.ChatDelivery{}).Error
+	})
+}
+
+// Test publie un message de test et retourne l'erreur du webhook, s'il y en a une
+func (s *IntegrationServiceImp) Test(userID, id uint) error {
+	integration, err := s.find(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.post(integration, chat.Message{
+		Text:  fmt.Sprintf("Test message from Todos: the %q integration is working.", integration.Name),
+		Color: chat.ColorInfo,
+		URL:   s.BaseURL,
+	})
+}
+
+// Announce met en file la publication de l'événement dans les intégrations de la liste du todo
+// et dans celles, sans liste, de son propriétaire ; DeliverPending les envoie. Il est abonné au
+// bus d'événements : un événement redistribué n'est mis en file qu'une fois par intégration.
+func (s *IntegrationServiceImp) Announce(evt events.Event) error {
+	if !webhookEvents[evt.Type] {
+		return nil
+	}
+	query := s.Db.Where("last_event_id < ?", evt.ID)
+	if evt.Todo.ListID != nil {
+		query = query.Where("list_id = ? OR (list_id IS NULL AND user_id = ?)", *evt.Todo.ListID, evt.Todo.UserID)
+	} else {
+		query = query.Where("list_id IS NULL AND user_id = ?", evt.Todo.UserID)
+	}
+	var integrations []models.ChatIntegration
+	if err := query.Order("id").Find(&integrations).Error; err != nil {
+		return err
+	}
+
+	msg := s.eventMessage(evt)
+	now := s.Now()
+	for _, integration := range integrations {
+		if !chatSubscribedTo(integration, evt.Type) {
+			continue
+		}
+		var existing int64
+		if err := s.Db.Model(&models.ChatDelivery{}).
+			Where("integration_id = ? AND event_id = ?", integration.ID, evt.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			continue // événement déjà reçu lors d'une distribution précédente
+		}
+		body, err := chat.Payload(integration.Format, msg)
+		if err != nil {
+			return err
+		}
+		delivery := models.ChatDelivery{
+			IntegrationID: integration.ID,
+			EventID:       evt.ID,
+			Event:         evt.Type,
+			Payload:       string(body),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}
+		if err := s.Db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverPending envoie les publications arrivées à échéance et retourne le nombre traité.
+// Une erreur temporaire (réseau, 429, 5xx) est retentée avec le délai des webhooks, jusqu'à
+// WebhookMaxAttempts tentatives ; un refus définitif (autre 4xx) n'est pas retenté.
+func (s *IntegrationServiceImp) DeliverPending() (int, error) {
+	var deliveries []models.ChatDelivery
+	err := s.Db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, s.Now()).
+		Order("next_attempt_at").Limit(chatBatchSize).Find(&deliveries).Error
+	if err != nil {
+		return 0, err
+	}
+	for i := range deliveries {
+		var integration models.ChatIntegration
+		if err := s.Db.First(&integration, deliveries[i].IntegrationID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.Db.Delete(&deliveries[i])
+				continue
+			}
+			return i, err
+		}
+		err := s.attempt(integration, &deliveries[i])
+		if err := s.Db.Save(&deliveries[i]).Error; err != nil {
+			return i, err
+		}
+		if err := s.recordResult(integration, err, map[string]interface{}{}); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt envoie la publication et met à jour son statut ; il retourne l'erreur de l'envoi
+func (s *IntegrationServiceImp) attempt(integration models.ChatIntegration, d *models.ChatDelivery) error {
+	d.Attempts++
+	err := s.send(integration.URL, []byte(d.Payload))
+	if err == nil {
+		now := s.Now()
+		d.Status, d.DeliveredAt, d.LastError = models.DeliverySucceeded, &now, ""
+		return nil
+	}
+	d.LastError = err.Error()
+	if !retryable(err) || d.Attempts >= WebhookMaxAttempts {
+		d.Status = models.DeliveryFailed
+		return err
+	}
+	d.NextAttemptAt = s.Now().Add(WebhookBackoff(d.Attempts))
+	return err
+}
+
+// SendDigests publie le résumé quotidien des intégrations dont l'heure est passée
+// et retourne le nombre de résumés publiés. Un résumé vide n'est pas publié.
+func (s *IntegrationServiceImp) SendDigests() (int, error) {
+	var integrations []models.ChatIntegration
+	if err := s.Db.Where("events LIKE ?", "%"+models.EventDigest+"%").Order("id").Find(&integrations).Error; err != nil {
+		return 0, err
+	}
+	now := s.Now()
+	sent := 0
+	for _, integration := range integrations {
+		if !chatSubscribedTo(integration, models.EventDigest) {
+			continue
+		}
+		scheduled, due := digestSchedule(integration, now)
+		if !due {
+			continue
+		}
+		msg, empty, err := s.digestMessage(integration, scheduled)
+		if err != nil {
+			return sent, err
+		}
+		if empty {
+			if err := s.recordResult(integration, nil, map[string]interface{}{"last_digest_at": now}); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		err = s.post(integration, msg)
+		updates := map[string]interface{}{"last_digest_at": now}
+		if retryable(err) {
+			updates = map[string]interface{}{} // nouvelle tentative au prochain passage
+		} else if err == nil {
+			sent++
+		}
+		if err := s.recordResult(integration, err, updates); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// RunDeliveries envoie les publications en attente à intervalle régulier jusqu'à l'annulation du contexte
+func (s *IntegrationServiceImp) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverPending(); err != nil {
+				log.Printf("Error delivering chat messages: %v", err)
+			}
+		}
+	}
+}
+
+// Run publie les résumés à intervalle régulier jusqu'à l'annulation du contexte
+func (s *IntegrationServiceImp) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SendDigests(); err != nil {
+				log.Printf("Error sending chat digests: %v", err)
+			}
+		}
+	}
+}
+
+func (s *IntegrationServiceImp) find(userID, id uint) (models.ChatIntegration, error) {
+	var integration models.ChatIntegration
+	if err := s.Db.Where("user_id = ?", userID).First(&integration, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ChatIntegration{}, ErrIntegrationNotFound
+		}
+		return models.ChatIntegration{}, err
+	}
+	return integration, nil
+}
+
+func (s *IntegrationServiceImp) normalize(integration *models.ChatIntegration) error {
+	integration.Name = strings.Join(strings.Fields(integration.Name), " ")
+	if integration.Name == "" || len(integration.Name) > 128 {
+		return fmt.Errorf("%w: the name is required (128 characters max)", ErrInvalidIntegration)
+	}
+	integration.Format = strings.ToLower(strings.TrimSpace(integration.Format))
+	if !chat.ValidFormat(integration.Format) {
+		return fmt.Errorf("%w: format must be one of %s", ErrInvalidIntegration, strings.Join(chat.Formats, ", "))
+	}
+	u, err := url.Parse(integration.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: invalid URL", ErrInvalidIntegration)
+	}
+
+	names := splitList(integration.Events)
+	for _, e := range names {
+		if !webhookEvents[e] && e != models.EventDigest {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidIntegration, e)
+		}
+	}
+	integration.Events = strings.Join(names, ",")
+	if integration.Events == "" {
+		integration.Events = defaultChatEvents
+	}
+
+	if integration.DigestTime == "" {
+		integration.DigestTime = defaultDigestTime
+	}
+	at, err := time.Parse("15:04", integration.DigestTime)
+	if err != nil {
+		return fmt.Errorf("%w: digest_time must be HH:MM", ErrInvalidIntegration)
+	}
+	integration.DigestTime = at.Format("15:04")
+	if _, err := time.LoadLocation(integration.Timezone); integration.Timezone != "" && err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidIntegration, integration.Timezone)
+	}
+
+	if integration.ListID != nil && *integration.ListID == 0 {
+		integration.ListID = nil
+	}
+	if integration.ListID != nil {
+		var count int64
+		if err := s.Db.Model(&models.ListModel{}).Where("id = ? AND user_id = ?", *integration.ListID, integration.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrListNotFound
+		}
+	}
+	return nil
+}
+
+// post envoie le message au webhook entrant de l'intégration
+func (s *IntegrationServiceImp) post(integration models.ChatIntegration, msg chat.Message) error {
+	body, err := chat.Payload(integration.Format, msg)
+	if err != nil {
+		return err
+	}
+	return s.send(integration.URL, body)
+}
+
+// send envoie un corps déjà mis en forme au webhook entrant
+func (s *IntegrationServiceImp) send(target string, body []byte) error {
+	resp, err := s.Client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post(target)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return chatResponseError{code: resp.StatusCode()}
+	}
+	return nil
+}
+
+// recordResult enregistre updates et le résultat de la dernière publication
+func (s *IntegrationServiceImp) recordResult(integration models.ChatIntegration, err error, updates map[string]interface{}) error {
+	updates["last_error"] = ""
+	if err != nil {
+		log.Printf("Error posting to chat integration %d: %v", integration.ID, err)
+		updates["last_error"] = truncate(err.Error(), 255)
+	}
+	return s.Db.Model(&integration).UpdateColumns(updates).Error
+}
+
+// retryable indique si une nouvelle tentative a des chances d'aboutir
+func retryable(err error) bool {
+	var resp chatResponseError
+	if errors.As(err, &resp) {
+		return resp.code == http.StatusTooManyRequests || resp.code >= 500
+	}
+	return err != nil
+}
+
+func (s *IntegrationServiceImp) eventMessage(evt events.Event) chat.Message {
+	todo := evt.Todo
+	msg := chat.Message{Title: todo.Title, URL: s.todoURL(todo.ID)}
+	switch evt.Type {
+	case models.EventTodoCreated:
+		msg.Text, msg.Color = "New todo: "+todo.Title, chat.ColorInfo
+	case models.EventTodoCompleted:
+		msg.Text, msg.Color = "Completed: "+todo.Title, chat.ColorSuccess
+	case models.EventTodoReopened:
+		msg.Text, msg.Color = "Reopened: "+todo.Title, chat.ColorWarning
+	case models.EventTodoDeleted:
+		msg.Text, msg.Color, msg.URL = "Deleted: "+todo.Title, chat.ColorMuted, ""
+	}
+
+	if todo.ListID != nil {
+		var list models.ListModel
+		if s.Db.Limit(1).Find(&list, *todo.ListID).Error == nil && list.ID != 0 {
+			msg.Fields = append(msg.Fields, chat.Field{Name: "List", Value: list.Name, Short: true})
+		}
+	}
+	if todo.AssigneeID != nil {
+		var assignee models.UserModel
+		if s.Db.Limit(1).Find(&assignee, *todo.AssigneeID).Error == nil && assignee.ID != 0 {
+			msg.Fields = append(msg.Fields, chat.Field{Name: "Assignee", Value: assignee.Name, Short: true})
+		}
+	}
+	if todo.DueAt != nil {
+		msg.Fields = append(msg.Fields, chat.Field{Name: "Due", Value: todo.DueAt.Format("Mon, Jan 2 2006"), Short: true})
+	}
+	if todo.Priority != "" {
+		msg.Fields = append(msg.Fields, chat.Field{Name: "Priority", Value: todo.Priority, Short: true})
+	}
+	return msg
+}
+
+// digestMessage résume les todos terminés dans les 24 heures précédant scheduled, ceux
+// à faire ce jour-là et ceux en retard ; empty est vrai s'il n'y a rien à dire
+func (s *IntegrationServiceImp) digestMessage(integration models.ChatIntegration, scheduled time.Time) (msg chat.Message, empty bool, err error) {
+	scope := func() *gorm.DB {
+		query := s.Db.Model(&models.TodoModel{}).Where("archived_at IS NULL")
+		if integration.ListID != nil {
+			return query.Where("list_id = ?", *integration.ListID)
+		}
+		return query.Where("user_id = ?", integration.UserID)
+	}
+	dayStart := time.Date(scheduled.Year(), scheduled.Month(), scheduled.Day(), 0, 0, 0, 0, scheduled.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	var completed, dueToday, overdue []models.TodoModel
+	var open int64
+	if err := scope().Where("completed = ? AND completed_at >= ? AND completed_at < ?", true, scheduled.Add(-24*time.Hour), scheduled).
+		Order("completed_at").Find(&completed).Error; err != nil {
+		return msg, false, err
+	}
+	if err := scope().Where("completed = ? AND due_at >= ? AND due_at < ?", false, dayStart, dayEnd).Order("due_at").Find(&dueToday).Error; err != nil {
+		return msg, false, err
+	}
+	if err := scope().Where("completed = ? AND due_at < ?", false, dayStart).Order("due_at").Find(&overdue).Error; err != nil {
+		return msg, false, err
+	}
+	if err := scope().Where("completed = ?", false).Count(&open).Error; err != nil {
+		return msg, false, err
+	}
+	if len(completed) == 0 && len(dueToday) == 0 && len(overdue) == 0 && open == 0 {
+		return msg, true, nil
+	}
+
+	name := "All todos"
+	if integration.ListID != nil {
+		var list models.ListModel
+		if err := s.Db.Limit(1).Find(&list, *integration.ListID).Error; err != nil {
+			return msg, false, err
+		}
+		name = list.Name
+	}
+	msg = chat.Message{
+		Text:  fmt.Sprintf("Daily digest for %s: %d completed, %d due today, %d overdue", name, len(completed), len(dueToday), len(overdue)),
+		Title: fmt.Sprintf("%s: %s", name, scheduled.Format("Monday, January 2")),
+		URL:   s.BaseURL,
+		Color: chat.ColorInfo,
+		Fields: []chat.Field{
+			{Name: "Completed", Value: fmt.Sprint(len(completed)), Short: true},
+			{Name: "Due today", Value: fmt.Sprint(len(dueToday)), Short: true},
+			{Name: "Overdue", Value: fmt.Sprint(len(overdue)), Short: true},
+			{Name: "Open", Value: fmt.Sprint(open), Short: true},
+		},
+		Sections: []chat.Section{
+			s.digestSection("Overdue", overdue),
+			s.digestSection("Due today", dueToday),
+			s.digestSection("Completed", completed),
+		},
+	}
+	if len(overdue) > 0 {
+		msg.Color = chat.ColorWarning
+	}
+	return msg, false, nil
+}
+
+func (s *IntegrationServiceImp) digestSection(title string, todos []models.TodoModel) chat.Section {
+	section := chat.Section{Title: title}
+	for i, todo := range todos {
+		if i == digestSectionSize {
+			section.Items = append(section.Items, chat.Item{Text: fmt.Sprintf("and %d more", len(todos)-i)})
+			break
+		}
+		section.Items = append(section.Items, chat.Item{Text: todo.Title, URL: s.todoURL(todo.ID)})
+	}
+	return section
+}
+
+func (s *IntegrationServiceImp) todoURL(id uint) string {
+	if s.BaseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/#todo-%d", s.BaseURL, id)
+}
+
+// digestSchedule retourne l'heure prévue du résumé du jour dans le fuseau de l'intégration,
+// et s'il est à publier : l'heure est passée et il n'a pas encore été publié
+func digestSchedule(integration models.ChatIntegration, now time.Time) (time.Time, bool) {
+	loc := time.Local
+	if tz, err := time.LoadLocation(integration.Timezone); integration.Timezone != "" && err == nil {
+		loc = tz
+	}
+	at, err := time.Parse("15:04", integration.DigestTime)
+	if err != nil {
+		at, _ = time.Parse("15:04", defaultDigestTime)
+	}
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if now.Before(scheduled) {
+		return scheduled, false
+	}
+	return scheduled, integration.LastDigestAt == nil || integration.LastDigestAt.Before(scheduled)
+}
+
+func chatSubscribedTo(integration models.ChatIntegration, event string) bool {
+	for _, e := range splitList(integration.Events) {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
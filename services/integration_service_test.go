@@ -0,0 +1,307 @@
+package services_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// chatServer tient lieu de webhook entrant : il garde les corps reçus par chemin
+type chatServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	status int
+	bodies map[string][]string
+}
+
+func newChatServer(t *testing.T) *chatServer {
+	s := &chatServer{status: http.StatusOK, bodies: map[string][]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.bodies[r.URL.Path] = append(s.bodies[r.URL.Path], string(body))
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *chatServer) received(path string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies[path]...)
+}
+
+func (s *chatServer) respond(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// redispatch remet les événements de l'outbox en attente, comme après une panne
+func redispatch(t *testing.T, db *gorm.DB, dispatcher *events.Dispatcher) {
+	require.NoError(t, db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&models.OutboxEvent{}).
+		UpdateColumns(map[string]interface{}{"dispatched_at": nil, "next_attempt_at": time.Now().Add(-time.Second)}).Error)
+	_, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+}
+
+func TestAnnounceToChat(t *testing.T) {
+	db := initSQLiteDB(t)
+	server := newChatServer(t)
+	service := services.NewIntegrationServiceImp(db, "https://todo.example.com/")
+	todos := services.NewTodoServiceImp(db, "")
+
+	list, err := services.NewListServiceImp(db).Create(models.ListModel{UserID: 1, Name: "Release"})
+	require.NoError(t, err)
+	_, err = service.Create(models.ChatIntegration{UserID: 1, Name: "Release channel", Format: "slack", URL: server.URL + "/slack", ListID: &list.ID, Events: "todo.created, todo.completed"})
+	require.NoError(t, err)
+	discord, err := service.Create(models.ChatIntegration{UserID: 1, Name: "Everything", Format: "discord", URL: server.URL + "/discord"})
+	require.NoError(t, err)
+	assert.Equal(t, "todo.completed,digest", discord.Events)
+	_, err = service.Create(models.ChatIntegration{UserID: 2, Name: "Someone else", Format: "mattermost", URL: server.URL + "/mattermost"})
+	require.NoError(t, err)
+
+	dispatcher := events.NewDispatcher(db)
+	dispatcher.Subscribe("chat", service.Announce)
+
+	ship, err := todos.Create(models.TodoModel{UserID: 1, Title: "Ship it", ListID: &list.ID, Priority: "A"})
+	require.NoError(t, err)
+	groceries, err := todos.Create(models.TodoModel{UserID: 1, Title: "Groceries"})
+	require.NoError(t, err)
+	_, err = todos.Update(1, ship.ID, services.TodoPatch{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	_, err = todos.Update(1, groceries.ID, services.TodoPatch{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	_, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Empty(t, server.received("/slack"), "messages are posted by DeliverPending")
+	delivered, err := service.DeliverPending()
+	require.NoError(t, err)
+	assert.Equal(t, 4, delivered)
+
+	slack := server.received("/slack")
+	require.Len(t, slack, 2, "created and completed in the list")
+	var payload struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			TitleLink string `json:"title_link"`
+			Color     string `json:"color"`
+			Fields    []struct {
+				Title string `json:"title"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(slack[1]), &payload))
+	assert.Equal(t, "Completed: Ship it", payload.Text)
+	assert.Equal(t, "https://todo.example.com/#todo-1", payload.Attachments[0].TitleLink)
+	assert.Equal(t, "List", payload.Attachments[0].Fields[0].Title)
+	assert.Equal(t, "Release", payload.Attachments[0].Fields[0].Value)
+
+	assert.Len(t, server.received("/discord"), 2, "both completions")
+	assert.Contains(t, server.received("/discord")[1], `"content":"Completed: Groceries"`)
+	assert.Empty(t, server.received("/mattermost"))
+
+	redispatch(t, db, dispatcher)
+	delivered, err = service.DeliverPending()
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	assert.Len(t, server.received("/slack"), 2, "events are announced once")
+	assert.Len(t, server.received("/discord"), 2)
+}
+
+func TestAnnounceToChatFailures(t *testing.T) {
+	db := initSQLiteDB(t)
+	server := newChatServer(t)
+	service := services.NewIntegrationServiceImp(db, "")
+	now := time.Now()
+	service.Now = func() time.Time { return now }
+	integration, err := service.Create(models.ChatIntegration{UserID: 1, Name: "Team", Format: "mattermost", URL: server.URL, Events: "todo.created"})
+	require.NoError(t, err)
+	dispatcher := events.NewDispatcher(db)
+	dispatcher.Subscribe("chat", service.Announce)
+	todos := services.NewTodoServiceImp(db, "")
+
+	server.respond(http.StatusServiceUnavailable)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Plan"})
+	require.NoError(t, err)
+	dispatched, err := dispatcher.DispatchPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, dispatched, "the event bus is not held up by the chat server")
+	delivered, err := service.DeliverPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	var saved models.ChatIntegration
+	require.NoError(t, db.First(&saved, integration.ID).Error)
+	assert.Equal(t, "unexpected response code 503", saved.LastError)
+	lastDelivery := func() models.ChatDelivery {
+		var delivery models.ChatDelivery
+		require.NoError(t, db.Last(&delivery).Error)
+		return delivery
+	}
+	delivery := lastDelivery()
+	assert.Equal(t, models.DeliveryPending, delivery.Status, "a temporary failure is retried")
+	assert.Equal(t, 1, delivery.Attempts)
+	delivered, _ = service.DeliverPending()
+	assert.Zero(t, delivered, "after a backoff")
+
+	server.respond(http.StatusOK)
+	now = now.Add(time.Hour)
+	_, err = service.DeliverPending()
+	require.NoError(t, err)
+	assert.Len(t, server.received("/"), 2)
+	require.NoError(t, db.First(&saved, integration.ID).Error)
+	assert.Empty(t, saved.LastError)
+	require.NoError(t, db.First(&delivery, delivery.ID).Error)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+
+	server.respond(http.StatusNotFound)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Review"})
+	require.NoError(t, err)
+	_, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	_, err = service.DeliverPending()
+	require.NoError(t, err)
+	delivery = lastDelivery()
+	assert.Equal(t, models.DeliveryFailed, delivery.Status, "a rejected message is not retried")
+	require.NoError(t, db.First(&saved, integration.ID).Error)
+	assert.Equal(t, "unexpected response code 404", saved.LastError)
+
+	// nombre de tentatives plafonné
+	server.respond(http.StatusBadGateway)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Retry"})
+	require.NoError(t, err)
+	_, err = dispatcher.DispatchPending()
+	require.NoError(t, err)
+	delivery = lastDelivery()
+	require.NoError(t, db.Model(&delivery).UpdateColumn("attempts", services.WebhookMaxAttempts-1).Error)
+	_, err = service.DeliverPending()
+	require.NoError(t, err)
+	require.NoError(t, db.First(&delivery, delivery.ID).Error)
+	assert.Equal(t, models.DeliveryFailed, delivery.Status)
+	assert.Equal(t, services.WebhookMaxAttempts, delivery.Attempts)
+
+	assert.Error(t, service.Test(1, integration.ID))
+	assert.ErrorIs(t, service.Test(2, integration.ID), services.ErrIntegrationNotFound)
+}
+
+func TestChatIntegrationValidation(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewIntegrationServiceImp(db, "")
+	valid := models.ChatIntegration{UserID: 1, Name: "Team", Format: "Slack", URL: "https://hooks.example.com/x"}
+
+	for _, tc := range []struct {
+		name   string
+		change func(i *models.ChatIntegration)
+	}{
+		{"format", func(i *models.ChatIntegration) { i.Format = "teams" }},
+		{"url", func(i *models.ChatIntegration) { i.URL = "hooks.example.com" }},
+		{"event", func(i *models.ChatIntegration) { i.Events = "todo.renamed" }},
+		{"digest time", func(i *models.ChatIntegration) { i.DigestTime = "25:00" }},
+		{"timezone", func(i *models.ChatIntegration) { i.Timezone = "Mars/Olympus" }},
+	} {
+		integration := valid
+		tc.change(&integration)
+		_, err := service.Create(integration)
+		assert.ErrorIs(t, err, services.ErrInvalidIntegration, tc.name)
+	}
+	listID := uint(42)
+	integration := valid
+	integration.ListID = &listID
+	_, err := service.Create(integration)
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+
+	created, err := service.Create(valid)
+	require.NoError(t, err)
+	assert.Equal(t, "slack", created.Format)
+	assert.Equal(t, "09:00", created.DigestTime)
+	updated, err := service.Update(1, created.ID, models.ChatIntegration{Name: "Team", Format: "discord", URL: created.URL, Events: "digest", DigestTime: "7:30", Timezone: "Europe/Paris"})
+	require.NoError(t, err)
+	assert.Equal(t, "07:30", updated.DigestTime)
+	_, err = service.Update(2, created.ID, updated)
+	assert.ErrorIs(t, err, services.ErrIntegrationNotFound)
+	assert.NoError(t, service.Delete(1, created.ID))
+	assert.ErrorIs(t, service.Delete(1, created.ID), services.ErrIntegrationNotFound)
+}
+
+func TestChatDigest(t *testing.T) {
+	db := initSQLiteDB(t)
+	server := newChatServer(t)
+	service := services.NewIntegrationServiceImp(db, "https://todo.example.com")
+	now := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	service.Now = func() time.Time { return now }
+	todos := services.NewTodoServiceImp(db, "")
+
+	_, err := service.Create(models.ChatIntegration{UserID: 1, Name: "Daily", Format: "slack", URL: server.URL + "/daily", Events: "digest", DigestTime: "08:30", Timezone: "UTC"})
+	require.NoError(t, err)
+	_, err = service.Create(models.ChatIntegration{UserID: 2, Name: "Nothing to say", Format: "discord", URL: server.URL + "/empty", Events: "digest", Timezone: "UTC"})
+	require.NoError(t, err)
+
+	dueToday := time.Date(2024, 9, 2, 17, 0, 0, 0, time.UTC)
+	late := time.Date(2024, 8, 30, 9, 0, 0, 0, time.UTC)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Due today", DueAt: &dueToday})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Late", DueAt: &late})
+	require.NoError(t, err)
+	for _, done := range []struct {
+		title string
+		at    time.Time
+	}{
+		{"Done yesterday", time.Date(2024, 9, 1, 15, 0, 0, 0, time.UTC)},
+		{"Done long ago", time.Date(2024, 8, 20, 15, 0, 0, 0, time.UTC)},
+	} {
+		todo, err := todos.Create(models.TodoModel{UserID: 1, Title: done.title})
+		require.NoError(t, err)
+		_, err = todos.Update(1, todo.ID, services.TodoPatch{TodoModel: models.TodoModel{CompletedAt: &done.at}, Completed: boolPtr(true)})
+		require.NoError(t, err)
+	}
+
+	now = time.Date(2024, 9, 1, 23, 0, 0, 0, time.UTC)
+	sent, err := service.SendDigests()
+	require.NoError(t, err)
+	assert.Zero(t, sent, "no digest on the day the integration is created")
+	now = time.Date(2024, 9, 2, 8, 0, 0, 0, time.UTC)
+	sent, _ = service.SendDigests()
+	assert.Zero(t, sent, "before the digest time")
+
+	now = time.Date(2024, 9, 2, 8, 31, 0, 0, time.UTC)
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	sent, _ = service.SendDigests()
+	assert.Zero(t, sent, "one digest a day")
+	assert.Empty(t, server.received("/empty"), "empty digests are not posted")
+
+	bodies := server.received("/daily")
+	require.Len(t, bodies, 1)
+	var payload struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		} `json:"attachments"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &payload))
+	assert.Equal(t, "Daily digest for All todos: 1 completed, 1 due today, 1 overdue", payload.Text)
+	assert.Equal(t, "All todos: Monday, September 2", payload.Attachments[0].Title)
+	assert.Equal(t, "*Overdue*\n• <https://todo.example.com/#todo-2|Late>\n\n"+
+		"*Due today*\n• <https://todo.example.com/#todo-1|Due today>\n\n"+
+		"*Completed*\n• <https://todo.example.com/#todo-3|Done yesterday>", payload.Attachments[0].Text)
+
+	now = time.Date(2024, 9, 3, 8, 45, 0, 0, time.UTC)
+	sent, _ = service.SendDigests()
+	assert.Equal(t, 1, sent, "the next day")
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"gorm.io/gorm"
+)
+
+// BoardColumn est une colonne du tableau kanban avec ses todos, dans l'ordre manuel
+type BoardColumn struct {
+	Status models.WorkflowStatus `json:"status"`
+	Todos  []models.TodoModel    `json:"todos"`
+}
+
+type Board struct {
+	ListID  uint          `json:"list_id"` // 0 pour les todos sans liste
+	Name    string        `json:"name"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+type ListService interface {
+	List(userID uint, archived bool) ([]models.ListModel, error)
+	Create(list models.ListModel) (models.ListModel, error)
+	Update(userID, id uint, list models.ListModel) (models.ListModel, error)
+	Delete(userID, id uint) error
+	SetWorkflow(userID, id uint, statuses []models.WorkflowStatus) (models.ListModel, error)
+	SetFields(userID, id uint, fields []models.CustomField) (models.ListModel, error)
+	Board(userID, id uint) (Board, error)
+}
+
+func NewListServiceImp(db *gorm.DB) *ListServiceImp {
+	return &ListServiceImp{Db: db}
+}
+
+type ListServiceImp struct {
+	Db *gorm.DB
+}
+
+func orderedStatuses(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// List retourne les listes de l'utilisateur ; les listes archivées seulement si archived est vrai
+func (s *ListServiceImp) List(userID uint, archived bool) ([]models.ListModel, error) {
+	var lists []models.ListModel
+	query := s.Db.Preload("Statuses", orderedStatuses).Preload("Fields", orderedFields).Where("user_id = ?", userID)
+	if !archived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.Order("name").Find(&lists).Error
+	return lists, err
+}
+
+// Create enregistre la liste avec le flux fourni, ou une copie de DefaultWorkflow
+func (s *ListServiceImp) Create(list models.ListModel) (models.ListModel, error) {
+	if list.ID != 0 {
+		return models.ListModel{}, errors.New("invalid ID")
+	}
+	if err := normalizeList(&list); err != nil {
+		return models.ListModel{}, err
+	}
+	statuses := list.Statuses
+	if len(statuses) == 0 {
+		statuses = append([]models.WorkflowStatus(nil), DefaultWorkflow...)
+	}
+	workflow, err := normalizeWorkflow(statuses)
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	list.Statuses = workflow
+	if list.Fields, err = normalizeFields(list.Fields); err != nil {
+		return models.ListModel{}, err
+	}
+
+	if err := s.Db.Create(&list).Error; err != nil {
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+func (s *ListServiceImp) Update(userID, id uint, list models.ListModel) (models.ListModel, error) {
+	existing, err := s.find(s.Db, userID, id)
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	if err := normalizeList(&list); err != nil {
+		return models.ListModel{}, err
+	}
+	if err := s.Db.Model(&existing).Omit("Statuses").Updates(map[string]interface{}{"name": list.Name, "updated_at": time.Now()}).Error; err != nil {
+		return models.ListModel{}, err
+	}
+	return existing, nil
+}
+
+// Delete supprime la liste ; ses todos rejoignent les todos sans liste et le flux par défaut
+func (s *ListServiceImp) Delete(userID, id uint) error {
+	if id == 0 {
+		return errors.New("invalid ID")
+	}
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		list, err := s.find(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		initial, final := DefaultWorkflow.Initial().Name, DefaultWorkflow.Final().Name
+		err = tx.Model(&models.TodoModel{}).Where("list_id = ?", id).Updates(map[string]interface{}{
+			"list_id": nil,
+			"status":  gorm.Expr("CASE WHEN completed THEN ? ELSE ? END", final, initial),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("list_id = ?", id).Delete(&models.WorkflowStatus{}).Error; err != nil {
+			return err
+		}
+		fields := tx.Model(&models.CustomField{}).Select("id").Where("list_id = ?", id)
+		if err := tx.Where("field_id IN (?)", fields).Delete(&models.CustomFieldValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("list_id = ?", id).Delete(&models.CustomField{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&list).Error
+	})
+}
+
+// SetWorkflow remplace les colonnes de la liste. Une colonne retirée ne doit plus contenir de todo ;
+// les todos des colonnes conservées sont mis en accord avec leur drapeau Done.
+func (s *ListServiceImp) SetWorkflow(userID, id uint, statuses []models.WorkflowStatus) (models.ListModel, error) {
+	workflow, err := normalizeWorkflow(statuses)
+	if err != nil {
+		return models.ListModel{}, err
+	}
+
+	var list models.ListModel
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		if list, err = s.find(tx, userID, id); err != nil {
+			return err
+		}
+		names := make([]string, len(workflow))
+		for i, status := range workflow {
+			names[i] = status.Name
+		}
+		var orphans int64
+		if err := tx.Model(&models.TodoModel{}).Where("list_id = ? AND status NOT IN ?", id, names).Count(&orphans).Error; err != nil {
+			return err
+		}
+		if orphans > 0 {
+			return ErrStatusInUse
+		}
+
+		if err := tx.Where("list_id = ?", id).Delete(&models.WorkflowStatus{}).Error; err != nil {
+			return err
+		}
+		for i := range workflow {
+			workflow[i].ListID = id
+		}
+		if err := tx.Create(&workflow).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, status := range workflow {
+			var todos []models.TodoModel
+			err := tx.Where("list_id = ? AND status = ? AND completed <> ?", id, status.Name, status.Done).Order("id").Find(&todos).Error
+			if err != nil {
+				return err
+			}
+			for _, todo := range todos {
+				if err := syncCompletion(tx, todo, status.Done, now, userID); err != nil {
+					return err
+				}
+			}
+		}
+		list.Statuses = workflow
+		return nil
+	})
+	if err != nil {
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+// syncCompletion termine ou rouvre un todo dont la colonne a changé de drapeau Done, avec
+// son historique et son événement, comme une mise à jour
+func syncCompletion(tx *gorm.DB, todo models.TodoModel, done bool, now time.Time, actorID uint) error {
+	action, event := models.ActivityReopened, events.TodoReopened
+	if done {
+		action, event = models.ActivityCompleted, events.TodoCompleted
+		if todo.CompletedAt == nil {
+			todo.CompletedAt = &now
+		}
+	} else {
+		todo.CompletedAt = nil
+	}
+	updates := map[string]interface{}{"completed": done, "completed_at": todo.CompletedAt, "updated_at": now}
+	if err := tx.Model(&todo).Omit("Tags").Updates(updates).Error; err != nil {
+		return err
+	}
+	changes := models.Changes{"completed": {From: !done, To: done}}
+	todo.Completed = done
+	if err := recordActivity(tx, todo.ID, actorID, action, changes); err != nil {
+		return err
+	}
+	return events.Record(tx, event, todo)
+}
+
+// Board range les todos de premier niveau de la liste par colonne ; id 0 désigne les todos sans liste
+func (s *ListServiceImp) Board(userID, id uint) (Board, error) {
+	board := Board{ListID: id, Name: "Inbox"}
+	workflow := DefaultWorkflow
+	query := s.Db.Preload("Tags").Where("user_id = ? AND parent_id IS NULL AND archived_at IS NULL", userID)
+	if id != 0 {
+		list, err := s.find(s.Db, userID, id)
+		if err != nil {
+			return Board{}, err
+		}
+		board.Name = list.Name
+		if len(list.Statuses) > 0 {
+			workflow = list.Statuses
+		}
+		query = query.Where("list_id = ?", id)
+	} else {
+		query = query.Where("list_id IS NULL")
+	}
+
+	var todos []models.TodoModel
+	if err := query.Order("position").Order("id").Find(&todos).Error; err != nil {
+		return Board{}, err
+	}
+	if err := withDependencies(s.Db, todos); err != nil {
+		return Board{}, err
+	}
+	if err := withFields(s.Db, todos); err != nil {
+		return Board{}, err
+	}
+
+	columns := make(map[string]int, len(workflow))
+	for i, status := range workflow {
+		columns[status.Name] = i
+		board.Columns = append(board.Columns, BoardColumn{Status: status, Todos: []models.TodoModel{}})
+	}
+	for _, todo := range todos {
+		i, ok := columns[todo.Status]
+		if !ok {
+			// statut absent du flux : colonne déduite de completed
+			status, _ := workflow.resolve("", todo.Completed)
+			i = columns[status.Name]
+		}
+		board.Columns[i].Todos = append(board.Columns[i].Todos, todo)
+	}
+	return board, nil
+}
+
+func (s *ListServiceImp) find(tx *gorm.DB, userID, id uint) (models.ListModel, error) {
+	var list models.ListModel
+	if err := tx.Preload("Statuses", orderedStatuses).Preload("Fields", orderedFields).Where("user_id = ?", userID).First(&list, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.ListModel{}, ErrListNotFound
+		}
+		return models.ListModel{}, err
+	}
+	return list, nil
+}
+
+func normalizeList(list *models.ListModel) error {
+	list.Name = strings.Join(strings.Fields(list.Name), " ")
+	if list.Name == "" {
+		return errors.New("the name is required")
+	}
+	if len(list.Name) > 128 {
+		return errors.New("the name is too long")
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowService(t *testing.T) {
+	db := initSQLiteDB(t)
+	lists := services.NewListServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+
+	_, err := lists.Create(models.ListModel{UserID: 1, Name: "Broken", Statuses: []models.WorkflowStatus{{Name: "open"}}})
+	assert.ErrorIs(t, err, services.ErrInvalidWorkflow, "a done status is required")
+
+	sprint, err := lists.Create(models.ListModel{UserID: 1, Name: " Sprint  42 ", Statuses: []models.WorkflowStatus{
+		{Name: "backlog", Transitions: "doing"},
+		{Name: "doing", Transitions: "backlog review"},
+		{Name: "review", Label: "In review", Transitions: "doing shipped"},
+		{Name: "shipped", Done: true},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sprint 42", sprint.Name)
+	assert.Equal(t, "backlog", sprint.Statuses[0].Label)
+	inbox, err := lists.Create(models.ListModel{UserID: 1, Name: "Inbox"})
+	assert.NoError(t, err)
+	assert.Len(t, inbox.Statuses, 4, "default workflow")
+
+	plain, err := todos.Create(models.TodoModel{UserID: 1, Title: "Plain", Completed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", plain.Status)
+	task, err := todos.Create(models.TodoModel{UserID: 1, Title: "Task", ListID: &sprint.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "backlog", task.Status)
+	_, err = todos.Create(models.TodoModel{UserID: 2, Title: "Intruder", ListID: &sprint.ID})
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Unknown", ListID: &sprint.ID, Status: "todo"})
+	assert.ErrorIs(t, err, services.ErrInvalidStatus)
+
+	_, err = todos.Update(1, task.ID, services.TodoPatch{TodoModel: models.TodoModel{Status: "review"}})
+	assert.ErrorIs(t, err, services.ErrInvalidTransition)
+	_, err = todos.Update(1, task.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrInvalidTransition, "backlog cannot jump to shipped")
+	for _, status := range []string{"doing", "review"} {
+		task, err = todos.Update(1, task.ID, services.TodoPatch{TodoModel: models.TodoModel{Status: status}})
+		assert.NoError(t, err)
+		assert.False(t, task.Completed)
+	}
+	task, err = todos.Update(1, task.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	assert.Equal(t, "shipped", task.Status)
+	assert.NotNil(t, task.CompletedAt)
+
+	found, err := todos.List(services.TodoFilter{UserID: 1, ListID: &sprint.ID, Status: "shipped"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Task"}, titles(found))
+
+	board, err := lists.Board(1, sprint.ID)
+	assert.NoError(t, err)
+	assert.Len(t, board.Columns, 4)
+	assert.Empty(t, board.Columns[0].Todos)
+	assert.Equal(t, []string{"Task"}, titles(board.Columns[3].Todos))
+	board, err = lists.Board(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Plain"}, titles(board.Columns[3].Todos))
+	_, err = lists.Board(2, sprint.ID)
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+
+	_, err = lists.SetWorkflow(1, sprint.ID, []models.WorkflowStatus{{Name: "backlog"}, {Name: "done", Done: true}})
+	assert.ErrorIs(t, err, services.ErrStatusInUse)
+	updated, err := lists.SetWorkflow(1, sprint.ID, []models.WorkflowStatus{{Name: "backlog"}, {Name: "shipped"}, {Name: "archived", Done: true}})
+	assert.NoError(t, err)
+	assert.Len(t, updated.Statuses, 3)
+	found, _ = todos.List(services.TodoFilter{UserID: 1, ListID: &sprint.ID})
+	assert.False(t, found[0].Completed, "shipped is no longer a done column")
+	assert.Nil(t, found[0].CompletedAt)
+	var reopened models.OutboxEvent
+	assert.NoError(t, db.Where("todo_id = ?", task.ID).Order("id DESC").First(&reopened).Error)
+	assert.Equal(t, events.TodoReopened, reopened.Type, "the change reaches webhooks and chat")
+	var activity models.TodoActivity
+	assert.NoError(t, db.Where("todo_id = ?", task.ID).Order("id DESC").First(&activity).Error)
+	assert.Equal(t, models.ActivityReopened, activity.Action)
+	assert.Equal(t, uint(1), activity.ActorID)
+
+	// une colonne inconnue du nouveau flux est remplacée par la colonne initiale
+	moved, err := todos.Update(1, task.ID, services.TodoPatch{TodoModel: models.TodoModel{ListID: &inbox.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, "todo", moved.Status)
+	assert.Equal(t, inbox.ID, *moved.ListID)
+
+	assert.NoError(t, lists.Delete(1, inbox.ID))
+	found, _ = todos.List(services.TodoFilter{UserID: 1, ListID: new(uint)})
+	assert.ElementsMatch(t, []string{"Plain", "Task"}, titles(found))
+	mine, _ := lists.List(1, false)
+	assert.Len(t, mine, 1)
+}
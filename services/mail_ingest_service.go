@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/mailin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxDescriptionLength = 65535 // colonne TEXT
+	mailTokenBytes       = 16    // 32 caractères : la partie locale d'une adresse est limitée à 64
+)
+
+var ErrUnknownRecipient = errors.New("unknown recipient")
+
+// forwardPrefix reconnaît les préfixes de transfert et de réponse : "Fwd:", "TR:", "Re:"…
+var forwardPrefix = regexp.MustCompile(`(?i)^\s*((fwd?|tr|wg|re|aw)\s*:\s*)+`)
+
+type MailIngestService interface {
+	Ingest(token string, msg mailin.Message) (models.TodoModel, error)
+}
+
+func NewMailIngestServiceImp(db *gorm.DB, todos TodoService, attachments AttachmentService) *MailIngestServiceImp {
+	return &MailIngestServiceImp{Db: db, Todos: todos, Attachments: attachments}
+}
+
+type MailIngestServiceImp struct {
+	Db          *gorm.DB
+	Todos       TodoService
+	Attachments AttachmentService
+}
+
+// Ingest crée un todo dans l'Inbox de l'utilisateur dont token est le jeton de réception, lu
+// dans l'adresse du destinataire (voir RecipientToken) : l'expéditeur, falsifiable, n'est pas
+// pris en compte. Le sujet sans préfixe de transfert devient le titre, le texte la description.
+// Les pièces jointes refusées (type, taille) sont listées à la fin de la description plutôt
+// que de rejeter l'e-mail.
+func (s *MailIngestServiceImp) Ingest(token string, msg mailin.Message) (models.TodoModel, error) {
+	user, err := NewUserServiceImp(s.Db).GetByMailToken(token)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return models.TodoModel{}, ErrUnknownRecipient
+		}
+		return models.TodoModel{}, err
+	}
+
+	todo, err := s.Todos.Create(models.TodoModel{
+		UserID:      user.ID,
+		Title:       mailTitle(msg.Subject),
+		Description: truncate(msg.Text, maxDescriptionLength),
+		ActorID:     user.ID,
+	})
+	if err != nil {
+		return models.TodoModel{}, err
+	}
+
+	// le todo existe déjà : un échec ne doit pas provoquer un nouvel envoi, donc un doublon
+	var skipped []string
+	for _, attachment := range msg.Attachments {
+		_, err := s.Attachments.Upload(todo.ID, user.ID, attachment.Filename, bytes.NewReader(attachment.Data), int64(len(attachment.Data)))
+		if err != nil {
+			log.Printf("Error attaching %q to todo %d from email: %v", attachment.Filename, todo.ID, err)
+			skipped = append(skipped, fmt.Sprintf("- %s: %v", attachmentName(attachment.Filename), err))
+		}
+	}
+	if len(skipped) > 0 {
+		note := "Attachments not imported:\n" + strings.Join(skipped, "\n")
+		if todo.Description != "" {
+			note = "\n\n" + note
+		}
+		todo.Description = truncate(todo.Description, max(0, maxDescriptionLength-len(note))) + note
+		if err := s.Db.Model(&todo).UpdateColumn("description", todo.Description).Error; err != nil {
+			return todo, err
+		}
+	}
+	return todo, nil
+}
+
+// RecipientToken retourne le jeton de rcpt si c'est une adresse de réception dérivée de
+// address : todo+<jeton>@example.com pour todo@example.com, sans tenir compte de la casse
+func RecipientToken(rcpt, address string) (string, bool) {
+	local, domain, ok := strings.Cut(address, "@")
+	rcptLocal, rcptDomain, rcptOK := strings.Cut(rcpt, "@")
+	if !ok || !rcptOK || !strings.EqualFold(domain, rcptDomain) {
+		return "", false
+	}
+	base, token, tagged := strings.Cut(rcptLocal, "+")
+	if !tagged || token == "" || !strings.EqualFold(base, local) {
+		return "", false
+	}
+	return strings.ToLower(token), true
+}
+
+// mailTitle retire les préfixes de transfert du sujet, "(no subject)" s'il est vide
+func mailTitle(subject string) string {
+	title := strings.Join(strings.Fields(forwardPrefix.ReplaceAllString(subject, "")), " ")
+	if title == "" {
+		return "(no subject)"
+	}
+	return truncate(title, 255)
+}
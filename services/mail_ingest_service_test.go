@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"bytes"
+	"net/mail"
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/blobstore"
+	"github.com/go-todo1/mailin"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailIngest(t *testing.T) {
+	db := initSQLiteDB(t)
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	attachments := services.NewAttachmentServiceImp(db, store, 1024)
+	todos := services.NewTodoServiceImp(db, "")
+	service := services.NewMailIngestServiceImp(db, todos, attachments)
+
+	alice := models.UserModel{Name: "Alice", Email: "alice@example.com", Handle: "alice", FeedToken: "a", MailToken: "4f3c"}
+	require.NoError(t, db.Create(&alice).Error)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	todo, err := service.Ingest("4f3c", mailin.Message{
+		From:    &mail.Address{Address: "someone@elsewhere.example"},
+		Subject: "Fwd: TR: Renew   the domain",
+		Text:    "It expires on Friday.",
+		Attachments: []mailin.Attachment{
+			{Filename: "invoice.png", ContentType: "image/png", Data: png},
+			{Filename: "setup.exe", ContentType: "application/octet-stream", Data: []byte("MZ\x90\x00")},
+			{Filename: "huge.txt", ContentType: "text/plain", Data: bytes.Repeat([]byte("a"), 2048)},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Renew the domain", todo.Title)
+	assert.Equal(t, alice.ID, todo.UserID, "routed by the recipient, not the sender")
+	assert.Nil(t, todo.ListID, "created in the Inbox")
+
+	saved, err := todos.List(services.TodoFilter{UserID: alice.ID})
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, "It expires on Friday.\n\nAttachments not imported:\n"+
+		"- setup.exe: attachment type is not allowed: application/octet-stream\n"+
+		"- huge.txt: attachment is too large", saved[0].Description)
+	files, err := attachments.List(alice.ID, todo.ID)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "invoice.png", files[0].FileName)
+	assert.Equal(t, alice.ID, files[0].UploaderID)
+
+	empty, err := service.Ingest("4f3c", mailin.Message{Subject: "Re: "})
+	require.NoError(t, err)
+	assert.Equal(t, "(no subject)", empty.Title)
+
+	_, err = service.Ingest("beef", mailin.Message{From: &mail.Address{Address: "alice@example.com"}, Subject: "Hi"})
+	assert.ErrorIs(t, err, services.ErrUnknownRecipient, "a forged sender is not enough")
+	_, err = service.Ingest("", mailin.Message{Subject: "Hi"})
+	assert.ErrorIs(t, err, services.ErrUnknownRecipient)
+
+	users := services.NewUserServiceImp(db)
+	bob, err := users.Create(models.UserModel{Name: "Bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	assert.Len(t, bob.MailToken, 32)
+	todo, err = service.Ingest(bob.MailToken, mailin.Message{Subject: "Call the bank"})
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, todo.UserID)
+	renewed, err := users.RegenerateMailToken(bob.ID)
+	require.NoError(t, err)
+	_, err = service.Ingest(bob.MailToken, mailin.Message{Subject: "Old address"})
+	assert.ErrorIs(t, err, services.ErrUnknownRecipient, "the previous address stops working")
+	_, err = service.Ingest(renewed.MailToken, mailin.Message{Subject: "New address"})
+	assert.NoError(t, err)
+}
+
+func TestRecipientToken(t *testing.T) {
+	for _, tc := range []struct {
+		rcpt  string
+		token string
+		ok    bool
+	}{
+		{"todo+4F3C@example.com", "4f3c", true},
+		{"TODO+4f3c@EXAMPLE.COM", "4f3c", true},
+		{"todo@example.com", "", false},
+		{"todo+@example.com", "", false},
+		{"todo+4f3c@example.org", "", false},
+		{"other+4f3c@example.com", "", false},
+		{"todo+4f3c", "", false},
+	} {
+		token, ok := services.RecipientToken(tc.rcpt, "todo@example.com")
+		assert.Equal(t, tc.ok, ok, tc.rcpt)
+		assert.Equal(t, tc.token, token, tc.rcpt)
+	}
+}
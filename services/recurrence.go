@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/rrule"
+	"gorm.io/gorm"
+)
+
+// maxSkippedOccurrences borne le rattrapage d'une série terminée très en retard
+const maxSkippedOccurrences = 1000
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// normalizeRecurrence valide la règle et la retourne sous forme canonique
+func normalizeRecurrence(s string) (string, error) {
+	rule, err := rrule.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	return rule.String(), nil
+}
+
+// nextOccurrence crée l'occurrence suivante d'un todo récurrent qui vient d'être
+// terminé. Les occurrences déjà passées sont sautées ; rien n'est créé si la série
+// est finie (COUNT ou UNTIL atteint).
+func nextOccurrence(tx *gorm.DB, todo *models.TodoModel) error {
+	rule, err := rrule.Parse(todo.Recurrence)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+
+	now := time.Now()
+	start := now
+	if todo.DueAt != nil {
+		start = *todo.DueAt
+	}
+	next, rest, ok := rule.Advance(start)
+	for i := 0; ok && next.Before(now) && i < maxSkippedOccurrences; i++ {
+		next, rest, ok = rest.Advance(next)
+	}
+	if !ok {
+		return nil
+	}
+
+	if todo.SeriesID == nil {
+		seriesID := todo.ID
+		if err := tx.Model(todo).UpdateColumn("series_id", seriesID).Error; err != nil {
+			return err
+		}
+		todo.SeriesID = &seriesID
+	}
+
+	occurrence := models.TodoModel{
+		UserID:          todo.UserID,
+		Title:           todo.Title,
+		Description:     todo.Description,
+		Priority:        todo.Priority,
+		DueAt:           &next,
+		Projects:        todo.Projects,
+		Contexts:        todo.Contexts,
+		ParentID:        todo.ParentID,
+		Recurrence:      rest.String(),
+		SeriesID:        todo.SeriesID,
+		ListID:          todo.ListID,
+		AssigneeID:      todo.AssigneeID,
+		EstimateMinutes: todo.EstimateMinutes,
+	}
+	workflow, err := workflowFor(tx, todo.UserID, todo.ListID)
+	if err != nil {
+		return err
+	}
+	occurrence.Status = workflow.Initial().Name
+	if err := appendPosition(tx, &occurrence); err != nil {
+		return err
+	}
+	if err := tx.Omit("Tags").Create(&occurrence).Error; err != nil {
+		return err
+	}
+	if len(todo.Tags) > 0 {
+		tagIDs := make([]uint, len(todo.Tags))
+		for i, tag := range todo.Tags {
+			tagIDs[i] = tag.ID
+		}
+		if err := setTodoTags(tx, &occurrence, tagIDs); err != nil {
+			return err
+		}
+	}
+	if todo.ListID != nil {
+		err := tx.Exec("INSERT INTO custom_field_values (todo_id, field_id, text_value, number_value, date_value, bool_value) "+
+			"SELECT ?, field_id, text_value, number_value, date_value, bool_value FROM custom_field_values WHERE todo_id = ?", occurrence.ID, todo.ID).Error
+		if err != nil {
+			return err
+		}
+		occurrence.Fields = todo.Fields
+	}
+	if err := recordActivity(tx, occurrence.ID, todo.ActorID, models.ActivityCreated, nil); err != nil {
+		return err
+	}
+	if err := events.Record(tx, events.TodoCreated, occurrence); err != nil {
+		return err
+	}
+	todo.Next = &occurrence
+	return nil
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSearchTerms = 10
+	maxSearchLimit = 100
+	snippetRadius  = 60
+	mysqlFullText  = "ft_todo_models_title_description"
+	mysqlComments  = "ft_comment_models_body"
+	sqliteFTSTable = "todo_fts"
+)
+
+type SearchResult struct {
+	Todo      models.TodoModel `json:"todo"`
+	Score     float64          `json:"score"`
+	TitleHTML string           `json:"title_html"` // titre échappé avec les termes entourés de <mark>
+	Snippet   string           `json:"snippet"`    // extrait de la description ou d'un commentaire, même format
+
+	comment string // commentaire le plus pertinent, pour l'extrait
+}
+
+type SearchService interface {
+	Search(userID uint, query string, limit int) ([]SearchResult, error)
+}
+
+func NewSearchServiceImp(db *gorm.DB) *SearchServiceImp {
+	return &SearchServiceImp{Db: db}
+}
+
+type SearchServiceImp struct {
+	Db   *gorm.DB
+	fts5 bool
+}
+
+// EnsureIndex crée l'index plein texte du moteur utilisé : FULLTEXT pour MySQL,
+// table virtuelle FTS5 synchronisée par triggers pour SQLite (si le module est compilé).
+// C'est le seul endroit qui les crée : les migrations goose n'en déclarent pas.
+func (s *SearchServiceImp) EnsureIndex() error {
+	switch s.Db.Dialector.Name() {
+	case "mysql":
+		if !s.Db.Migrator().HasIndex(&models.TodoModel{}, mysqlFullText) {
+			if err := s.Db.Exec("CREATE FULLTEXT INDEX " + mysqlFullText + " ON todo_models (title, description)").Error; err != nil {
+				return err
+			}
+		}
+		if s.Db.Migrator().HasIndex(&models.CommentModel{}, mysqlComments) {
+			return nil
+		}
+		return s.Db.Exec("CREATE FULLTEXT INDEX " + mysqlComments + " ON comment_models (body)").Error
+	case "sqlite":
+		err := s.Db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + sqliteFTSTable +
+			" USING fts5(title, description, content='todo_models', content_rowid='id')").Error
+		if err != nil {
+			// go-sqlite3 n'inclut FTS5 qu'avec le tag de build sqlite_fts5
+			log.Printf("FTS5 unavailable, falling back to LIKE search: %v", err)
+			return nil
+		}
+		for _, stmt := range []string{
+			`CREATE TRIGGER IF NOT EXISTS todo_fts_ai AFTER INSERT ON todo_models BEGIN
+				INSERT INTO todo_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS todo_fts_ad AFTER DELETE ON todo_models BEGIN
+				INSERT INTO todo_fts(todo_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS todo_fts_au AFTER UPDATE ON todo_models BEGIN
+				INSERT INTO todo_fts(todo_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+				INSERT INTO todo_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+			END`,
+			`INSERT INTO todo_fts(todo_fts) VALUES ('rebuild')`,
+		} {
+			if err := s.Db.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		s.fts5 = true
+	}
+	return nil
+}
+
+// Search retourne les todos non archivés correspondant à la requête, les plus pertinents d'abord
+func (s *SearchServiceImp) Search(userID uint, query string, limit int) ([]SearchResult, error) {
+	terms := SearchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var results []SearchResult
+	var err error
+	switch {
+	case s.Db.Dialector.Name() == "mysql":
+		results, err = s.searchMySQL(userID, terms, limit)
+	case s.fts5:
+		results, err = s.searchFTS5(userID, terms, limit)
+	default:
+		results, err = s.searchLike(userID, terms, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if results, err = s.withComments(results, userID, terms, limit); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].TitleHTML = Highlight(results[i].Todo.Title, terms, 0)
+		results[i].Snippet = Highlight(results[i].Todo.Description, terms, snippetRadius)
+		if results[i].comment != "" && !strings.Contains(results[i].Snippet, "<mark>") {
+			results[i].Snippet = Highlight(results[i].comment, terms, snippetRadius)
+		}
+	}
+	return results, nil
+}
+
+type commentHit struct {
+	TodoID uint
+	Body   string
+	Score  float64
+}
+
+// withComments ajoute le score des commentaires correspondants à celui de leur todo,
+// en ajoutant aux résultats les todos trouvés uniquement par leurs commentaires
+func (s *SearchServiceImp) withComments(results []SearchResult, userID uint, terms []string, limit int) ([]SearchResult, error) {
+	query := s.Db.Table("comment_models").Joins("JOIN todo_models ON todo_models.id = comment_models.todo_id")
+	query = query.Where("todo_models.user_id = ? AND todo_models.archived_at IS NULL", userID)
+	var hits []commentHit
+	if s.Db.Dialector.Name() == "mysql" {
+		against := strings.Join(terms, " ")
+		query = query.Select("comment_models.todo_id, comment_models.body, MATCH(comment_models.body) AGAINST (? IN NATURAL LANGUAGE MODE) AS score", against).
+			Where("MATCH(comment_models.body) AGAINST (? IN NATURAL LANGUAGE MODE)", against).
+			Order("score DESC").Limit(maxSearchLimit)
+		if err := query.Scan(&hits).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		cond := s.Db
+		for i, t := range terms {
+			if i == 0 {
+				cond = cond.Where("LOWER(comment_models.body) LIKE ?", "%"+t+"%")
+			} else {
+				cond = cond.Or("LOWER(comment_models.body) LIKE ?", "%"+t+"%")
+			}
+		}
+		if err := query.Select("comment_models.todo_id, comment_models.body").Where(cond).Limit(maxSearchLimit).Scan(&hits).Error; err != nil {
+			return nil, err
+		}
+		for i := range hits {
+			body := strings.ToLower(hits[i].Body)
+			for _, t := range terms {
+				hits[i].Score += float64(strings.Count(body, t))
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	index := make(map[uint]int, len(results))
+	for i, r := range results {
+		index[r.Todo.ID] = i
+	}
+	best := map[uint]float64{}
+	var missing []uint
+	for _, hit := range hits {
+		i, ok := index[hit.TodoID]
+		if !ok {
+			results = append(results, SearchResult{Todo: models.TodoModel{ID: hit.TodoID}})
+			i = len(results) - 1
+			index[hit.TodoID] = i
+			missing = append(missing, hit.TodoID)
+		}
+		results[i].Score += hit.Score
+		if hit.Score > best[hit.TodoID] {
+			best[hit.TodoID] = hit.Score
+			results[i].comment = hit.Body
+		}
+	}
+	if len(missing) > 0 {
+		var todos []models.TodoModel
+		if err := s.Db.Where("id IN ?", missing).Find(&todos).Error; err != nil {
+			return nil, err
+		}
+		for _, todo := range todos {
+			results[index[todo.ID]].Todo = todo
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+type scoredTodo struct {
+	models.TodoModel
+	Score float64
+}
+
+func (s *SearchServiceImp) searchMySQL(userID uint, terms []string, limit int) ([]SearchResult, error) {
+	against := strings.Join(terms, " ")
+	query := s.Db.Model(&models.TodoModel{}).
+		Select("todo_models.*, MATCH(title, description) AGAINST (? IN NATURAL LANGUAGE MODE) AS score", against).
+		Where("MATCH(title, description) AGAINST (? IN NATURAL LANGUAGE MODE) AND archived_at IS NULL", against)
+	return s.scored(query.Where("user_id = ?", userID).Order("score DESC").Limit(limit))
+}
+
+func (s *SearchServiceImp) searchFTS5(userID uint, terms []string, limit int) ([]SearchResult, error) {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + t + `"`
+	}
+	// bm25 est négatif, plus petit = plus pertinent ; le titre pèse double
+	query := s.Db.Table("todo_fts").
+		Select("todo_models.*, -bm25(todo_fts, 2.0, 1.0) AS score").
+		Joins("JOIN todo_models ON todo_models.id = todo_fts.rowid").
+		Where("todo_fts MATCH ? AND todo_models.archived_at IS NULL", strings.Join(quoted, " OR "))
+	return s.scored(query.Where("todo_models.user_id = ?", userID).Order("score DESC").Limit(limit))
+}
+
+// searchLike sert de repli sans index plein texte : le score compte les occurrences
+func (s *SearchServiceImp) searchLike(userID uint, terms []string, limit int) ([]SearchResult, error) {
+	query := s.Db.Model(&models.TodoModel{})
+	cond := s.Db
+	for i, t := range terms {
+		pattern := "%" + t + "%"
+		if i == 0 {
+			cond = cond.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", pattern, pattern)
+		} else {
+			cond = cond.Or("LOWER(title) LIKE ? OR LOWER(description) LIKE ?", pattern, pattern)
+		}
+	}
+	var todos []models.TodoModel
+	if err := query.Where(cond).Where("user_id = ? AND archived_at IS NULL", userID).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(todos))
+	for _, todo := range todos {
+		title, desc := strings.ToLower(todo.Title), strings.ToLower(todo.Description)
+		var score float64
+		for _, t := range terms {
+			score += 2*float64(strings.Count(title, t)) + float64(strings.Count(desc, t))
+		}
+		results = append(results, SearchResult{Todo: todo, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *SearchServiceImp) scored(query *gorm.DB) ([]SearchResult, error) {
+	var rows []scoredTodo
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{Todo: row.TodoModel, Score: row.Score}
+	}
+	return results, nil
+}
+
+// SearchTerms découpe la requête en mots en minuscules, sans doublon
+func SearchTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) < 2 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// Highlight échappe text pour le HTML et entoure les termes de <mark>. Si radius > 0,
+// seul un extrait de radius caractères autour de la première occurrence est conservé.
+func Highlight(text string, terms []string, radius int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		lower = runes // casse non réversible rune à rune : comparaison exacte
+	}
+
+	marks := make([]bool, len(runes))
+	first := -1
+	for _, t := range terms {
+		tr := []rune(t)
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) == t {
+				for j := i; j < i+len(tr); j++ {
+					marks[j] = true
+				}
+				if first < 0 || i < first {
+					first = i
+				}
+			}
+		}
+	}
+
+	start, end := 0, len(runes)
+	if radius > 0 && len(runes) > 2*radius {
+		if first < 0 {
+			first = 0
+		}
+		start = first - radius
+		if start < 0 {
+			start = 0
+		}
+		end = start + 2*radius
+		if end > len(runes) {
+			end = len(runes)
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	open := false
+	for i := start; i < end; i++ {
+		if marks[i] && !open {
+			b.WriteString("<mark>")
+			open = true
+		} else if !marks[i] && open {
+			b.WriteString("</mark>")
+			open = false
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchService(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewSearchServiceImp(db)
+	assert.NoError(t, service.EnsureIndex())
+
+	db.Create(&models.TodoModel{UserID: 1, Title: "Invoice ACME", Description: "Send the invoice for the July deployment"})
+	db.Create(&models.TodoModel{UserID: 1, Title: "Deploy backend", Description: "Rollout after the invoice service is fixed"})
+	db.Create(&models.TodoModel{UserID: 1, Title: "Water plants"})
+	db.Create(&models.TodoModel{UserID: 2, Title: "Invoice <script>", Description: "other user"})
+
+	results, err := service.Search(1, "invoice", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "Invoice ACME", results[0].Todo.Title)
+	assert.Equal(t, "<mark>Invoice</mark> ACME", results[0].TitleHTML)
+	assert.Equal(t, "Send the <mark>invoice</mark> for the July deployment", results[0].Snippet)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results, err = service.Search(2, "invoice", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "<mark>Invoice</mark> &lt;script&gt;", results[0].TitleHTML)
+
+	// sans utilisateur, aucun todo des autres n'est trouvé
+	results, err = service.Search(0, "invoice", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	db.Create(&models.CommentModel{TodoID: 3, AuthorID: 1, Body: "Check the watering invoice from the garden shop"})
+	results, err = service.Search(1, "watering", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Water plants", results[0].Todo.Title)
+	assert.Equal(t, "Check the <mark>watering</mark> invoice from the garden shop", results[0].Snippet)
+	results, err = service.Search(1, "invoice", 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	results, err = service.Search(1, "a !", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	// les todos archivés, et leurs commentaires, ne sont plus trouvés
+	db.Model(&models.TodoModel{}).Where("id IN ?", []uint{1, 3}).UpdateColumn("archived_at", time.Now())
+	results, err = service.Search(1, "invoice", 10)
+	assert.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Deploy backend", results[0].Todo.Title)
+	results, err = service.Search(1, "watering", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestHighlight(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog near the riverbank at dawn"
+	assert.Equal(t, "…ver the <mark>lazy</mark> dog…", services.Highlight(text, []string{"lazy"}, 8))
+	assert.Equal(t, "<mark>The</mark> quick", services.Highlight("The quick", []string{"the"}, 0))
+	assert.Equal(t, []string{"café", "2024"}, services.SearchTerms("Café, café & 2024!"))
+}
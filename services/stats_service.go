@@ -0,0 +1,285 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+// DefaultStatsDays est la période couverte par les statistiques sans ?from=
+const DefaultStatsDays = 30
+
+// MaxStatsDays borne la période demandée, les séries étant calculées jour par jour
+const MaxStatsDays = 366
+
+var ErrStatsRangeTooLong = fmt.Errorf("the stats period cannot exceed %d days", MaxStatsDays)
+
+// StatsFilter restreint les statistiques aux todos de UserID, toujours appliqué ; ListID 0 : Inbox.
+// Les jours sont découpés dans Location (heure locale par défaut), To est exclu.
+type StatsFilter struct {
+	UserID   uint
+	ListID   *uint
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// DayCount est le nombre de todos terminés un jour donné (2006-01-02)
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+// Streaks compte les jours consécutifs avec au moins un todo terminé, sur tout l'historique
+type Streaks struct {
+	Current int `json:"current"` // se termine aujourd'hui, ou hier si rien n'est encore terminé aujourd'hui
+	Longest int `json:"longest"`
+}
+
+// ProductivityStats résume la période ; le taux de retard porte sur les todos arrivés à échéance
+type ProductivityStats struct {
+	From                   time.Time  `json:"from"`
+	To                     time.Time  `json:"to"`
+	Created                int64      `json:"created"`
+	Completed              int64      `json:"completed"`
+	CompletedPerDay        []DayCount `json:"completed_per_day"`
+	AverageCompleteSeconds int64      `json:"average_complete_seconds"` // de la création à la fin
+	Due                    int64      `json:"due"`
+	Overdue                int64      `json:"overdue"` // terminés après l'échéance ou encore ouverts
+	OverdueRate            float64    `json:"overdue_rate"`
+	Streaks                Streaks    `json:"streaks"`
+}
+
+// BurndownDay est l'état d'une liste à la fin d'une journée
+type BurndownDay struct {
+	Date      string `json:"date"`
+	Open      int64  `json:"open"`
+	Completed int64  `json:"completed"` // terminés ce jour-là
+}
+
+// ListBurndown est le burndown d'une liste ; ID 0 : Inbox
+type ListBurndown struct {
+	ID   uint          `json:"id"`
+	Name string        `json:"name"`
+	Days []BurndownDay `json:"days"`
+}
+
+type StatsService interface {
+	Productivity(filter StatsFilter) (ProductivityStats, error)
+	Burndown(filter StatsFilter) ([]ListBurndown, error)
+}
+
+func NewStatsServiceImp(db *gorm.DB) *StatsServiceImp {
+	return &StatsServiceImp{Db: db, Now: time.Now}
+}
+
+type StatsServiceImp struct {
+	Db  *gorm.DB
+	Now func() time.Time // horloge, remplaçable dans les tests
+}
+
+// statsTodo est la projection des todos utile aux statistiques
+type statsTodo struct {
+	ListID      *uint
+	CreatedAt   time.Time
+	DueAt       *time.Time
+	CompletedAt *time.Time
+}
+
+func (s *StatsServiceImp) Productivity(filter StatsFilter) (ProductivityStats, error) {
+	filter, err := s.normalize(filter)
+	if err != nil {
+		return ProductivityStats{}, err
+	}
+	now := s.Now()
+	stats := ProductivityStats{From: filter.From, To: filter.To}
+
+	var todos []statsTodo
+	err = s.scoped(filter).
+		Where("created_at < ? AND (created_at >= ? OR (completed_at >= ? AND completed_at < ?) OR (due_at >= ? AND due_at < ?))",
+			filter.To, filter.From, filter.From, filter.To, filter.From, filter.To).
+		Find(&todos).Error
+	if err != nil {
+		return ProductivityStats{}, err
+	}
+
+	perDay := map[string]int64{}
+	var completeSeconds int64
+	for _, t := range todos {
+		if !t.CreatedAt.Before(filter.From) {
+			stats.Created++
+		}
+		if t.CompletedAt != nil && within(*t.CompletedAt, filter) {
+			stats.Completed++
+			perDay[t.CompletedAt.In(filter.Location).Format("2006-01-02")]++
+			completeSeconds += int64(t.CompletedAt.Sub(t.CreatedAt).Seconds())
+		}
+		// seuls les todos dont l'échéance est passée peuvent être en retard
+		if t.DueAt != nil && within(*t.DueAt, filter) && t.DueAt.Before(now) {
+			stats.Due++
+			if t.CompletedAt == nil || t.CompletedAt.After(*t.DueAt) {
+				stats.Overdue++
+			}
+		}
+	}
+	if stats.Completed > 0 {
+		stats.AverageCompleteSeconds = completeSeconds / stats.Completed
+	}
+	if stats.Due > 0 {
+		stats.OverdueRate = float64(stats.Overdue) / float64(stats.Due)
+	}
+	for _, day := range days(filter) {
+		date := day.Format("2006-01-02")
+		stats.CompletedPerDay = append(stats.CompletedPerDay, DayCount{Date: date, Count: perDay[date]})
+	}
+
+	stats.Streaks, err = s.streaks(filter, now)
+	if err != nil {
+		return ProductivityStats{}, err
+	}
+	return stats, nil
+}
+
+// Burndown retourne, pour chaque liste ayant des todos sur la période, les todos ouverts en fin de journée
+func (s *StatsServiceImp) Burndown(filter StatsFilter) ([]ListBurndown, error) {
+	filter, err := s.normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	var todos []statsTodo
+	err = s.scoped(filter).
+		Where("created_at < ? AND (completed_at IS NULL OR completed_at >= ?)", filter.To, filter.From).
+		Find(&todos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byList := map[uint][]statsTodo{}
+	for _, t := range todos {
+		var id uint
+		if t.ListID != nil {
+			id = *t.ListID
+		}
+		byList[id] = append(byList[id], t)
+	}
+	ids := make([]uint, 0, len(byList))
+	for id := range byList {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var lists []models.ListModel
+	if err := s.Db.Select("id, name").Where("id IN ?", ids).Find(&lists).Error; err != nil {
+		return nil, err
+	}
+	names := map[uint]string{0: "Inbox"}
+	for _, l := range lists {
+		names[l.ID] = l.Name
+	}
+
+	burndowns := make([]ListBurndown, 0, len(ids))
+	for _, id := range ids {
+		burndown := ListBurndown{ID: id, Name: names[id]}
+		for _, day := range days(filter) {
+			end := day.AddDate(0, 0, 1)
+			point := BurndownDay{Date: day.Format("2006-01-02")}
+			for _, t := range byList[id] {
+				if !t.CreatedAt.Before(end) {
+					continue
+				}
+				if t.CompletedAt == nil || !t.CompletedAt.Before(end) {
+					point.Open++
+				} else if !t.CompletedAt.Before(day) {
+					point.Completed++
+				}
+			}
+			burndown.Days = append(burndown.Days, point)
+		}
+		burndowns = append(burndowns, burndown)
+	}
+	return burndowns, nil
+}
+
+// normalize complète le fuseau et la période, alignée sur des débuts de journée, et refuse
+// une période de plus de MaxStatsDays jours
+func (s *StatsServiceImp) normalize(filter StatsFilter) (StatsFilter, error) {
+	if filter.Location == nil {
+		filter.Location = time.Local
+	}
+	if filter.To.IsZero() {
+		filter.To = startOfDay(s.Now().In(filter.Location)).AddDate(0, 0, 1)
+	}
+	if filter.From.IsZero() {
+		filter.From = startOfDay(filter.To.In(filter.Location)).AddDate(0, 0, -DefaultStatsDays)
+	}
+	if filter.From.AddDate(0, 0, MaxStatsDays).Before(filter.To) {
+		return filter, ErrStatsRangeTooLong
+	}
+	return filter, nil
+}
+
+// scoped garde les todos archivés : l'archivage automatique range les todos terminés depuis
+// 30 jours, les exclure effacerait l'historique des todos terminés, du burndown et des séries
+func (s *StatsServiceImp) scoped(filter StatsFilter) *gorm.DB {
+	query := s.Db.Model(&models.TodoModel{}).Select("list_id, created_at, due_at, completed_at")
+	query = query.Where("user_id = ?", filter.UserID)
+	if filter.ListID != nil {
+		if *filter.ListID == 0 {
+			query = query.Where("list_id IS NULL")
+		} else {
+			query = query.Where("list_id = ?", *filter.ListID)
+		}
+	}
+	return query
+}
+
+// streaks parcourt les jours distincts de fin de todo, indépendamment de la période demandée
+func (s *StatsServiceImp) streaks(filter StatsFilter, now time.Time) (Streaks, error) {
+	var completed []statsTodo
+	if err := s.scoped(filter).Where("completed_at IS NOT NULL").Order("completed_at").Find(&completed).Error; err != nil {
+		return Streaks{}, err
+	}
+	var streaks Streaks
+	var last time.Time
+	run := 0
+	for _, t := range completed {
+		day := startOfDay(t.CompletedAt.In(filter.Location))
+		switch {
+		case day.Equal(last):
+			continue
+		case !last.IsZero() && day.Equal(last.AddDate(0, 0, 1)):
+			run++
+		default:
+			run = 1
+		}
+		last = day
+		if run > streaks.Longest {
+			streaks.Longest = run
+		}
+	}
+	today := startOfDay(now.In(filter.Location))
+	if !last.IsZero() && (last.Equal(today) || last.Equal(today.AddDate(0, 0, -1))) {
+		streaks.Current = run
+	}
+	return streaks, nil
+}
+
+func within(t time.Time, filter StatsFilter) bool {
+	return !t.Before(filter.From) && t.Before(filter.To)
+}
+
+// days retourne le début de chaque journée de la période
+func days(filter StatsFilter) []time.Time {
+	var result []time.Time
+	for day := startOfDay(filter.From.In(filter.Location)); day.Before(filter.To); day = day.AddDate(0, 0, 1) {
+		result = append(result, day)
+	}
+	return result
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
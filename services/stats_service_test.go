@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsService(t *testing.T) {
+	db := initSQLiteDB(t)
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2024, 8, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	sprint := models.ListModel{UserID: 1, Name: "Sprint"}
+	require.NoError(t, db.Create(&sprint).Error)
+	for _, todo := range []models.TodoModel{
+		{Title: "late", CreatedAt: *at(10, 0), DueAt: at(14, 0), CompletedAt: at(15, 10)},
+		{Title: "on time", ListID: &sprint.ID, CreatedAt: *at(15, 9), DueAt: at(17, 0), CompletedAt: at(16, 9)},
+		{Title: "overdue", ListID: &sprint.ID, CreatedAt: *at(16, 0), DueAt: at(18, 0)},
+		{Title: "quick", ListID: &sprint.ID, CreatedAt: *at(19, 0), CompletedAt: at(19, 6)},
+		{Title: "today", CreatedAt: *at(20, 8), CompletedAt: at(20, 10)},
+		{Title: "later", ListID: &sprint.ID, CreatedAt: *at(18, 0), DueAt: at(25, 0)},
+		{Title: "history 1", CreatedAt: *at(1, 0), CompletedAt: at(1, 9)},
+		{Title: "history 2", CreatedAt: *at(1, 0), CompletedAt: at(2, 9)},
+		{Title: "history 3", CreatedAt: *at(1, 0), CompletedAt: at(3, 9)},
+		{UserID: 2, Title: "someone else", CreatedAt: *at(15, 0), CompletedAt: at(16, 0)},
+	} {
+		todo.Completed = todo.CompletedAt != nil
+		if todo.UserID == 0 {
+			todo.UserID = 1
+		}
+		require.NoError(t, db.Create(&todo).Error)
+	}
+
+	stats := services.NewStatsServiceImp(db)
+	stats.Now = func() time.Time { return *at(20, 12) }
+	filter := services.StatsFilter{UserID: 1, From: *at(14, 0), To: *at(21, 0), Location: time.UTC}
+
+	productivity, err := stats.Productivity(filter)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), productivity.Created)
+	assert.Equal(t, int64(4), productivity.Completed)
+	assert.Equal(t, []services.DayCount{
+		{Date: "2024-08-14"}, {Date: "2024-08-15", Count: 1}, {Date: "2024-08-16", Count: 1}, {Date: "2024-08-17"},
+		{Date: "2024-08-18"}, {Date: "2024-08-19", Count: 1}, {Date: "2024-08-20", Count: 1},
+	}, productivity.CompletedPerDay)
+	assert.Equal(t, int64((468000+86400+21600+7200)/4), productivity.AverageCompleteSeconds)
+	assert.Equal(t, int64(3), productivity.Due, "a due date still to come does not count")
+	assert.Equal(t, int64(2), productivity.Overdue)
+	assert.InDelta(t, 2.0/3, productivity.OverdueRate, 1e-9)
+	assert.Equal(t, services.Streaks{Current: 2, Longest: 3}, productivity.Streaks)
+
+	// sans rien de terminé hier ni aujourd'hui, la série en cours est rompue
+	stats.Now = func() time.Time { return *at(22, 12) }
+	productivity, err = stats.Productivity(filter)
+	require.NoError(t, err)
+	assert.Equal(t, services.Streaks{Current: 0, Longest: 3}, productivity.Streaks)
+
+	burndown, err := stats.Burndown(filter)
+	require.NoError(t, err)
+	require.Len(t, burndown, 2)
+	assert.Equal(t, services.ListBurndown{ID: 0, Name: "Inbox", Days: []services.BurndownDay{
+		{Date: "2024-08-14", Open: 1}, {Date: "2024-08-15", Completed: 1}, {Date: "2024-08-16"}, {Date: "2024-08-17"},
+		{Date: "2024-08-18"}, {Date: "2024-08-19"}, {Date: "2024-08-20", Completed: 1},
+	}}, burndown[0])
+	assert.Equal(t, services.ListBurndown{ID: sprint.ID, Name: "Sprint", Days: []services.BurndownDay{
+		{Date: "2024-08-14"}, {Date: "2024-08-15", Open: 1}, {Date: "2024-08-16", Open: 1, Completed: 1}, {Date: "2024-08-17", Open: 1},
+		{Date: "2024-08-18", Open: 2}, {Date: "2024-08-19", Open: 2, Completed: 1}, {Date: "2024-08-20", Open: 2},
+	}}, burndown[1])
+
+	inbox := uint(0)
+	filter.ListID = &inbox
+	burndown, err = stats.Burndown(filter)
+	require.NoError(t, err)
+	require.Len(t, burndown, 1)
+	assert.Equal(t, "Inbox", burndown[0].Name)
+
+	// période par défaut : les 30 derniers jours, aujourd'hui compris
+	productivity, err = stats.Productivity(services.StatsFilter{UserID: 1, Location: time.UTC})
+	require.NoError(t, err)
+	assert.Len(t, productivity.CompletedPerDay, services.DefaultStatsDays)
+	assert.Equal(t, *at(23, 0), productivity.To)
+	assert.Equal(t, int64(7), productivity.Completed)
+	// sans utilisateur, rien : 0 ne désigne pas tous les utilisateurs
+	productivity, err = stats.Productivity(services.StatsFilter{Location: time.UTC})
+	require.NoError(t, err)
+	assert.Zero(t, productivity.Completed)
+
+	tooLong := services.StatsFilter{From: *at(0, 0), To: at(0, 0).AddDate(0, 0, services.MaxStatsDays+1), Location: time.UTC}
+	_, err = stats.Productivity(tooLong)
+	assert.ErrorIs(t, err, services.ErrStatsRangeTooLong)
+	_, err = stats.Burndown(tooLong)
+	assert.ErrorIs(t, err, services.ErrStatsRangeTooLong)
+	tooLong.To = at(0, 0).AddDate(0, 0, services.MaxStatsDays)
+	_, err = stats.Burndown(tooLong)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const defaultTagColor = "#6c757d"
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrInvalidTag  = errors.New("invalid tag")
+	tagColorRe     = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+type TagService interface {
+	List(userID uint) ([]models.TagModel, error)
+	Create(tag models.TagModel) (models.TagModel, error)
+	Update(userID, id uint, tag models.TagModel) (models.TagModel, error)
+	Delete(userID, id uint) error
+	Resolve(userID uint, names []string) ([]models.TagModel, error)
+}
+
+func NewTagServiceImp(db *gorm.DB) *TagServiceImp {
+	return &TagServiceImp{Db: db}
+}
+
+type TagServiceImp struct {
+	Db *gorm.DB
+}
+
+func (s *TagServiceImp) List(userID uint) ([]models.TagModel, error) {
+	var tags []models.TagModel
+	err := s.Db.Where("user_id = ?", userID).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (s *TagServiceImp) Create(tag models.TagModel) (models.TagModel, error) {
+	if tag.ID != 0 {
+		return models.TagModel{}, errors.New("invalid ID")
+	}
+	if err := normalizeTag(&tag); err != nil {
+		return models.TagModel{}, err
+	}
+	if err := s.Db.Create(&tag).Error; err != nil {
+		return models.TagModel{}, err
+	}
+	return tag, nil
+}
+
+func (s *TagServiceImp) Update(userID, id uint, tag models.TagModel) (models.TagModel, error) {
+	var existing models.TagModel
+	if err := s.Db.Where("user_id = ?", userID).First(&existing, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TagModel{}, ErrTagNotFound
+		}
+		return models.TagModel{}, err
+	}
+	if tag.Name == "" {
+		tag.Name = existing.Name
+	}
+	if tag.Color == "" {
+		tag.Color = existing.Color
+	}
+	if err := normalizeTag(&tag); err != nil {
+		return models.TagModel{}, err
+	}
+	if err := s.Db.Model(&existing).Updates(map[string]interface{}{"name": tag.Name, "color": tag.Color}).Error; err != nil {
+		return models.TagModel{}, err
+	}
+	return existing, nil
+}
+
+func (s *TagServiceImp) Delete(userID, id uint) error {
+	if id == 0 {
+		return errors.New("invalid ID")
+	}
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		var tag models.TagModel
+		if err := tx.Where("user_id = ?", userID).First(&tag, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+		if err := tx.Exec("DELETE FROM todo_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// Resolve retourne les étiquettes portant ces noms, sans tenir compte de la casse,
+// en créant celles qui n'existent pas encore
+func (s *TagServiceImp) Resolve(userID uint, names []string) ([]models.TagModel, error) {
+	var tags []models.TagModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			tag := models.TagModel{UserID: userID, Name: name}
+			if err := normalizeTag(&tag); err != nil {
+				return err
+			}
+			var existing models.TagModel
+			err := tx.Where("user_id = ? AND LOWER(name) = ?", userID, strings.ToLower(tag.Name)).First(&existing).Error
+			switch {
+			case err == nil:
+				tag = existing
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if err := tx.Create(&tag).Error; err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+			tags = append(tags, tag)
+		}
+		return nil
+	})
+	return tags, err
+}
+
+func normalizeTag(tag *models.TagModel) error {
+	tag.Name = strings.Join(strings.Fields(tag.Name), " ")
+	if tag.Name == "" {
+		return fmt.Errorf("%w: the name is required", ErrInvalidTag)
+	}
+	if len(tag.Name) > 64 {
+		return fmt.Errorf("%w: the name is too long", ErrInvalidTag)
+	}
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if !tagColorRe.MatchString(tag.Color) {
+		return fmt.Errorf("%w: invalid color, expected #rrggbb", ErrInvalidTag)
+	}
+	tag.Color = strings.ToLower(tag.Color)
+	return nil
+}
@@ -0,0 +1,101 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func titles(todos []models.TodoModel) []string {
+	var out []string
+	for _, todo := range todos {
+		out = append(out, todo.Title)
+	}
+	return out
+}
+
+func TestTagService(t *testing.T) {
+	db := initSQLiteDB(t)
+	tags := services.NewTagServiceImp(db)
+	todos := &services.TodoServiceImp{Db: db}
+
+	work, err := tags.Create(models.TagModel{UserID: 1, Name: "  Work ", Color: "#FF0000"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", work.Name)
+	assert.Equal(t, "#ff0000", work.Color)
+	urgent, err := tags.Create(models.TagModel{UserID: 1, Name: "urgent"})
+	assert.NoError(t, err)
+	assert.Equal(t, "#6c757d", urgent.Color)
+	other, err := tags.Create(models.TagModel{UserID: 2, Name: "work"})
+	assert.NoError(t, err)
+
+	_, err = tags.Create(models.TagModel{UserID: 1, Name: "work", Color: "red"})
+	assert.Error(t, err)
+	_, err = tags.Create(models.TagModel{UserID: 1, Name: "Work"})
+	assert.Error(t, err, "duplicate name for the same user")
+
+	both, err := todos.Create(models.TodoModel{UserID: 1, Title: "Both", TagIDs: []uint{work.ID, urgent.ID}})
+	assert.NoError(t, err)
+	assert.Len(t, both.Tags, 2)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Work only", TagIDs: []uint{work.ID}})
+	assert.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "None"})
+	assert.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Foreign", TagIDs: []uint{other.ID}})
+	assert.ErrorIs(t, err, services.ErrTagNotFound)
+
+	list, err := todos.List(services.TodoFilter{UserID: 1, Tags: []string{"WORK", "urgent"}})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Both", "Work only"}, titles(list))
+
+	list, err = todos.List(services.TodoFilter{UserID: 1, Tags: []string{"work", "urgent"}, MatchAll: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Both"}, titles(list))
+	assert.Len(t, list[0].Tags, 2)
+
+	updated, err := todos.Update(1, both.ID, services.TodoPatch{TodoModel: models.TodoModel{TagIDs: []uint{}}})
+	assert.NoError(t, err)
+	assert.Empty(t, updated.Tags)
+
+	renamed, err := tags.Update(1, work.ID, models.TagModel{Name: "Job"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Job", renamed.Name)
+	assert.Equal(t, "#ff0000", renamed.Color)
+	_, err = tags.Update(2, work.ID, models.TagModel{Name: "Stolen"})
+	assert.ErrorIs(t, err, services.ErrTagNotFound)
+
+	assert.ErrorIs(t, tags.Delete(2, work.ID), services.ErrTagNotFound)
+	assert.NoError(t, tags.Delete(1, work.ID))
+	list, err = todos.List(services.TodoFilter{UserID: 1, Tags: []string{"job"}})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	mine, err := tags.List(1)
+	assert.NoError(t, err)
+	assert.Len(t, mine, 1)
+
+	resolved, err := tags.Resolve(1, []string{"URGENT", "finance"})
+	assert.NoError(t, err)
+	assert.Equal(t, urgent.ID, resolved[0].ID)
+	assert.Equal(t, "finance", resolved[1].Name)
+	mine, _ = tags.List(1)
+	assert.Len(t, mine, 2)
+
+	// étiquettes par nom : créées avec le todo, ou pas du tout
+	missing := uint(999)
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Orphan", TagNames: []string{"ops"}, ParentID: &missing})
+	assert.ErrorIs(t, err, services.ErrInvalidParent)
+	var count int64
+	db.Model(&models.TagModel{}).Where("name = ?", "ops").Count(&count)
+	assert.Zero(t, count, "no tag is left behind by a failed create")
+	_, err = todos.Create(models.TodoModel{UserID: 1, Title: "Bad tag", TagNames: []string{strings.Repeat("x", 65)}})
+	assert.ErrorIs(t, err, services.ErrInvalidTag)
+	ops, err := todos.Create(models.TodoModel{UserID: 1, Title: "Deploy", TagNames: []string{"ops", "Finance"}})
+	assert.NoError(t, err)
+	if assert.Len(t, ops.Tags, 2) {
+		assert.ElementsMatch(t, []string{"ops", "finance"}, []string{ops.Tags[0].Name, ops.Tags[1].Name})
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/notifications"
+	"gorm.io/gorm"
+)
+
+var ErrAssigneeNotFound = errors.New("assignee not found")
+
+// Workload résume les todos ouverts assignés à une personne ; UserID 0 regroupe les todos sans responsable
+type Workload struct {
+	UserID  uint   `json:"user_id"`
+	Name    string `json:"name"`
+	Handle  string `json:"handle"`
+	Open    int64  `json:"open"`
+	Overdue int64  `json:"overdue"`
+}
+
+type TeamService interface {
+	Workload(userID uint, listID *uint) ([]Workload, error)
+}
+
+func NewTeamServiceImp(db *gorm.DB) *TeamServiceImp {
+	return &TeamServiceImp{Db: db}
+}
+
+type TeamServiceImp struct {
+	Db *gorm.DB
+}
+
+// Workload compte les todos ouverts, non archivés, et en retard de l'utilisateur par responsable,
+// en une requête groupée, éventuellement limitée à une de ses listes (0 : todos sans liste).
+// Les plus chargés d'abord.
+func (s *TeamServiceImp) Workload(userID uint, listID *uint) ([]Workload, error) {
+	type row struct {
+		AssigneeID   *uint
+		OpenCount    int64
+		OverdueCount int64
+	}
+	query := s.Db.Model(&models.TodoModel{}).
+		Select("assignee_id, COUNT(*) AS open_count, SUM(CASE WHEN due_at < ? THEN 1 ELSE 0 END) AS overdue_count", time.Now()).
+		Where("user_id = ? AND completed = ? AND archived_at IS NULL", userID, false).
+		Group("assignee_id")
+	if listID != nil {
+		if *listID == 0 {
+			query = query.Where("list_id IS NULL")
+		} else {
+			if _, err := NewListServiceImp(s.Db).find(s.Db, userID, *listID); err != nil {
+				return nil, err
+			}
+			query = query.Where("list_id = ?", *listID)
+		}
+	}
+	var rows []row
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		if r.AssigneeID != nil {
+			ids = append(ids, *r.AssigneeID)
+		}
+	}
+	users := map[uint]models.UserModel{}
+	if len(ids) > 0 {
+		var found []models.UserModel
+		if err := s.Db.Select("id, name, handle").Where("id IN ?", ids).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, u := range found {
+			users[u.ID] = u
+		}
+	}
+
+	workloads := make([]Workload, 0, len(rows))
+	for _, r := range rows {
+		w := Workload{Open: r.OpenCount, Overdue: r.OverdueCount}
+		if r.AssigneeID != nil {
+			u := users[*r.AssigneeID]
+			w.UserID, w.Name, w.Handle = *r.AssigneeID, u.Name, u.Handle
+		}
+		workloads = append(workloads, w)
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Open != workloads[j].Open {
+			return workloads[i].Open > workloads[j].Open
+		}
+		return workloads[i].UserID < workloads[j].UserID
+	})
+	return workloads, nil
+}
+
+func checkAssignee(tx *gorm.DB, userID uint) error {
+	var count int64
+	if err := tx.Model(&models.UserModel{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAssigneeNotFound
+	}
+	return nil
+}
+
+// notifyAssignee prévient le responsable du todo, sauf s'il s'est assigné lui-même
+func notifyAssignee(tx *gorm.DB, todo models.TodoModel, actorID uint) error {
+	if todo.AssigneeID == nil || *todo.AssigneeID == actorID {
+		return nil
+	}
+	actor := "Someone"
+	var user models.UserModel
+	if actorID != 0 {
+		if err := tx.First(&user, actorID).Error; err == nil {
+			actor = user.Name
+		}
+	}
+	return notifications.Record(tx, models.NotificationModel{
+		UserID:  *todo.AssigneeID,
+		Type:    models.NotificationAssigned,
+		ActorID: actorID,
+		TodoID:  todo.ID,
+		Message: truncate(fmt.Sprintf("%s assigned you %q", actor, todo.Title), 255),
+	})
+}
@@ -0,0 +1,139 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/blobstore"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssigneesAndWorkload(t *testing.T) {
+	db := initSQLiteDB(t)
+	users := services.NewUserServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+	team := services.NewTeamServiceImp(db)
+
+	alice, err := users.Create(models.UserModel{Name: "Alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	bob, err := users.Create(models.UserModel{Name: "Bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	id := func(v uint) *uint { return &v }
+	yesterday := time.Now().Add(-24 * time.Hour)
+
+	_, err = todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Nobody", AssigneeID: id(999)})
+	assert.ErrorIs(t, err, services.ErrAssigneeNotFound)
+
+	review, err := todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Review PR", AssigneeID: id(bob.ID), DueAt: &yesterday})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Deploy", AssigneeID: id(bob.ID)})
+	require.NoError(t, err)
+	mine, err := todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Plan", AssigneeID: id(alice.ID)})
+	require.NoError(t, err)
+	_, err = todos.Create(models.TodoModel{UserID: bob.ID, ActorID: bob.ID, Title: "Unassigned", DueAt: &yesterday})
+	require.NoError(t, err)
+	done, err := todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Done", AssigneeID: id(bob.ID), Completed: true})
+	require.NoError(t, err)
+
+	var notifications []models.NotificationModel
+	db.Order("id").Find(&notifications)
+	assert.Len(t, notifications, 3, "self-assignment is not notified")
+	assert.Equal(t, bob.ID, notifications[0].UserID)
+	assert.Equal(t, models.NotificationAssigned, notifications[0].Type)
+	assert.Equal(t, `Alice assigned you "Review PR"`, notifications[0].Message)
+
+	assigned, err := todos.List(services.TodoFilter{UserID: bob.ID, Assignee: id(bob.ID)})
+	assert.NoError(t, err)
+	assert.Len(t, assigned, 3, "todos assigned to the caller are listed whatever their owner")
+	assigned, err = todos.List(services.TodoFilter{UserID: 3, Assignee: id(bob.ID)})
+	assert.NoError(t, err)
+	assert.Empty(t, assigned, "other users' todos stay hidden")
+	unassigned, err := todos.List(services.TodoFilter{UserID: bob.ID, Assignee: id(0)})
+	assert.NoError(t, err)
+	assert.Len(t, unassigned, 1)
+	anonymous, err := todos.List(services.TodoFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, anonymous, "no header, no todos")
+
+	workload, err := team.Workload(alice.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.Workload{
+		{UserID: bob.ID, Name: "Bob", Handle: "bob", Open: 2, Overdue: 1},
+		{UserID: alice.ID, Name: "Alice", Handle: "alice", Open: 1, Overdue: 0},
+	}, workload, "only the caller's todos")
+	workload, err = team.Workload(bob.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.Workload{{UserID: 0, Open: 1, Overdue: 1}}, workload)
+	project, err := services.NewListServiceImp(db).Create(models.ListModel{UserID: alice.ID, Name: "Project"})
+	require.NoError(t, err)
+	_, err = team.Workload(bob.ID, &project.ID)
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+
+	// réassigner notifie le nouveau responsable et apparaît dans l'historique
+	updated, err := todos.Update(alice.ID, mine.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: alice.ID, AssigneeID: id(bob.ID)}})
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, *updated.AssigneeID)
+	var activity models.TodoActivity
+	db.Where("todo_id = ?", mine.ID).Order("id DESC").First(&activity)
+	assert.Equal(t, models.Change{From: float64(alice.ID), To: float64(bob.ID)}, activity.Changes["assignee_id"])
+	_, err = todos.Update(alice.ID, review.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: bob.ID, AssigneeID: id(0)}})
+	assert.NoError(t, err)
+	_, err = todos.Update(alice.ID, done.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: alice.ID, Title: "Done!"}, Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	var count int64
+	db.Model(&models.NotificationModel{}).Count(&count)
+	assert.Equal(t, int64(4), count, "only a change of assignee is notified")
+
+	_, err = services.NewArchiveServiceImp(db).ArchiveTodo(alice.ID, updated.ID)
+	require.NoError(t, err)
+	workload, err = team.Workload(alice.ID, id(0))
+	assert.NoError(t, err)
+	assert.Equal(t, []services.Workload{
+		{UserID: 0, Open: 1, Overdue: 1},
+		{UserID: bob.ID, Name: "Bob", Handle: "bob", Open: 1, Overdue: 0},
+	}, workload, "archived todos are not counted")
+}
+
+func TestAssigneeWork(t *testing.T) {
+	db := initSQLiteDB(t)
+	users := services.NewUserServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+	store, err := blobstore.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	attachments := services.NewAttachmentServiceImp(db, store, 1024)
+
+	alice, err := users.Create(models.UserModel{Name: "Alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	bob, err := users.Create(models.UserModel{Name: "Bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	carol, err := users.Create(models.UserModel{Name: "Carol", Email: "carol@example.com"})
+	require.NoError(t, err)
+	todo, err := todos.Create(models.TodoModel{UserID: alice.ID, ActorID: alice.ID, Title: "Review PR", AssigneeID: &bob.ID})
+	require.NoError(t, err)
+
+	// l'assigné travaille sur le todo sans pouvoir le modifier
+	_, err = todos.Update(bob.ID, todo.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: bob.ID, Title: "Mine now"}})
+	assert.ErrorIs(t, err, services.ErrAssigneeWork)
+	_, err = todos.Update(carol.ID, todo.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: carol.ID}, Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	done, err := todos.Update(bob.ID, todo.ID, services.TodoPatch{TodoModel: models.TodoModel{ActorID: bob.ID}, Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	assert.True(t, done.Completed)
+	assert.Equal(t, "Review PR", done.Title)
+
+	timer, err := services.NewTimeServiceImp(db).Start(bob.ID, todo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, bob.ID, timer.UserID)
+	_, err = services.NewTimeServiceImp(db).Start(carol.ID, todo.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+
+	notes, err := attachments.Upload(todo.ID, bob.ID, "notes.txt", strings.NewReader("hello"), 5)
+	assert.NoError(t, err)
+	_, err = attachments.Upload(todo.ID, carol.ID, "notes.txt", strings.NewReader("hello"), 5)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	assert.NoError(t, attachments.Delete(bob.ID, notes.ID))
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/exchange"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTemplateItems  = 200
+	maxDueOffsetDays  = 3650
+	maxTemplateTitle  = 255
+	maxTemplateLength = 128
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrInvalidTemplate  = errors.New("invalid template")
+)
+
+// TemplateInstantiation précise où et à partir de quand créer les todos d'un modèle
+type TemplateInstantiation struct {
+	Start    time.Time // les échéances sont décalées à partir de cette date
+	ListID   *uint     // liste existante ; nil : une nouvelle liste est créée
+	ListName string    // nom de la nouvelle liste, celui du modèle par défaut
+}
+
+// TemplateInstance est le résultat d'une instanciation, les todos en arborescence
+type TemplateInstance struct {
+	List  models.ListModel   `json:"list"`
+	Todos []models.TodoModel `json:"todos"`
+}
+
+type TemplateService interface {
+	List(userID uint) ([]models.TemplateModel, error)
+	Get(userID, id uint) (models.TemplateModel, error)
+	Create(template models.TemplateModel) (models.TemplateModel, error)
+	Update(userID, id uint, template models.TemplateModel) (models.TemplateModel, error)
+	Delete(userID, id uint) error
+	FromList(userID, listID uint, name string) (models.TemplateModel, error)
+	Instantiate(userID, id uint, options TemplateInstantiation) (TemplateInstance, error)
+}
+
+func NewTemplateServiceImp(db *gorm.DB) *TemplateServiceImp {
+	return &TemplateServiceImp{Db: db}
+}
+
+type TemplateServiceImp struct {
+	Db *gorm.DB
+}
+
+func (s *TemplateServiceImp) List(userID uint) ([]models.TemplateModel, error) {
+	var templates []models.TemplateModel
+	err := s.Db.Where("user_id = ?", userID).Order("name").Find(&templates).Error
+	return templates, err
+}
+
+func (s *TemplateServiceImp) Get(userID, id uint) (models.TemplateModel, error) {
+	return s.find(s.Db, userID, id)
+}
+
+func (s *TemplateServiceImp) Create(template models.TemplateModel) (models.TemplateModel, error) {
+	if template.ID != 0 {
+		return models.TemplateModel{}, errors.New("invalid ID")
+	}
+	if err := normalizeTemplate(&template); err != nil {
+		return models.TemplateModel{}, err
+	}
+	if err := s.Db.Create(&template).Error; err != nil {
+		return models.TemplateModel{}, err
+	}
+	return template, nil
+}
+
+// Update remplace le nom, la description et les todos du modèle
+func (s *TemplateServiceImp) Update(userID, id uint, template models.TemplateModel) (models.TemplateModel, error) {
+	existing, err := s.find(s.Db, userID, id)
+	if err != nil {
+		return models.TemplateModel{}, err
+	}
+	if err := normalizeTemplate(&template); err != nil {
+		return models.TemplateModel{}, err
+	}
+	existing.Name, existing.Description, existing.Items = template.Name, template.Description, template.Items
+	err = s.Db.Model(&existing).Updates(map[string]interface{}{
+		"name": existing.Name, "description": existing.Description, "items": existing.Items, "updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return models.TemplateModel{}, err
+	}
+	return existing, nil
+}
+
+func (s *TemplateServiceImp) Delete(userID, id uint) error {
+	result := s.Db.Where("user_id = ?", userID).Delete(&models.TemplateModel{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// FromList enregistre les todos non archivés d'une liste comme modèle. Les échéances deviennent des décalages
+// en jours par rapport à la plus proche d'entre elles.
+func (s *TemplateServiceImp) FromList(userID, listID uint, name string) (models.TemplateModel, error) {
+	var list models.ListModel
+	if err := s.Db.Where("user_id = ?", userID).First(&list, listID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TemplateModel{}, ErrListNotFound
+		}
+		return models.TemplateModel{}, err
+	}
+	var todos []models.TodoModel
+	err := s.Db.Preload("Tags").Where("list_id = ? AND archived_at IS NULL", listID).Order("position").Order("id").Find(&todos).Error
+	if err != nil {
+		return models.TemplateModel{}, err
+	}
+
+	var start time.Time
+	for _, todo := range todos {
+		if todo.DueAt != nil && (start.IsZero() || todo.DueAt.Before(start)) {
+			start = *todo.DueAt
+		}
+	}
+	start = startOfDay(start.In(time.Local))
+	var items func(todos []models.TodoModel) []models.TemplateItem
+	items = func(todos []models.TodoModel) []models.TemplateItem {
+		result := make([]models.TemplateItem, 0, len(todos))
+		for _, todo := range todos {
+			item := models.TemplateItem{
+				Title:           todo.Title,
+				Description:     todo.Description,
+				Priority:        todo.Priority,
+				EstimateMinutes: todo.EstimateMinutes,
+				Subtasks:        items(todo.Children),
+			}
+			for _, tag := range todo.Tags {
+				item.Tags = append(item.Tags, tag.Name)
+			}
+			if todo.DueAt != nil {
+				offset := daysBetween(start, startOfDay(todo.DueAt.In(time.Local)))
+				item.DueOffsetDays = &offset
+			}
+			result = append(result, item)
+		}
+		return result
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = list.Name
+	}
+	return s.Create(models.TemplateModel{UserID: userID, Name: name, Items: items(BuildTree(todos))})
+}
+
+// Instantiate crée la liste et tous les todos du modèle dans une seule transaction : en cas d'erreur,
+// rien n'est créé. Les todos passent par TodoService.Create (statut, position, historique, événements).
+func (s *TemplateServiceImp) Instantiate(userID, id uint, options TemplateInstantiation) (TemplateInstance, error) {
+	if options.Start.IsZero() {
+		options.Start = startOfDay(time.Now())
+	}
+	var instance TemplateInstance
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		template, err := s.find(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		lists := NewListServiceImp(tx)
+		if options.ListID != nil {
+			instance.List, err = lists.find(tx, userID, *options.ListID)
+		} else {
+			name := options.ListName
+			if strings.TrimSpace(name) == "" {
+				name = template.Name
+			}
+			instance.List, err = lists.Create(models.ListModel{UserID: userID, Name: name})
+		}
+		if err != nil {
+			return err
+		}
+
+		todos, tags := NewTodoServiceImp(tx, ""), NewTagServiceImp(tx)
+		var create func(items []models.TemplateItem, parentID *uint) error
+		create = func(items []models.TemplateItem, parentID *uint) error {
+			for _, item := range items {
+				todo := models.TodoModel{
+					UserID:          userID,
+					ActorID:         userID,
+					Title:           item.Title,
+					Description:     item.Description,
+					Priority:        item.Priority,
+					ListID:          &instance.List.ID,
+					ParentID:        parentID,
+					EstimateMinutes: item.EstimateMinutes,
+				}
+				if item.DueOffsetDays != nil {
+					due := options.Start.AddDate(0, 0, *item.DueOffsetDays)
+					todo.DueAt = &due
+				}
+				if len(item.Tags) > 0 {
+					resolved, err := tags.Resolve(userID, item.Tags)
+					if err != nil {
+						return err
+					}
+					for _, tag := range resolved {
+						todo.TagIDs = append(todo.TagIDs, tag.ID)
+					}
+				}
+				created, err := todos.Create(todo)
+				if err != nil {
+					return fmt.Errorf("%s: %w", item.Title, err)
+				}
+				instance.Todos = append(instance.Todos, created)
+				if err := create(item.Subtasks, &created.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return create(template.Items, nil)
+	})
+	if err != nil {
+		return TemplateInstance{}, err
+	}
+	instance.Todos = BuildTree(instance.Todos)
+	return instance, nil
+}
+
+func (s *TemplateServiceImp) find(tx *gorm.DB, userID, id uint) (models.TemplateModel, error) {
+	var template models.TemplateModel
+	if err := tx.Where("user_id = ?", userID).First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TemplateModel{}, ErrTemplateNotFound
+		}
+		return models.TemplateModel{}, err
+	}
+	return template, nil
+}
+
+// normalizeTemplate vérifie le modèle : nombre de todos, profondeur des sous-tâches, priorités et décalages
+func normalizeTemplate(template *models.TemplateModel) error {
+	template.Name = strings.Join(strings.Fields(template.Name), " ")
+	if template.Name == "" || len(template.Name) > maxTemplateLength {
+		return fmt.Errorf("%w: a name of 1 to %d bytes is required", ErrInvalidTemplate, maxTemplateLength)
+	}
+	count := 0
+	var normalize func(items []models.TemplateItem, depth int) error
+	normalize = func(items []models.TemplateItem, depth int) error {
+		if len(items) > 0 && depth > DefaultMaxDepth {
+			return fmt.Errorf("%w: %w", ErrInvalidTemplate, ErrMaxDepth)
+		}
+		for i := range items {
+			item := &items[i]
+			if count++; count > maxTemplateItems {
+				return fmt.Errorf("%w: at most %d todos expected", ErrInvalidTemplate, maxTemplateItems)
+			}
+			item.Title = strings.TrimSpace(item.Title)
+			if item.Title == "" || len(item.Title) > maxTemplateTitle {
+				return fmt.Errorf("%w: every todo needs a title of 1 to %d bytes", ErrInvalidTemplate, maxTemplateTitle)
+			}
+			if !exchange.ValidPriority(item.Priority) {
+				return fmt.Errorf("%w: invalid priority %q", ErrInvalidTemplate, item.Priority)
+			}
+			if item.DueOffsetDays != nil && (*item.DueOffsetDays < -maxDueOffsetDays || *item.DueOffsetDays > maxDueOffsetDays) {
+				return fmt.Errorf("%w: due offsets must stay within %d days", ErrInvalidTemplate, maxDueOffsetDays)
+			}
+			if item.EstimateMinutes != nil && *item.EstimateMinutes < 0 {
+				return fmt.Errorf("%w: %w", ErrInvalidTemplate, ErrInvalidEstimate)
+			}
+			var tags []string
+			for _, tag := range item.Tags {
+				if tag = strings.Join(strings.Fields(tag), " "); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			item.Tags = tags
+			if err := normalize(item.Subtasks, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := normalize(template.Items, 1); err != nil {
+		return err
+	}
+	if template.Items == nil {
+		template.Items = models.TemplateItems{}
+	}
+	return nil
+}
+
+// daysBetween compte les jours calendaires de from à to, deux débuts de journée
+func daysBetween(from, to time.Time) int {
+	days := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		days++
+	}
+	for day := from; day.After(to); day = day.AddDate(0, 0, -1) {
+		days--
+	}
+	return days
+}
@@ -0,0 +1,142 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestTemplateValidation(t *testing.T) {
+	service := services.NewTemplateServiceImp(initSQLiteDB(t))
+
+	deep := models.TemplateItem{Title: "level 6"}
+	for i := 5; i > 0; i-- {
+		deep = models.TemplateItem{Title: "level", Subtasks: []models.TemplateItem{deep}}
+	}
+	for _, template := range []models.TemplateModel{
+		{Name: "  "},
+		{Name: "Sprint", Items: models.TemplateItems{{Title: " "}}},
+		{Name: "Sprint", Items: models.TemplateItems{{Title: "Plan", Priority: "high"}}},
+		{Name: "Sprint", Items: models.TemplateItems{{Title: "Plan", DueOffsetDays: intPtr(5000)}}},
+		{Name: "Sprint", Items: models.TemplateItems{{Title: "Plan", EstimateMinutes: intPtr(-1)}}},
+		{Name: "Sprint", Items: models.TemplateItems{deep}},
+	} {
+		_, err := service.Create(template)
+		assert.ErrorIs(t, err, services.ErrInvalidTemplate, template.Name)
+	}
+
+	created, err := service.Create(models.TemplateModel{UserID: 1, Name: " Weekly  review ", Items: models.TemplateItems{
+		{Title: " Inbox zero ", Tags: []string{" admin ", ""}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly review", created.Name)
+	assert.Equal(t, "Inbox zero", created.Items[0].Title)
+	assert.Equal(t, []string{"admin"}, created.Items[0].Tags)
+
+	_, err = service.Get(2, created.ID)
+	assert.ErrorIs(t, err, services.ErrTemplateNotFound)
+	templates, err := service.List(1)
+	require.NoError(t, err)
+	assert.Len(t, templates, 1)
+
+	updated, err := service.Update(1, created.ID, models.TemplateModel{Name: "Weekly", Items: models.TemplateItems{{Title: "Review"}}})
+	require.NoError(t, err)
+	fetched, err := service.Get(1, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated.Items, fetched.Items)
+	assert.Equal(t, "Weekly", fetched.Name)
+
+	assert.ErrorIs(t, service.Delete(2, created.ID), services.ErrTemplateNotFound)
+	assert.NoError(t, service.Delete(1, created.ID))
+}
+
+func TestTemplateInstantiate(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTemplateServiceImp(db)
+
+	template, err := service.Create(models.TemplateModel{UserID: 1, Name: "Onboarding", Items: models.TemplateItems{
+		{Title: "Accounts", Priority: "A", DueOffsetDays: intPtr(1), Tags: []string{"it"}, Subtasks: []models.TemplateItem{
+			{Title: "Email", DueOffsetDays: intPtr(0), EstimateMinutes: intPtr(15)},
+			{Title: "Laptop", Tags: []string{"it", "hardware"}},
+		}},
+		{Title: "First review", DueOffsetDays: intPtr(30)},
+	}})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 9, 2, 0, 0, 0, 0, time.Local)
+	instance, err := service.Instantiate(1, template.ID, services.TemplateInstantiation{Start: start, ListName: "Onboarding Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "Onboarding Alice", instance.List.Name)
+	require.Len(t, instance.Todos, 2)
+	accounts := instance.Todos[0]
+	assert.Equal(t, "Accounts", accounts.Title)
+	assert.Equal(t, "A", accounts.Priority)
+	assert.True(t, start.AddDate(0, 0, 1).Equal(*accounts.DueAt))
+	require.Len(t, accounts.Children, 2)
+	assert.True(t, start.Equal(*accounts.Children[0].DueAt))
+	assert.Equal(t, 15, *accounts.Children[0].EstimateMinutes)
+	assert.Nil(t, accounts.Children[1].DueAt)
+	assert.True(t, start.AddDate(0, 0, 30).Equal(*instance.Todos[1].DueAt))
+
+	var todos []models.TodoModel
+	require.NoError(t, db.Preload("Tags").Where("list_id = ?", instance.List.ID).Order("id").Find(&todos).Error)
+	require.Len(t, todos, 4)
+	assert.Equal(t, accounts.ID, *todos[1].ParentID)
+	require.Len(t, todos[2].Tags, 2)
+	var tags int64
+	db.Model(&models.TagModel{}).Where("user_id = ?", 1).Count(&tags)
+	assert.Equal(t, int64(2), tags)
+
+	archivedAt := time.Now()
+	require.NoError(t, db.Create(&models.TodoModel{UserID: 1, ListID: &instance.List.ID, Title: "Old", ArchivedAt: &archivedAt}).Error)
+
+	// le modèle tiré de la liste retrouve les décalages et l'arborescence, sans les todos archivés
+	saved, err := service.FromList(1, instance.List.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Onboarding Alice", saved.Name)
+	require.Len(t, saved.Items, 2)
+	assert.Equal(t, 1, *saved.Items[0].DueOffsetDays)
+	assert.Equal(t, []string{"it"}, saved.Items[0].Tags)
+	assert.Equal(t, 0, *saved.Items[0].Subtasks[0].DueOffsetDays)
+	assert.Equal(t, 30, *saved.Items[1].DueOffsetDays)
+	_, err = service.FromList(2, instance.List.ID, "")
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+
+	_, err = service.Instantiate(2, template.ID, services.TemplateInstantiation{Start: start})
+	assert.ErrorIs(t, err, services.ErrTemplateNotFound)
+}
+
+func TestTemplateInstantiateRollback(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTemplateServiceImp(db)
+	lists := services.NewListServiceImp(db)
+
+	list, err := lists.Create(models.ListModel{UserID: 1, Name: "Releases"})
+	require.NoError(t, err)
+	_, err = lists.SetFields(1, list.ID, []models.CustomField{{Name: "env", Type: models.FieldText, Required: true}})
+	require.NoError(t, err)
+	template, err := service.Create(models.TemplateModel{UserID: 1, Name: "Release", Items: models.TemplateItems{
+		{Title: "Freeze", Tags: []string{"release"}},
+	}})
+	require.NoError(t, err)
+
+	// le champ obligatoire de la liste fait échouer la création : ni todo ni étiquette ne restent
+	_, err = service.Instantiate(1, template.ID, services.TemplateInstantiation{ListID: &list.ID})
+	assert.ErrorIs(t, err, services.ErrInvalidFieldValue)
+	var todos, tags int64
+	db.Model(&models.TodoModel{}).Count(&todos)
+	db.Model(&models.TagModel{}).Count(&tags)
+	assert.Zero(t, todos)
+	assert.Zero(t, tags)
+
+	other, err := lists.Create(models.ListModel{UserID: 2, Name: "Other"})
+	require.NoError(t, err)
+	_, err = service.Instantiate(1, template.ID, services.TemplateInstantiation{ListID: &other.ID})
+	assert.ErrorIs(t, err, services.ErrListNotFound)
+}
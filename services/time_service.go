@@ -0,0 +1,375 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/exchange"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidEstimate   = errors.New("the estimate must be a positive number of minutes")
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	ErrInvalidTimeEntry  = errors.New("a time entry needs a start before its end")
+	ErrNoRunningTimer    = errors.New("no timer is running on this todo")
+	ErrNotEntryOwner     = errors.New("only the owner can change a time entry")
+)
+
+// Regroupements des totaux de temps
+const (
+	GroupByTodo = "todo"
+	GroupByList = "list"
+	GroupByUser = "user"
+)
+
+// TimeFilter restreint les entrées prises en compte ; les bornes portent sur StartedAt, To exclu
+type TimeFilter struct {
+	UserID uint // auteur des entrées, toujours appliqué
+	TodoID uint
+	ListID *uint // 0 : todos sans liste
+	From   time.Time
+	To     time.Time
+}
+
+// TimeEntryPatch est la correction d'une entrée : seuls les champs fournis sont modifiés
+type TimeEntryPatch struct {
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      *string    `json:"note"`
+}
+
+// TimeTotal est le temps passé sur un todo, une liste ou par un utilisateur
+type TimeTotal struct {
+	ID              uint   `json:"id"` // 0 : todos sans liste
+	Name            string `json:"name"`
+	Seconds         int64  `json:"seconds"`
+	EstimateMinutes *int   `json:"estimate_minutes,omitempty"` // regroupement par todo uniquement
+}
+
+type TimeService interface {
+	Start(userID, todoID uint) (models.TimeEntry, error)
+	Stop(userID, todoID uint) (models.TimeEntry, error)
+	List(userID, todoID uint) ([]models.TimeEntry, error)
+	Create(entry models.TimeEntry) (models.TimeEntry, error)
+	Update(userID, id uint, patch TimeEntryPatch) (models.TimeEntry, error)
+	Delete(userID, id uint) error
+	Totals(filter TimeFilter, groupBy string) ([]TimeTotal, error)
+	Timesheet(w io.Writer, filter TimeFilter) error
+}
+
+func NewTimeServiceImp(db *gorm.DB) *TimeServiceImp {
+	return &TimeServiceImp{Db: db}
+}
+
+type TimeServiceImp struct {
+	Db *gorm.DB
+}
+
+// Start démarre un chronomètre sur un todo de l'utilisateur ou qui lui est assigné ; celui
+// qui tournait pour l'utilisateur sur un autre todo est arrêté. Relancer le chronomètre en cours le retourne tel quel.
+func (s *TimeServiceImp) Start(userID, todoID uint) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if _, err := findMemberTodo(tx, userID, todoID); err != nil {
+			return err
+		}
+		var running []models.TimeEntry
+		if err := tx.Where("user_id = ? AND ended_at IS NULL", userID).Find(&running).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, r := range running {
+			if r.TodoID == todoID {
+				entry = r
+				return nil
+			}
+			if err := stopEntry(tx, &r, now); err != nil {
+				return err
+			}
+		}
+		entry = models.TimeEntry{TodoID: todoID, UserID: userID, StartedAt: now}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+func (s *TimeServiceImp) Stop(userID, todoID uint) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := s.Db.Where("user_id = ? AND todo_id = ? AND ended_at IS NULL", userID, todoID).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TimeEntry{}, ErrNoRunningTimer
+		}
+		return models.TimeEntry{}, err
+	}
+	if err := stopEntry(s.Db, &entry, time.Now()); err != nil {
+		return models.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+func (s *TimeServiceImp) List(userID, todoID uint) ([]models.TimeEntry, error) {
+	if _, err := findMemberTodo(s.Db, userID, todoID); err != nil {
+		return nil, err
+	}
+	var entries []models.TimeEntry
+	if err := s.Db.Where("todo_id = ?", todoID).Order("started_at").Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Create enregistre une période saisie à la main, début et fin obligatoires
+func (s *TimeServiceImp) Create(entry models.TimeEntry) (models.TimeEntry, error) {
+	if entry.ID != 0 {
+		return models.TimeEntry{}, errors.New("invalid ID")
+	}
+	if entry.EndedAt == nil {
+		return models.TimeEntry{}, ErrInvalidTimeEntry
+	}
+	if err := normalizeTimeEntry(&entry); err != nil {
+		return models.TimeEntry{}, err
+	}
+	if _, err := findMemberTodo(s.Db, entry.UserID, entry.TodoID); err != nil {
+		return models.TimeEntry{}, err
+	}
+	if err := s.Db.Create(&entry).Error; err != nil {
+		return models.TimeEntry{}, err
+	}
+	return entry, nil
+}
+
+// Update corrige le début, la fin et la note d'une entrée de l'utilisateur ; un champ absent
+// est conservé. Sans fin, une entrée en cours continue de tourner ; une entrée terminée garde sa fin.
+func (s *TimeServiceImp) Update(userID, id uint, patch TimeEntryPatch) (models.TimeEntry, error) {
+	existing, err := s.owned(userID, id)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	if patch.StartedAt != nil && !patch.StartedAt.IsZero() {
+		existing.StartedAt = *patch.StartedAt
+	}
+	if patch.EndedAt != nil {
+		existing.EndedAt = patch.EndedAt
+	}
+	if patch.Note != nil {
+		existing.Note = *patch.Note
+	}
+	if err := normalizeTimeEntry(&existing); err != nil {
+		return models.TimeEntry{}, err
+	}
+	err = s.Db.Model(&existing).Updates(map[string]interface{}{
+		"started_at": existing.StartedAt,
+		"ended_at":   existing.EndedAt,
+		"seconds":    existing.Seconds,
+		"note":       existing.Note,
+		"updated_at": time.Now(),
+	}).Error
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	return existing, nil
+}
+
+func (s *TimeServiceImp) Delete(userID, id uint) error {
+	entry, err := s.owned(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.Db.Delete(&entry).Error
+}
+
+// Totals additionne les entrées terminées par todo, liste ou utilisateur, en une requête
+// groupée ; les plus longs d'abord
+func (s *TimeServiceImp) Totals(filter TimeFilter, groupBy string) ([]TimeTotal, error) {
+	var totals []TimeTotal
+	query := s.filtered(filter).Where("time_entries.ended_at IS NOT NULL")
+	switch groupBy {
+	case "", GroupByTodo:
+		err := query.Select("time_entries.todo_id AS id, todo_models.title AS name, todo_models.estimate_minutes, SUM(time_entries.seconds) AS seconds").
+			Group("time_entries.todo_id, todo_models.title, todo_models.estimate_minutes").
+			Scan(&totals).Error
+		if err != nil {
+			return nil, err
+		}
+	case GroupByList:
+		err := query.Select("COALESCE(todo_models.list_id, 0) AS id, SUM(time_entries.seconds) AS seconds").
+			Group("todo_models.list_id").Scan(&totals).Error
+		if err != nil {
+			return nil, err
+		}
+		var lists []models.ListModel
+		if err := s.Db.Select("id, name").Where("id IN ?", totalIDs(totals)).Find(&lists).Error; err != nil {
+			return nil, err
+		}
+		names := map[uint]string{0: "Inbox"}
+		for _, l := range lists {
+			names[l.ID] = l.Name
+		}
+		nameTotals(totals, names)
+	case GroupByUser:
+		err := query.Select("time_entries.user_id AS id, SUM(time_entries.seconds) AS seconds").
+			Group("time_entries.user_id").Scan(&totals).Error
+		if err != nil {
+			return nil, err
+		}
+		var users []models.UserModel
+		if err := s.Db.Select("id, name").Where("id IN ?", totalIDs(totals)).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		names := map[uint]string{}
+		for _, u := range users {
+			names[u.ID] = u.Name
+		}
+		nameTotals(totals, names)
+	default:
+		return nil, fmt.Errorf("invalid group %q", groupBy)
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Seconds != totals[j].Seconds {
+			return totals[i].Seconds > totals[j].Seconds
+		}
+		return totals[i].ID < totals[j].ID
+	})
+	return totals, nil
+}
+
+// Timesheet écrit les entrées terminées en CSV, une ligne par période, pour la facturation
+func (s *TimeServiceImp) Timesheet(w io.Writer, filter TimeFilter) error {
+	type row struct {
+		StartedAt time.Time
+		EndedAt   time.Time
+		Seconds   int64
+		Note      string
+		TodoID    uint
+		Title     string
+		ListName  *string
+		UserName  *string
+	}
+	var rows []row
+	err := s.filtered(filter).
+		Select("time_entries.started_at, time_entries.ended_at, time_entries.seconds, time_entries.note, " +
+			"time_entries.todo_id, todo_models.title, list_models.name AS list_name, user_models.name AS user_name").
+		Joins("LEFT JOIN list_models ON list_models.id = todo_models.list_id").
+		Joins("LEFT JOIN user_models ON user_models.id = time_entries.user_id").
+		Where("time_entries.ended_at IS NOT NULL").
+		Order("time_entries.started_at").Order("time_entries.id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "user", "todo_id", "todo", "list", "started_at", "ended_at", "minutes", "hours", "note"}); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		list, user := "Inbox", ""
+		if r.ListName != nil {
+			list = *r.ListName
+		}
+		if r.UserName != nil {
+			user = *r.UserName
+		}
+		record := []string{
+			r.StartedAt.Format("2006-01-02"),
+			exchange.CSVCell(user),
+			strconv.FormatUint(uint64(r.TodoID), 10),
+			exchange.CSVCell(r.Title),
+			exchange.CSVCell(list),
+			r.StartedAt.Format(time.RFC3339),
+			r.EndedAt.Format(time.RFC3339),
+			strconv.FormatInt((r.Seconds+30)/60, 10),
+			strconv.FormatFloat(float64(r.Seconds)/3600, 'f', 2, 64),
+			exchange.CSVCell(r.Note),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *TimeServiceImp) filtered(filter TimeFilter) *gorm.DB {
+	query := s.Db.Table("time_entries").Joins("JOIN todo_models ON todo_models.id = time_entries.todo_id")
+	query = query.Where("time_entries.user_id = ?", filter.UserID)
+	if filter.TodoID != 0 {
+		query = query.Where("time_entries.todo_id = ?", filter.TodoID)
+	}
+	if filter.ListID != nil {
+		if *filter.ListID == 0 {
+			query = query.Where("todo_models.list_id IS NULL")
+		} else {
+			query = query.Where("todo_models.list_id = ?", *filter.ListID)
+		}
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("time_entries.started_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("time_entries.started_at < ?", filter.To)
+	}
+	return query
+}
+
+func totalIDs(totals []TimeTotal) []uint {
+	ids := make([]uint, len(totals))
+	for i, t := range totals {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+func nameTotals(totals []TimeTotal, names map[uint]string) {
+	for i := range totals {
+		totals[i].Name = names[totals[i].ID]
+	}
+}
+
+func (s *TimeServiceImp) owned(userID, id uint) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	if err := s.Db.First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.TimeEntry{}, ErrTimeEntryNotFound
+		}
+		return models.TimeEntry{}, err
+	}
+	if entry.UserID != userID {
+		return models.TimeEntry{}, ErrNotEntryOwner
+	}
+	return entry, nil
+}
+
+func stopEntry(tx *gorm.DB, entry *models.TimeEntry, at time.Time) error {
+	entry.EndedAt = &at
+	entry.Seconds = int64(at.Sub(entry.StartedAt).Seconds())
+	return tx.Model(entry).Updates(map[string]interface{}{"ended_at": at, "seconds": entry.Seconds, "updated_at": at}).Error
+}
+
+// normalizeTimeEntry vérifie les bornes et recalcule la durée ; une entrée sans fin dure 0
+func normalizeTimeEntry(entry *models.TimeEntry) error {
+	if entry.StartedAt.IsZero() || entry.StartedAt.After(time.Now()) {
+		return ErrInvalidTimeEntry
+	}
+	entry.Seconds = 0
+	if entry.EndedAt != nil {
+		if !entry.EndedAt.After(entry.StartedAt) {
+			return ErrInvalidTimeEntry
+		}
+		entry.Seconds = int64(entry.EndedAt.Sub(entry.StartedAt).Seconds())
+	}
+	entry.Note = truncate(strings.TrimSpace(entry.Note), 255)
+	return nil
+}
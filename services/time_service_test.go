@@ -0,0 +1,130 @@
+package services_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeService(t *testing.T) {
+	db := initSQLiteDB(t)
+	users := services.NewUserServiceImp(db)
+	lists := services.NewListServiceImp(db)
+	todos := services.NewTodoServiceImp(db, "")
+	timer := services.NewTimeServiceImp(db)
+
+	alice, err := users.Create(models.UserModel{Name: "Alice", Email: "alice@example.com"})
+	require.NoError(t, err)
+	bob, err := users.Create(models.UserModel{Name: "Bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	client, err := lists.Create(models.ListModel{UserID: alice.ID, Name: "ACME"})
+	require.NoError(t, err)
+
+	minutes := func(n int) *int { return &n }
+	_, err = todos.Create(models.TodoModel{UserID: alice.ID, Title: "Negative", EstimateMinutes: minutes(-5)})
+	assert.ErrorIs(t, err, services.ErrInvalidEstimate)
+	design, err := todos.Create(models.TodoModel{UserID: alice.ID, Title: "Design", ListID: &client.ID, EstimateMinutes: minutes(120)})
+	require.NoError(t, err)
+	assert.Equal(t, 120, *design.EstimateMinutes)
+	build, err := todos.Create(models.TodoModel{UserID: alice.ID, Title: "Build"})
+	require.NoError(t, err)
+
+	// un seul chronomètre par utilisateur : en démarrer un autre arrête le premier
+	first, err := timer.Start(alice.ID, design.ID)
+	require.NoError(t, err)
+	again, err := timer.Start(alice.ID, design.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+	_, err = timer.Start(alice.ID, build.ID)
+	assert.NoError(t, err)
+	entries, err := timer.List(alice.ID, design.ID)
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotNil(t, entries[0].EndedAt)
+	_, err = timer.Stop(alice.ID, design.ID)
+	assert.ErrorIs(t, err, services.ErrNoRunningTimer)
+	stopped, err := timer.Stop(alice.ID, build.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, stopped.EndedAt)
+	_, err = timer.Start(alice.ID, 999)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = timer.Start(bob.ID, design.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = timer.List(bob.ID, design.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+
+	// saisie manuelle
+	day := time.Date(2024, 8, 12, 9, 0, 0, 0, time.UTC)
+	end := day.Add(90 * time.Minute)
+	manual, err := timer.Create(models.TimeEntry{TodoID: design.ID, UserID: alice.ID, StartedAt: day, EndedAt: &end, Note: " workshop "})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5400), manual.Seconds)
+	assert.Equal(t, "workshop", manual.Note)
+	_, err = timer.Create(models.TimeEntry{TodoID: design.ID, UserID: alice.ID, StartedAt: end, EndedAt: &day})
+	assert.ErrorIs(t, err, services.ErrInvalidTimeEntry)
+	bobEnd := day.Add(time.Hour)
+	_, err = timer.Create(models.TimeEntry{TodoID: build.ID, UserID: bob.ID, StartedAt: day, EndedAt: &bobEnd})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound, "only on the user's own todos")
+	bobsBuild, err := todos.Create(models.TodoModel{UserID: bob.ID, Title: "Build"})
+	require.NoError(t, err)
+	bobs, err := timer.Create(models.TimeEntry{TodoID: bobsBuild.ID, UserID: bob.ID, StartedAt: day, EndedAt: &bobEnd, Note: "=1+1"})
+	require.NoError(t, err)
+
+	mine, notes := "mine", "workshop + notes"
+	_, err = timer.Update(bob.ID, manual.ID, services.TimeEntryPatch{Note: &mine})
+	assert.ErrorIs(t, err, services.ErrNotEntryOwner)
+	longer := day.Add(2 * time.Hour)
+	edited, err := timer.Update(alice.ID, manual.ID, services.TimeEntryPatch{EndedAt: &longer, Note: &notes})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7200), edited.Seconds)
+	assert.Equal(t, day, edited.StartedAt)
+	edited, err = timer.Update(alice.ID, manual.ID, services.TimeEntryPatch{StartedAt: &day})
+	assert.NoError(t, err)
+	assert.Equal(t, "workshop + notes", edited.Note, "the note is kept when not sent")
+
+	byTodo, err := timer.Totals(services.TimeFilter{UserID: alice.ID, From: day, To: day.AddDate(0, 0, 1)}, services.GroupByTodo)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.TimeTotal{{ID: design.ID, Name: "Design", Seconds: 7200, EstimateMinutes: minutes(120)}}, byTodo, "only the user's entries")
+
+	byList, err := timer.Totals(services.TimeFilter{UserID: bob.ID, From: day, To: day.AddDate(0, 0, 1)}, services.GroupByList)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.TimeTotal{{ID: 0, Name: "Inbox", Seconds: 3600}}, byList)
+	anonymous, err := timer.Totals(services.TimeFilter{From: day, To: day.AddDate(0, 0, 1)}, services.GroupByList)
+	assert.NoError(t, err)
+	assert.Empty(t, anonymous, "0 is not every user")
+
+	byUser, err := timer.Totals(services.TimeFilter{UserID: alice.ID, ListID: &client.ID, From: day, To: day.AddDate(0, 0, 1)}, services.GroupByUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []services.TimeTotal{{ID: alice.ID, Name: "Alice", Seconds: 7200}}, byUser)
+
+	_, err = timer.Totals(services.TimeFilter{}, "project")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, timer.Timesheet(&buf, services.TimeFilter{UserID: alice.ID, From: day, To: day.AddDate(0, 0, 1)}))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"date", "user", "todo_id", "todo", "list", "started_at", "ended_at", "minutes", "hours", "note"}, records[0])
+	assert.Equal(t, []string{"2024-08-12", "Alice", "1", "Design", "ACME"}, records[1][:5])
+	assert.Equal(t, []string{"120", "2.00", "workshop + notes"}, records[1][7:])
+	buf.Reset()
+	require.NoError(t, timer.Timesheet(&buf, services.TimeFilter{UserID: bob.ID, From: day, To: day.AddDate(0, 0, 1)}))
+	records, err = csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"Bob", "Build", "Inbox", "60", "1.00", "'=1+1"}, []string{records[1][1], records[1][3], records[1][4], records[1][7], records[1][8], records[1][9]}, "formulas are neutralised")
+
+	assert.ErrorIs(t, timer.Delete(alice.ID, bobs.ID), services.ErrNotEntryOwner)
+	assert.NoError(t, timer.Delete(bob.ID, bobs.ID))
+	assert.NoError(t, todos.Delete(alice.ID, design.ID))
+	var left int64
+	db.Model(&models.TimeEntry{}).Where("todo_id = ?", design.ID).Count(&left)
+	assert.Zero(t, left)
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidDependency = errors.New("dependencies must be other todos of the same user")
+	ErrDependencyCycle   = errors.New("dependency would create a cycle")
+	ErrOpenDependencies  = errors.New("todo has open dependencies")
+)
+
+type GraphNode struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	Completed bool   `json:"completed"`
+	Blocked   bool   `json:"blocked"`
+}
+
+// GraphEdge indique que From dépend de To
+type GraphEdge struct {
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
+
+// DependencyGraph contient les dépendances transitives d'un todo et les todos qui en dépendent
+type DependencyGraph struct {
+	Root  uint        `json:"root"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// setDependencies remplace les dépendances du todo après avoir vérifié l'absence de cycle
+func setDependencies(tx *gorm.DB, todo *models.TodoModel, ids []uint) error {
+	unique := map[uint]bool{}
+	var depIDs []uint
+	for _, id := range ids {
+		if id == todo.ID {
+			return ErrDependencyCycle
+		}
+		if !unique[id] {
+			unique[id] = true
+			depIDs = append(depIDs, id)
+		}
+	}
+	sort.Slice(depIDs, func(i, j int) bool { return depIDs[i] < depIDs[j] })
+
+	if len(depIDs) > 0 {
+		var count int64
+		err := tx.Model(&models.TodoModel{}).Where("id IN ? AND user_id = ?", depIDs, todo.UserID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if int(count) != len(depIDs) {
+			return ErrInvalidDependency
+		}
+		for _, id := range depIDs {
+			cycle, err := dependsOn(tx, id, todo.ID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrDependencyCycle
+			}
+		}
+	}
+
+	if err := tx.Where("todo_id = ?", todo.ID).Delete(&models.TodoDependency{}).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, id := range depIDs {
+		dep := models.TodoDependency{TodoID: todo.ID, DependsOnID: id, CreatedAt: now}
+		if err := tx.Create(&dep).Error; err != nil {
+			return err
+		}
+	}
+	todo.DependsOn = depIDs
+	return nil
+}
+
+// dependsOn indique si from dépend, directement ou non, de target
+func dependsOn(tx *gorm.DB, from, target uint) (bool, error) {
+	seen := map[uint]bool{from: true}
+	frontier := []uint{from}
+	for len(frontier) > 0 {
+		var next []uint
+		err := tx.Model(&models.TodoDependency{}).Where("todo_id IN ?", frontier).Pluck("depends_on_id", &next).Error
+		if err != nil {
+			return false, err
+		}
+		frontier = nil
+		for _, id := range next {
+			if id == target {
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// openDependencies retourne les dépendances non terminées du todo
+func openDependencies(tx *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Table("todo_dependencies").
+		Joins("JOIN todo_models ON todo_models.id = todo_dependencies.depends_on_id").
+		Where("todo_dependencies.todo_id = ? AND todo_models.completed = ?", id, false).
+		Order("todo_dependencies.depends_on_id").
+		Pluck("todo_dependencies.depends_on_id", &ids).Error
+	return ids, err
+}
+
+// withDependencies renseigne DependsOn et Blocked
+func withDependencies(tx *gorm.DB, todos []models.TodoModel) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	index := make(map[uint]int, len(todos))
+	ids := make([]uint, len(todos))
+	for i, todo := range todos {
+		index[todo.ID] = i
+		ids[i] = todo.ID
+	}
+
+	var rows []struct {
+		TodoID      uint
+		DependsOnID uint
+		Completed   bool
+	}
+	err := tx.Table("todo_dependencies").
+		Select("todo_dependencies.todo_id, todo_dependencies.depends_on_id, todo_models.completed").
+		Joins("JOIN todo_models ON todo_models.id = todo_dependencies.depends_on_id").
+		Where("todo_dependencies.todo_id IN ?", ids).
+		Order("todo_dependencies.depends_on_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		todo := &todos[index[row.TodoID]]
+		todo.DependsOn = append(todo.DependsOn, row.DependsOnID)
+		if !row.Completed {
+			todo.Blocked = true
+		}
+	}
+	return nil
+}
+
+// Graph retourne le graphe de dépendances autour du todo : ce dont il dépend
+// transitivement et ce qui dépend de lui
+func (s *TodoServiceImp) Graph(userID, id uint) (DependencyGraph, error) {
+	if _, err := findUserTodo(s.Db, userID, id); err != nil {
+		return DependencyGraph{}, err
+	}
+
+	graph := DependencyGraph{Root: id, Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	nodes := map[uint]bool{id: true}
+	// amont en suivant todo_id, puis aval en suivant depends_on_id
+	for _, column := range []string{"todo_id", "depends_on_id"} {
+		seen := map[uint]bool{id: true}
+		frontier := []uint{id}
+		for len(frontier) > 0 {
+			var deps []models.TodoDependency
+			if err := s.Db.Where(column+" IN ?", frontier).Find(&deps).Error; err != nil {
+				return DependencyGraph{}, err
+			}
+			frontier = nil
+			for _, dep := range deps {
+				next := dep.DependsOnID
+				if column == "depends_on_id" {
+					next = dep.TodoID
+				}
+				if !seen[next] {
+					seen[next] = true
+					nodes[next] = true
+					frontier = append(frontier, next)
+				}
+			}
+		}
+	}
+
+	ids := make([]uint, 0, len(nodes))
+	for nodeID := range nodes {
+		ids = append(ids, nodeID)
+	}
+	var todos []models.TodoModel
+	if err := s.Db.Where("id IN ?", ids).Order("id").Find(&todos).Error; err != nil {
+		return DependencyGraph{}, err
+	}
+	if err := withDependencies(s.Db, todos); err != nil {
+		return DependencyGraph{}, err
+	}
+	for _, todo := range todos {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: todo.ID, Title: todo.Title, Completed: todo.Completed, Blocked: todo.Blocked})
+	}
+	// toutes les arêtes entre les nœuds retenus, pas seulement celles parcourues
+	var deps []models.TodoDependency
+	err := s.Db.Where("todo_id IN ? AND depends_on_id IN ?", ids, ids).
+		Order("todo_id").Order("depends_on_id").Find(&deps).Error
+	if err != nil {
+		return DependencyGraph{}, err
+	}
+	for _, dep := range deps {
+		graph.Edges = append(graph.Edges, GraphEdge{From: dep.TodoID, To: dep.DependsOnID})
+	}
+	return graph, nil
+}
@@ -4,29 +4,159 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/exchange"
+	"github.com/go-todo1/position"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrTodoNotFound  = errors.New("todo not found")
+	ErrInvalidAnchor = errors.New("exactly one of before or after must reference another todo of the same list")
+	ErrAssigneeWork  = errors.New("an assignee can only change the status of the todo")
+)
+
+const (
+	SortPosition = "position"
+	SortPriority = "priority"
+)
+
+// TodoFilter restreint la liste des todos ; Tags contient des noms d'étiquettes
+type TodoFilter struct {
+	UserID   uint   // propriétaire, toujours appliqué
+	ListID   *uint  // 0 : todos sans liste
+	Status   string // colonne du flux de travail
+	Assignee *uint  // 0 : todos sans responsable ; UserID : aussi ceux des autres qui lui sont confiés
+	Tags     []string
+	MatchAll bool             // vrai : le todo doit porter toutes les étiquettes, sinon au moins une
+	Fields   []FieldCondition // conditions sur les champs personnalisés, toutes requises
+	Sort     string           // SortPosition (par défaut), SortPriority ou un champ, voir SortFieldPrefix
+	Tree     bool             // imbriquer les sous-tâches dans Children
+	Archived bool             // inclure les todos archivés
+}
+
+// TodoPatch est le corps d'une mise à jour : Completed n'est appliqué que s'il est fourni,
+// pour qu'une modification du seul titre ne rouvre pas un todo terminé
+type TodoPatch struct {
+	models.TodoModel
+	Completed *bool `json:"completed"`
+	// Replace efface les champs laissés vides au lieu de les conserver (PUT CalDAV)
+	Replace bool `json:"-"`
+}
+
 type TodoService interface {
+	List(filter TodoFilter) ([]models.TodoModel, error)
 	Create(todo models.TodoModel) (models.TodoModel, error)
-	Update(id uint, todo models.TodoModel) (models.TodoModel, error)
-	Delete(id uint) error
+	Update(userID, id uint, patch TodoPatch) (models.TodoModel, error)
+	Delete(userID, id uint) error
+	Move(userID, id, before, after uint) (models.TodoModel, error)
+	Graph(userID, id uint) (DependencyGraph, error)
 	GetQuote() (models.QuoteResponse, error)
+	Export(userID uint, w io.Writer, format string) error
+	Import(userID uint, r io.Reader, format string, dryRun bool) (exchange.Report, error)
 }
 
 func NewTodoServiceImp(db *gorm.DB, apiKey string) *TodoServiceImp {
-	return &TodoServiceImp{Db: db, APIKey: apiKey}
+	return &TodoServiceImp{Db: db, APIKey: apiKey, MaxDepth: DefaultMaxDepth}
 }
 
 type TodoServiceImp struct {
-	Db      *gorm.DB
-	APIKey  string
-	Service TodoService
+	Db       *gorm.DB
+	APIKey   string
+	Service  TodoService
+	MaxDepth int // niveaux de sous-tâches autorisés, DefaultMaxDepth si nul
+}
+
+// List retourne les todos de filter.UserID ; en filtrant sur lui-même comme responsable,
+// l'utilisateur voit aussi les todos des autres qui lui sont confiés
+func (s *TodoServiceImp) List(filter TodoFilter) ([]models.TodoModel, error) {
+	query := s.Db.Preload("Tags")
+	if filter.Assignee != nil && *filter.Assignee != 0 && *filter.Assignee == filter.UserID {
+		query = query.Where("todo_models.user_id = ? OR todo_models.assignee_id = ?", filter.UserID, filter.UserID)
+	} else {
+		query = query.Where("todo_models.user_id = ?", filter.UserID)
+	}
+	if filter.ListID != nil {
+		if *filter.ListID == 0 {
+			query = query.Where("todo_models.list_id IS NULL")
+		} else {
+			query = query.Where("todo_models.list_id = ?", *filter.ListID)
+		}
+	}
+	if filter.Status != "" {
+		query = query.Where("todo_models.status = ?", filter.Status)
+	}
+	if !filter.Archived {
+		query = query.Where("todo_models.archived_at IS NULL")
+	}
+	if filter.Assignee != nil {
+		if *filter.Assignee == 0 {
+			query = query.Where("todo_models.assignee_id IS NULL")
+		} else {
+			query = query.Where("todo_models.assignee_id = ?", *filter.Assignee)
+		}
+	}
+	if len(filter.Tags) > 0 {
+		names := make([]string, len(filter.Tags))
+		for i, name := range filter.Tags {
+			names[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+		tagged := s.Db.Table("todo_tags").Select("todo_tags.todo_id").
+			Joins("JOIN tag_models ON tag_models.id = todo_tags.tag_id").
+			Where("LOWER(tag_models.name) IN ?", names)
+		if filter.MatchAll {
+			tagged = tagged.Group("todo_tags.todo_id").Having("COUNT(DISTINCT LOWER(tag_models.name)) = ?", len(names))
+		}
+		query = query.Where("todo_models.id IN (?)", tagged)
+	}
+	for _, cond := range filter.Fields {
+		matching, err := fieldFilter(s.Db, filter, cond)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("todo_models.id IN (?)", matching)
+	}
+
+	switch {
+	case filter.Sort == "", filter.Sort == SortPosition:
+	case filter.Sort == SortPriority:
+		// sans priorité en dernier, puis de A à Z
+		query = query.Order("CASE WHEN todo_models.priority = '' OR todo_models.priority IS NULL THEN 1 ELSE 0 END").
+			Order("todo_models.priority")
+	case IsFieldSort(filter.Sort):
+		var err error
+		if query, err = sortByField(s.Db, query, filter); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid sort %q", filter.Sort)
+	}
+
+	var todos []models.TodoModel
+	if err := query.Order("todo_models.position").Order("todo_models.id").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	if err := withProgress(s.Db, todos); err != nil {
+		return nil, err
+	}
+	if err := withDependencies(s.Db, todos); err != nil {
+		return nil, err
+	}
+	if err := withFields(s.Db, todos); err != nil {
+		return nil, err
+	}
+	if filter.Tree {
+		return BuildTree(todos), nil
+	}
+	return todos, nil
 }
 
 // Create
@@ -34,35 +164,330 @@ func (s *TodoServiceImp) Create(todo models.TodoModel) (models.TodoModel, error)
 	if todo.ID != 0 {
 		return models.TodoModel{}, fmt.Errorf("invalid ID")
 	}
+	if !exchange.ValidPriority(todo.Priority) {
+		return models.TodoModel{}, errors.New("invalid priority")
+	}
+	if todo.Recurrence != "" {
+		recurrence, err := normalizeRecurrence(todo.Recurrence)
+		if err != nil {
+			return models.TodoModel{}, err
+		}
+		todo.Recurrence = recurrence
+	}
+
+	tagIDs, tagNames, dependsOn, fields := todo.TagIDs, todo.TagNames, todo.DependsOn, todo.Fields
+	todo.Tags, todo.TagIDs, todo.TagNames, todo.Children, todo.DependsOn, todo.Fields = nil, nil, nil, nil, nil, nil
+	if todo.ParentID != nil && *todo.ParentID == 0 {
+		todo.ParentID = nil
+	}
+	if todo.ListID != nil && *todo.ListID == 0 {
+		todo.ListID = nil
+	}
+	if todo.AssigneeID != nil && *todo.AssigneeID == 0 {
+		todo.AssigneeID = nil
+	}
+	if todo.EstimateMinutes != nil && *todo.EstimateMinutes < 0 {
+		return models.TodoModel{}, ErrInvalidEstimate
+	}
+	if todo.EstimateMinutes != nil && *todo.EstimateMinutes == 0 {
+		todo.EstimateMinutes = nil
+	}
 
-	fmt.Println(&todo)
 	err := s.Db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&todo).Error; err != nil {
+		// le statut fait foi ; sans statut, il est déduit de completed
+		workflow, err := workflowFor(tx, todo.UserID, todo.ListID)
+		if err != nil {
 			return err
 		}
-		return nil
+		status, err := workflow.resolve(todo.Status, todo.Completed)
+		if err != nil {
+			return err
+		}
+		todo.Status, todo.Completed = status.Name, status.Done
+		stampCompletion(&todo)
+
+		if todo.ParentID != nil {
+			if err := s.checkParent(tx, todo, *todo.ParentID); err != nil {
+				return err
+			}
+		}
+		if todo.AssigneeID != nil {
+			if err := checkAssignee(tx, *todo.AssigneeID); err != nil {
+				return err
+			}
+		}
+		if err := appendPosition(tx, &todo); err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags").Create(&todo).Error; err != nil {
+			return err
+		}
+		if len(tagNames) > 0 {
+			tags, err := NewTagServiceImp(tx).Resolve(todo.UserID, tagNames)
+			if err != nil {
+				return err
+			}
+			for _, tag := range tags {
+				tagIDs = append(tagIDs, tag.ID)
+			}
+		}
+		if tagIDs != nil {
+			if err := setTodoTags(tx, &todo, tagIDs); err != nil {
+				return err
+			}
+		}
+		if err := setFieldValues(tx, &todo, fields, true); err != nil {
+			return err
+		}
+		if len(dependsOn) > 0 {
+			if err := setDependencies(tx, &todo, dependsOn); err != nil {
+				return err
+			}
+			open, err := openDependencies(tx, todo.ID)
+			if err != nil {
+				return err
+			}
+			todo.Blocked = len(open) > 0
+			if todo.Blocked && todo.Completed && !todo.IgnoreDependencies {
+				return ErrOpenDependencies
+			}
+		}
+		if err := recordActivity(tx, todo.ID, todo.ActorID, models.ActivityCreated, nil); err != nil {
+			return err
+		}
+		if err := notifyAssignee(tx, todo, todo.ActorID); err != nil {
+			return err
+		}
+		return events.Record(tx, events.TodoCreated, todo)
 	})
 
 	return todo, err
 }
 
-func (s *TodoServiceImp) Update(id uint, todo models.TodoModel) (models.TodoModel, error) {
-	var existingTodo models.TodoModel
-	if err := s.Db.First(&existingTodo, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.TodoModel{}, errors.New("todo not found")
+// Update modifie un todo de l'utilisateur ; l'assigné peut seulement en changer le statut
+// ou le terminer, les todos des autres sont introuvables
+func (s *TodoServiceImp) Update(userID, id uint, patch TodoPatch) (models.TodoModel, error) {
+	todo := patch.TodoModel
+	if !exchange.ValidPriority(todo.Priority) {
+		return models.TodoModel{}, errors.New("invalid priority")
+	}
+	if todo.EstimateMinutes != nil && *todo.EstimateMinutes < 0 {
+		return models.TodoModel{}, ErrInvalidEstimate
+	}
+	if todo.Recurrence != "" {
+		recurrence, err := normalizeRecurrence(todo.Recurrence)
+		if err != nil {
+			return models.TodoModel{}, err
 		}
-		return models.TodoModel{}, err
+		todo.Recurrence = recurrence
 	}
 
-	todo.UpdatedAt = time.Now()
-	if err := s.Db.Model(&existingTodo).Updates(todo).Error; err != nil {
+	var existingTodo models.TodoModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Tags").Where("(user_id = ? OR assignee_id = ?)", userID, userID).First(&existingTodo, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTodoNotFound
+			}
+			return err
+		}
+		if existingTodo.UserID != userID && !statusOnly(patch) {
+			return ErrAssigneeWork
+		}
+		if err := loadFields(tx, &existingTodo); err != nil {
+			return err
+		}
+		before := existingTodo
+		existingTodo.ActorID = todo.ActorID
+
+		wasCompleted := existingTodo.Completed
+		if patch.Completed != nil {
+			todo.Completed = *patch.Completed
+		} else {
+			todo.Completed = wasCompleted
+		}
+		updates := map[string]interface{}{
+			"updated_at": time.Now(),
+		}
+		if todo.Title != "" {
+			updates["title"] = todo.Title
+		}
+		if todo.Description != "" {
+			updates["description"] = todo.Description
+		}
+		if todo.Priority != "" {
+			updates["priority"] = todo.Priority
+		}
+		if todo.DueAt != nil {
+			updates["due_at"] = todo.DueAt
+		}
+		if todo.Projects != "" {
+			updates["projects"] = todo.Projects
+		}
+		if todo.Contexts != "" {
+			updates["contexts"] = todo.Contexts
+		}
+		if todo.Recurrence != "" {
+			updates["recurrence"] = todo.Recurrence
+		}
+		if patch.Replace {
+			// les propriétés absentes de la nouvelle version sont effacées
+			if todo.Description == "" {
+				updates["description"] = ""
+			}
+			if todo.Priority == "" {
+				updates["priority"] = ""
+			}
+			if todo.DueAt == nil {
+				updates["due_at"] = nil
+			}
+			if todo.Projects == "" {
+				updates["projects"] = ""
+			}
+			if todo.Recurrence == "" {
+				updates["recurrence"] = ""
+			}
+		}
+		if todo.ParentID != nil {
+			if *todo.ParentID == 0 {
+				updates["parent_id"] = nil
+			} else {
+				if err := s.checkParent(tx, existingTodo, *todo.ParentID); err != nil {
+					return err
+				}
+				updates["parent_id"] = *todo.ParentID
+			}
+		}
+		if todo.EstimateMinutes != nil {
+			if *todo.EstimateMinutes == 0 {
+				updates["estimate_minutes"] = nil
+				existingTodo.EstimateMinutes = nil
+			} else {
+				estimate := *todo.EstimateMinutes
+				updates["estimate_minutes"] = estimate
+				existingTodo.EstimateMinutes = &estimate
+			}
+		}
+		if todo.AssigneeID != nil {
+			if *todo.AssigneeID == 0 {
+				updates["assignee_id"] = nil
+				existingTodo.AssigneeID = nil
+			} else {
+				if err := checkAssignee(tx, *todo.AssigneeID); err != nil {
+					return err
+				}
+				assigneeID := *todo.AssigneeID
+				updates["assignee_id"] = assigneeID
+				existingTodo.AssigneeID = &assigneeID
+			}
+		}
+		if err := applyStatus(tx, &existingTodo, &todo, updates); err != nil {
+			return err
+		}
+		if _, moved := updates["list_id"]; moved {
+			// les valeurs des champs de l'ancienne liste sont perdues
+			if err := dropStaleFieldValues(tx, existingTodo); err != nil {
+				return err
+			}
+			existingTodo.Fields = nil
+		}
+		if err := setFieldValues(tx, &existingTodo, todo.Fields, false); err != nil {
+			return err
+		}
+		if todo.DependsOn != nil {
+			if err := setDependencies(tx, &existingTodo, todo.DependsOn); err != nil {
+				return err
+			}
+		}
+		open, err := openDependencies(tx, existingTodo.ID)
+		if err != nil {
+			return err
+		}
+		if todo.Completed && !wasCompleted && len(open) > 0 && !todo.IgnoreDependencies {
+			return ErrOpenDependencies
+		}
+		existingTodo.Blocked = len(open) > 0
+
+		var cascade []models.TodoModel
+		if todo.Completed && !wasCompleted {
+			children, err := descendants(tx, existingTodo.ID)
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				if !child.Completed {
+					cascade = append(cascade, child)
+				}
+			}
+			if len(cascade) > 0 && !todo.CompleteChildren {
+				return ErrIncompleteChildren
+			}
+		}
+		switch {
+		case todo.Completed && !wasCompleted:
+			completedAt := time.Now()
+			if todo.CompletedAt != nil {
+				completedAt = *todo.CompletedAt
+			}
+			updates["completed_at"] = completedAt
+		case !todo.Completed && wasCompleted:
+			updates["completed_at"] = nil
+		}
+		if err := tx.Model(&existingTodo).Omit("Tags").Updates(updates).Error; err != nil {
+			return err
+		}
+		if todo.TagIDs != nil {
+			if err := setTodoTags(tx, &existingTodo, todo.TagIDs); err != nil {
+				return err
+			}
+		}
+		for _, child := range cascade {
+			workflow, err := workflowFor(tx, child.UserID, child.ListID)
+			if err != nil {
+				return err
+			}
+			now, status := time.Now(), workflow.Final().Name
+			err = tx.Model(&child).Updates(map[string]interface{}{"completed": true, "status": status, "completed_at": now, "updated_at": now}).Error
+			if err != nil {
+				return err
+			}
+			changes := models.Changes{"completed": {From: false, To: true}, "status": {From: child.Status, To: status}}
+			child.Completed, child.Status, child.CompletedAt = true, status, &now
+			if err := recordActivity(tx, child.ID, todo.ActorID, models.ActivityCompleted, changes); err != nil {
+				return err
+			}
+			if err := events.Record(tx, events.TodoCompleted, child); err != nil {
+				return err
+			}
+		}
+		if err := recordActivity(tx, existingTodo.ID, todo.ActorID, activityAction(before, existingTodo), todoChanges(before, existingTodo)); err != nil {
+			return err
+		}
+		if idValue(existingTodo.AssigneeID) != idValue(before.AssigneeID) {
+			if err := notifyAssignee(tx, existingTodo, todo.ActorID); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case !wasCompleted && existingTodo.Completed:
+			if err := events.Record(tx, events.TodoCompleted, existingTodo); err != nil {
+				return err
+			}
+			if existingTodo.Recurrence != "" {
+				return nextOccurrence(tx, &existingTodo)
+			}
+		case wasCompleted && !existingTodo.Completed:
+			return events.Record(tx, events.TodoReopened, existingTodo)
+		}
+		return nil
+	})
+	if err != nil {
 		return models.TodoModel{}, err
 	}
 	return existingTodo, nil
 }
 
-func (s *TodoServiceImp) Delete(id uint) error {
+func (s *TodoServiceImp) Delete(userID, id uint) error {
 	if id == 0 {
 		return errors.New("invalid ID")
 	}
@@ -71,8 +496,24 @@ func (s *TodoServiceImp) Delete(id uint) error {
 	if tx.Error != nil {
 		return tx.Error
 	}
+	if _, err := findUserTodo(tx, userID, id); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	if err := tx.Delete(&models.TodoModel{}, id).Error; err != nil {
+	// les sous-tâches sont supprimées avec leur parent, les plus profondes d'abord
+	subtasks, err := descendants(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i := len(subtasks) - 1; i >= 0; i-- {
+		if err := deleteTodo(tx, subtasks[i].ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := deleteTodo(tx, id); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -80,6 +521,311 @@ func (s *TodoServiceImp) Delete(id uint) error {
 	return tx.Commit().Error
 }
 
+func deleteTodo(tx *gorm.DB, id uint) error {
+	for _, table := range []string{"todo_tags", "comment_models", "todo_activities", "time_entries", "custom_field_values"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE todo_id = ?", id).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("DELETE FROM todo_dependencies WHERE todo_id = ? OR depends_on_id = ?", id, id).Error; err != nil {
+		return err
+	}
+	// les sessions de concentration restent dans les statistiques, sans todo
+	if err := tx.Exec("UPDATE focus_sessions SET todo_id = NULL WHERE todo_id = ?", id).Error; err != nil {
+		return err
+	}
+	res := tx.Delete(&models.TodoModel{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return events.Record(tx, events.TodoDeleted, models.TodoModel{ID: id})
+	}
+	return nil
+}
+
+// Move place le todo juste avant before ou juste après after, des todos de la même
+// liste du même utilisateur. Seule la position du todo déplacé change.
+func (s *TodoServiceImp) Move(userID, id, before, after uint) (models.TodoModel, error) {
+	if (before == 0) == (after == 0) || before == id || after == id {
+		return models.TodoModel{}, ErrInvalidAnchor
+	}
+
+	var todo models.TodoModel
+	err := s.Db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if todo, err = findUserTodo(tx, userID, id); err != nil {
+			return err
+		}
+		var anchor models.TodoModel
+		anchors := tx.Where("user_id = ?", todo.UserID)
+		if todo.ListID == nil {
+			anchors = anchors.Where("list_id IS NULL")
+		} else {
+			anchors = anchors.Where("list_id = ?", *todo.ListID)
+		}
+		if err := anchors.First(&anchor, before+after).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidAnchor
+			}
+			return err
+		}
+
+		// voisin de l'ancre du côté où le todo est inséré, le todo lui-même exclu
+		neighbours := tx.Model(&models.TodoModel{}).Where("user_id = ? AND id <> ?", todo.UserID, todo.ID)
+		var lo, hi string
+		if before != 0 {
+			hi = anchor.Position
+			err = neighbours.Where("position < ?", hi).Select("COALESCE(MAX(position), '')").Scan(&lo).Error
+		} else {
+			lo = anchor.Position
+			err = neighbours.Where("position > ?", lo).Select("COALESCE(MIN(position), '')").Scan(&hi).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		pos, err := position.Between(lo, hi)
+		if err != nil {
+			return err
+		}
+		todo.Position = pos
+		return tx.Model(&todo).Omit("Tags").Updates(map[string]interface{}{"position": pos, "updated_at": time.Now()}).Error
+	})
+	if err != nil {
+		return models.TodoModel{}, err
+	}
+	return todo, nil
+}
+
+// EnsurePositions attribue une position, dans l'ordre de création, aux todos qui n'en ont pas
+func (s *TodoServiceImp) EnsurePositions() error {
+	var todos []models.TodoModel
+	if err := s.Db.Where("position = '' OR position IS NULL").Order("id").Find(&todos).Error; err != nil {
+		return err
+	}
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		for i := range todos {
+			if err := appendPosition(tx, &todos[i]); err != nil {
+				return err
+			}
+			if err := tx.Model(&todos[i]).UpdateColumn("position", todos[i].Position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Export écrit les todos non archivés de l'utilisateur dans le format demandé, par lots pour limiter la mémoire
+func (s *TodoServiceImp) Export(userID uint, w io.Writer, format string) error {
+	enc, err := exchange.NewEncoder(w, format)
+	if err != nil {
+		return err
+	}
+
+	var batch []models.TodoModel
+	res := s.Db.Where("user_id = ? AND archived_at IS NULL", userID).Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, n int) error {
+		for _, todo := range batch {
+			if err := enc.Encode(todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	return enc.Close()
+}
+
+// Import crée les todos lus dans r pour l'utilisateur, en une seule transaction. Les lignes
+// invalides et les doublons (même titre qu'un de ses todos ou qu'un todo déjà importé) sont
+// ignorés et reportés.
+func (s *TodoServiceImp) Import(userID uint, r io.Reader, format string, dryRun bool) (exchange.Report, error) {
+	records, err := exchange.Decode(r, format)
+	if err != nil {
+		return exchange.Report{}, err
+	}
+
+	report := exchange.Report{
+		Format:     format,
+		DryRun:     dryRun,
+		Total:      len(records),
+		Duplicates: []exchange.RowError{},
+		Errors:     []exchange.RowError{},
+		Todos:      []models.TodoModel{},
+	}
+
+	var titles []string
+	if err := s.Db.Model(&models.TodoModel{}).Where("user_id = ?", userID).Pluck("title", &titles).Error; err != nil {
+		return exchange.Report{}, err
+	}
+	seen := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		seen[exchange.DuplicateKey(title)] = true
+	}
+
+	for _, rec := range records {
+		if rec.Err != nil {
+			report.Errors = append(report.Errors, exchange.RowError{Row: rec.Row, Message: rec.Err.Error()})
+			continue
+		}
+		key := exchange.DuplicateKey(rec.Todo.Title)
+		if seen[key] {
+			report.Duplicates = append(report.Duplicates, exchange.RowError{Row: rec.Row, Message: fmt.Sprintf("duplicate title %q", rec.Todo.Title)})
+			continue
+		}
+		seen[key] = true
+		report.Todos = append(report.Todos, rec.Todo)
+	}
+	report.Imported = len(report.Todos)
+
+	if dryRun || len(report.Todos) == 0 {
+		return report, nil
+	}
+
+	err = s.Db.Transaction(func(tx *gorm.DB) error {
+		for i := range report.Todos {
+			todo := &report.Todos[i]
+			todo.UserID, todo.ActorID = userID, userID
+			workflow, err := workflowFor(tx, userID, todo.ListID)
+			if err != nil {
+				return err
+			}
+			status, err := workflow.resolve("", todo.Completed)
+			if err != nil {
+				return err
+			}
+			todo.Status, todo.Completed = status.Name, status.Done
+			stampCompletion(todo)
+			if err := appendPosition(tx, todo); err != nil {
+				return err
+			}
+			if err := tx.Create(todo).Error; err != nil {
+				return err
+			}
+			if err := recordActivity(tx, todo.ID, todo.ActorID, models.ActivityCreated, nil); err != nil {
+				return err
+			}
+			if err := events.Record(tx, events.TodoCreated, *todo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return exchange.Report{}, err
+	}
+	return report, nil
+}
+
+// applyStatus détermine la colonne du todo mis à jour et en déduit completed. Un statut explicite
+// doit respecter les transitions du flux ; sinon la colonne suit completed. Changer de liste
+// conserve la colonne si le nouveau flux la connaît.
+// statusOnly indique que la modification ne touche qu'au statut et à la complétion
+func statusOnly(patch TodoPatch) bool {
+	rest := patch.TodoModel
+	rest.ID, rest.UserID, rest.ActorID = 0, 0, 0
+	rest.Status, rest.Completed, rest.CompletedAt = "", false, nil
+	rest.CompleteChildren, rest.IgnoreDependencies = false, false
+	return !patch.Replace && reflect.DeepEqual(rest, models.TodoModel{})
+}
+
+func applyStatus(tx *gorm.DB, existing, todo *models.TodoModel, updates map[string]interface{}) error {
+	listID, moved := existing.ListID, false
+	if todo.ListID != nil {
+		if *todo.ListID == 0 {
+			listID = nil
+		} else {
+			listID = todo.ListID
+		}
+		moved = (listID == nil) != (existing.ListID == nil) || (listID != nil && *listID != *existing.ListID)
+	}
+	workflow, err := workflowFor(tx, existing.UserID, listID)
+	if err != nil {
+		return err
+	}
+
+	var status models.WorkflowStatus
+	current, known := workflow.Status(existing.Status)
+	switch {
+	case todo.Status != "":
+		if status, err = workflow.resolve(todo.Status, false); err != nil {
+			return err
+		}
+		if !moved && !workflow.Allows(existing.Status, status.Name) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, existing.Status, status.Name)
+		}
+	case known && current.Done == todo.Completed:
+		status = current
+	default:
+		status, _ = workflow.resolve("", todo.Completed)
+		if known && !moved && !workflow.Allows(existing.Status, status.Name) {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, existing.Status, status.Name)
+		}
+	}
+
+	todo.Completed = status.Done
+	updates["completed"], updates["status"] = status.Done, status.Name
+	if moved {
+		updates["list_id"] = listID
+		existing.ListID = listID
+	}
+	return nil
+}
+
+// setTodoTags remplace les étiquettes du todo ; elles doivent appartenir au même utilisateur
+func setTodoTags(tx *gorm.DB, todo *models.TodoModel, tagIDs []uint) error {
+	var tags []models.TagModel
+	if len(tagIDs) > 0 {
+		if err := tx.Where("id IN ? AND user_id = ?", tagIDs, todo.UserID).Find(&tags).Error; err != nil {
+			return err
+		}
+		unique := map[uint]bool{}
+		for _, id := range tagIDs {
+			unique[id] = true
+		}
+		if len(tags) != len(unique) {
+			return ErrTagNotFound
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM todo_tags WHERE todo_id = ?", todo.ID).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := tx.Exec("INSERT INTO todo_tags (todo_id, tag_id) VALUES (?, ?)", todo.ID, tag.ID).Error; err != nil {
+			return err
+		}
+	}
+	todo.Tags = tags
+	return nil
+}
+
+// appendPosition place le todo en fin de liste de son utilisateur
+func appendPosition(tx *gorm.DB, todo *models.TodoModel) error {
+	var last string
+	err := tx.Model(&models.TodoModel{}).Where("user_id = ?", todo.UserID).
+		Select("COALESCE(MAX(position), '')").Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	todo.Position, err = position.After(last)
+	return err
+}
+
+// stampCompletion renseigne la date de fin d'un todo créé déjà terminé
+func stampCompletion(todo *models.TodoModel) {
+	if todo.Completed && todo.CompletedAt == nil {
+		now := time.Now()
+		todo.CompletedAt = &now
+	}
+	if !todo.Completed {
+		todo.CompletedAt = nil
+	}
+}
+
 // Obtient une citation aléatoire de l'API
 func (s *TodoServiceImp) GetQuote() (models.QuoteResponse, error) {
 	client := resty.New()
@@ -2,12 +2,15 @@ package services_test
 
 import (
 	"database/sql"
+	"strings"
 
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 
 	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/exchange"
 	"github.com/go-todo1/services"
 	"github.com/stretchr/testify/assert"
 
@@ -33,6 +36,8 @@ func initMockDB() (*gorm.DB, sqlmock.Sqlmock, *sql.DB, error) {
 	return gormDB, mock, sqlDB, nil
 }
 
+func boolPtr(b bool) *bool { return &b }
+
 func TestDeleteTodoService(t *testing.T) {
 	testCases := []struct {
 		name        string
@@ -49,7 +54,17 @@ func TestDeleteTodoService(t *testing.T) {
 					return nil, nil, nil, err
 				}
 				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT \\* FROM `todo_models` WHERE user_id = \\? AND `todo_models`.`id` = \\? ORDER BY `todo_models`.`id` LIMIT \\?$").WithArgs(uint(1), uint(1), 1).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 1))
+				mock.ExpectQuery("^SELECT \\* FROM `todo_models` WHERE parent_id IN \\(\\?\\)$").WithArgs(uint(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("^DELETE FROM todo_tags WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM comment_models WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM todo_activities WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM time_entries WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM custom_field_values WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM todo_dependencies WHERE todo_id = \\? OR depends_on_id = \\?$").WithArgs(uint(1), uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^UPDATE focus_sessions SET todo_id = NULL WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
 				mock.ExpectExec("^DELETE FROM `todo_models` WHERE `todo_models`.`id` = \\?$").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `outbox_events`").WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 				return gormDB, mock, sqlDB, nil
 			},
@@ -66,6 +81,15 @@ func TestDeleteTodoService(t *testing.T) {
 					return nil, nil, nil, err
 				}
 				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT \\* FROM `todo_models` WHERE user_id = \\? AND `todo_models`.`id` = \\? ORDER BY `todo_models`.`id` LIMIT \\?$").WithArgs(uint(1), uint(1), 1).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 1))
+				mock.ExpectQuery("^SELECT \\* FROM `todo_models` WHERE parent_id IN \\(\\?\\)$").WithArgs(uint(1)).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectExec("^DELETE FROM todo_tags WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM comment_models WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM todo_activities WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM time_entries WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM custom_field_values WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^DELETE FROM todo_dependencies WHERE todo_id = \\? OR depends_on_id = \\?$").WithArgs(uint(1), uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("^UPDATE focus_sessions SET todo_id = NULL WHERE todo_id = \\?$").WithArgs(uint(1)).WillReturnResult(sqlmock.NewResult(0, 0))
 				mock.ExpectExec("^DELETE FROM `todo_models` WHERE `todo_models`.`id` = \\?$").WithArgs(int64(1)).WillReturnError(gorm.ErrInvalidTransaction)
 				mock.ExpectRollback()
 				return gormDB, mock, sqlDB, nil
@@ -75,6 +99,23 @@ func TestDeleteTodoService(t *testing.T) {
 				assert.Equal(t, gorm.ErrInvalidTransaction.Error(), err.Error())
 			},
 		},
+		{
+			name: "not owned",
+			id:   1,
+			setup: func() (*gorm.DB, sqlmock.Sqlmock, *sql.DB, error) {
+				gormDB, mock, sqlDB, err := initMockDB()
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				mock.ExpectBegin()
+				mock.ExpectQuery("^SELECT \\* FROM `todo_models` WHERE user_id = \\? AND `todo_models`.`id` = \\?").WithArgs(uint(1), uint(1), 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+				return gormDB, mock, sqlDB, nil
+			},
+			checkResult: func(err error) {
+				assert.ErrorIs(t, err, services.ErrTodoNotFound)
+			},
+		},
 		{
 			name: "bad id",
 			id:   0,
@@ -98,7 +139,7 @@ func TestDeleteTodoService(t *testing.T) {
 				t.Fatalf("gormDB is nil")
 			}
 			service := services.NewTodoServiceImp(gormDB, "80dda35e2emshd2e339a97923cdcp1ee214jsn9effd6aab9d6") // Utilisez NewTodoServiceImp ici
-			err = service.Delete(tc.id)
+			err = service.Delete(1, tc.id)
 			tc.checkResult(err)
 			if mock != nil {
 				assert.NoError(t, mock.ExpectationsWereMet())
@@ -129,8 +170,17 @@ func TestCreateTodoService(t *testing.T) {
 					return nil, nil, nil, err
 				}
 				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT COALESCE\\(MAX\\(position\\), ''\\) FROM `todo_models` WHERE user_id = \\?").
+					WithArgs(0).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(""))
 				mock.ExpectExec("INSERT INTO `todo_models`").
-					WithArgs("Test Todo", false, sqlmock.AnyArg(), sqlmock.AnyArg()). // add arguments for timestamps
+					WithArgs(0, "", "Test Todo", "", false, "", nil, nil, "", "", "00000001i", nil, "", nil, nil, "todo", nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()). // add arguments for timestamps
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `todo_activities`").
+					WithArgs(uint(1), 0, "created", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO `outbox_events`").
+					WithArgs("todo.created", uint(1), sqlmock.AnyArg(), 0, "", sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				mock.ExpectCommit()
 				return gormDB, mock, sqlDB, nil
@@ -153,8 +203,11 @@ func TestCreateTodoService(t *testing.T) {
 					return nil, nil, nil, err
 				}
 				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT COALESCE\\(MAX\\(position\\), ''\\) FROM `todo_models` WHERE user_id = \\?").
+					WithArgs(0).
+					WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(""))
 				mock.ExpectExec("INSERT INTO `todo_models`").
-					WithArgs("Test Todo", false, sqlmock.AnyArg(), sqlmock.AnyArg()). // add arguments for timestamps
+					WithArgs(0, "", "Test Todo", "", false, "", nil, nil, "", "", "00000001i", nil, "", nil, nil, "todo", nil, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()). // add arguments for timestamps
 					WillReturnError(gorm.ErrInvalidTransaction)
 				mock.ExpectRollback()
 				return gormDB, mock, sqlDB, nil
@@ -238,3 +291,302 @@ func TestGetQuoteService(t *testing.T) {
 		})
 	}
 }
+
+func TestImportTodoService(t *testing.T) {
+	testCases := []struct {
+		name        string
+		dryRun      bool
+		checkResult func(report exchange.Report, count int64)
+	}{
+		{
+			name: "import",
+			checkResult: func(report exchange.Report, count int64) {
+				assert.Equal(t, 2, report.Imported)
+				assert.Equal(t, int64(3), count)
+				for _, todo := range report.Todos {
+					assert.Equal(t, uint(1), todo.UserID)
+				}
+			},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			checkResult: func(report exchange.Report, count int64) {
+				assert.Equal(t, 2, report.Imported)
+				assert.Equal(t, int64(1), count)
+			},
+		},
+	}
+
+	input := "title,completed\nLearn Go,false\nWrite tests,true\n write  TESTS ,false\n,false\nDeploy,false\n"
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := initSQLiteDB(t)
+			db.Create(&models.TodoModel{UserID: 1, Title: "learn go"})
+			db.Create(&models.TodoModel{UserID: 2, Title: "Deploy"}) // titre d'un autre utilisateur, importé quand même
+			service := services.NewTodoServiceImp(db, "")
+
+			report, err := service.Import(1, strings.NewReader(input), exchange.FormatCSV, tc.dryRun)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, report.Total)
+			assert.Equal(t, []exchange.RowError{
+				{Row: 1, Message: `duplicate title "Learn Go"`},
+				{Row: 3, Message: `duplicate title "write  TESTS"`},
+			}, report.Duplicates)
+			assert.Equal(t, []exchange.RowError{{Row: 4, Message: "title is required"}}, report.Errors)
+
+			var count int64
+			db.Model(&models.TodoModel{}).Where("user_id = ?", 1).Count(&count)
+			tc.checkResult(report, count)
+		})
+	}
+}
+
+func TestMoveTodoService(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTodoServiceImp(db, "")
+	var ids []uint
+	for _, title := range []string{"a", "b", "c", "d"} {
+		todo, err := service.Create(models.TodoModel{UserID: 1, Title: title})
+		assert.NoError(t, err)
+		ids = append(ids, todo.ID)
+	}
+	other, _ := service.Create(models.TodoModel{UserID: 2, Title: "other"})
+
+	order := func() string {
+		todos, err := service.List(services.TodoFilter{UserID: 1})
+		assert.NoError(t, err)
+		var b strings.Builder
+		for _, todo := range todos {
+			b.WriteString(todo.Title)
+		}
+		return b.String()
+	}
+	assert.Equal(t, "abcd", order())
+
+	_, err := service.Move(1, ids[3], ids[0], 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "dabc", order())
+
+	_, err = service.Move(1, ids[3], 0, ids[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "abdc", order())
+
+	_, err = service.Move(1, ids[0], 0, ids[2])
+	assert.NoError(t, err)
+	assert.Equal(t, "bdca", order())
+
+	// insertions répétées au même endroit sans renuméroter les autres todos
+	for i := 0; i < 50; i++ {
+		_, err = service.Move(1, ids[0], ids[2], 0)
+		assert.NoError(t, err)
+		_, err = service.Move(1, ids[0], 0, ids[3])
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "bdac", order())
+
+	_, err = service.Move(1, ids[0], 0, 0)
+	assert.ErrorIs(t, err, services.ErrInvalidAnchor)
+	_, err = service.Move(1, ids[0], ids[1], ids[2])
+	assert.ErrorIs(t, err, services.ErrInvalidAnchor)
+	_, err = service.Move(1, ids[0], other.ID, 0)
+	assert.ErrorIs(t, err, services.ErrInvalidAnchor)
+	listed := models.ListModel{UserID: 1, Name: "Elsewhere"}
+	db.Create(&listed)
+	elsewhere, _ := service.Create(models.TodoModel{UserID: 1, Title: "x", ListID: &listed.ID})
+	_, err = service.Move(1, ids[0], elsewhere.ID, 0)
+	assert.ErrorIs(t, err, services.ErrInvalidAnchor, "the anchor must be in the same list")
+	assert.NoError(t, service.Delete(1, elsewhere.ID))
+	_, err = service.Move(1, 999, ids[1], 0)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+
+	// les todos d'un autre utilisateur sont introuvables
+	_, err = service.Move(2, ids[0], ids[1], 0)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	_, err = service.Update(2, ids[0], services.TodoPatch{TodoModel: models.TodoModel{Title: "stolen"}})
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+	assert.ErrorIs(t, service.Delete(2, ids[0]), services.ErrTodoNotFound)
+	var exported strings.Builder
+	assert.NoError(t, service.Export(2, &exported, exchange.FormatTodoTxt))
+	assert.Equal(t, 1, strings.Count(exported.String(), "\n"))
+	assert.True(t, strings.HasSuffix(exported.String(), " other\n"), exported.String())
+
+	db.Create(&models.TodoModel{UserID: 1, Title: "e"})
+	assert.NoError(t, service.EnsurePositions())
+	assert.Equal(t, "bdace", order())
+
+	_, err = service.Update(1, ids[2], services.TodoPatch{TodoModel: models.TodoModel{Priority: "A"}})
+	assert.NoError(t, err)
+	todos, err := service.List(services.TodoFilter{UserID: 1, Sort: services.SortPriority})
+	assert.NoError(t, err)
+	assert.Equal(t, "c", todos[0].Title)
+}
+
+func TestSubtasksService(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTodoServiceImp(db, "")
+	service.MaxDepth = 3
+	parentOf := func(id uint) *uint { return &id }
+
+	root, err := service.Create(models.TodoModel{UserID: 1, Title: "Release"})
+	assert.NoError(t, err)
+	a, err := service.Create(models.TodoModel{UserID: 1, Title: "Changelog", ParentID: parentOf(root.ID)})
+	assert.NoError(t, err)
+	b, err := service.Create(models.TodoModel{UserID: 1, Title: "Build", ParentID: parentOf(root.ID)})
+	assert.NoError(t, err)
+	b1, err := service.Create(models.TodoModel{UserID: 1, Title: "Linux", ParentID: parentOf(b.ID)})
+	assert.NoError(t, err)
+	_, err = service.Create(models.TodoModel{UserID: 1, Title: "Windows", ParentID: parentOf(b.ID), Completed: true})
+	assert.NoError(t, err)
+
+	_, err = service.Create(models.TodoModel{UserID: 1, Title: "Too deep", ParentID: parentOf(b1.ID)})
+	assert.ErrorIs(t, err, services.ErrMaxDepth)
+	_, err = service.Create(models.TodoModel{UserID: 2, Title: "Foreign", ParentID: parentOf(root.ID)})
+	assert.ErrorIs(t, err, services.ErrInvalidParent)
+	_, err = service.Update(1, root.ID, services.TodoPatch{TodoModel: models.TodoModel{ParentID: parentOf(b1.ID)}})
+	assert.ErrorIs(t, err, services.ErrInvalidParent, "cycle")
+	_, err = service.Update(1, b.ID, services.TodoPatch{TodoModel: models.TodoModel{ParentID: parentOf(a.ID)}})
+	assert.ErrorIs(t, err, services.ErrMaxDepth, "subtree would exceed the depth limit")
+
+	tree, err := service.List(services.TodoFilter{UserID: 1, Tree: true})
+	assert.NoError(t, err)
+	assert.Len(t, tree, 1)
+	assert.Equal(t, "Release", tree[0].Title)
+	assert.Equal(t, 25, tree[0].Progress) // (0 + 50) / 2
+	assert.Equal(t, []string{"Changelog", "Build"}, titles(tree[0].Children))
+	assert.Equal(t, 50, tree[0].Children[1].Progress)
+	assert.Len(t, tree[0].Children[1].Children, 2)
+
+	// l'avancement tient compte des sous-tâches exclues par le filtre
+	open, err := service.List(services.TodoFilter{UserID: 1, Status: services.DefaultWorkflow.Initial().Name})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Release", "Changelog", "Build", "Linux"}, titles(open))
+	assert.Equal(t, 25, open[0].Progress)
+	assert.Equal(t, 50, open[2].Progress)
+
+	_, err = service.Update(1, root.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrIncompleteChildren)
+	done, err := service.Update(1, root.ID, services.TodoPatch{TodoModel: models.TodoModel{CompleteChildren: true}, Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	assert.True(t, done.Completed)
+	flat, err := service.List(services.TodoFilter{UserID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, flat, 5)
+	for _, todo := range flat {
+		assert.True(t, todo.Completed, todo.Title)
+		assert.Equal(t, 100, todo.Progress)
+	}
+
+	detached, err := service.Update(1, b.ID, services.TodoPatch{TodoModel: models.TodoModel{ParentID: parentOf(0)}})
+	assert.NoError(t, err)
+	assert.True(t, detached.Completed, "completed is left unchanged when omitted")
+	tree, _ = service.List(services.TodoFilter{UserID: 1, Tree: true})
+	assert.Len(t, tree, 2)
+
+	assert.NoError(t, service.Delete(1, b.ID))
+	flat, _ = service.List(services.TodoFilter{UserID: 1})
+	assert.Equal(t, []string{"Release", "Changelog"}, titles(flat))
+}
+
+func TestRecurringTodoService(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTodoServiceImp(db, "")
+	tags := services.NewTagServiceImp(db)
+	tag, _ := tags.Create(models.TagModel{UserID: 1, Name: "ops"})
+
+	_, err := service.Create(models.TodoModel{UserID: 1, Title: "Bad", Recurrence: "FREQ=HOURLY"})
+	assert.ErrorIs(t, err, services.ErrInvalidRecurrence)
+
+	due := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	report, err := service.Create(models.TodoModel{
+		UserID: 1, Title: "Weekly report", DueAt: &due,
+		Recurrence: "freq=weekly;count=2", TagIDs: []uint{tag.ID},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=2", report.Recurrence)
+
+	done, err := service.Update(1, report.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	if assert.NotNil(t, done.Next) {
+		next := done.Next
+		assert.Equal(t, "Weekly report", next.Title)
+		assert.False(t, next.Completed)
+		assert.True(t, due.AddDate(0, 0, 7).Equal(*next.DueAt))
+		assert.Equal(t, "FREQ=WEEKLY;COUNT=1", next.Recurrence)
+		assert.Equal(t, report.ID, *next.SeriesID)
+		assert.Equal(t, report.ID, *done.SeriesID)
+		assert.Len(t, next.Tags, 1)
+
+		// dernière occurrence de la série : rien n'est créé
+		last, err := service.Update(1, next.ID, services.TodoPatch{Completed: boolPtr(true)})
+		assert.NoError(t, err)
+		assert.Nil(t, last.Next)
+	}
+
+	// une série très en retard reprend à la prochaine date future
+	late := time.Now().AddDate(0, 0, -10)
+	standup, _ := service.Create(models.TodoModel{UserID: 1, Title: "Standup prep", DueAt: &late, Recurrence: "FREQ=DAILY"})
+	done, err = service.Update(1, standup.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	if assert.NotNil(t, done.Next) {
+		assert.True(t, done.Next.DueAt.After(time.Now()))
+		assert.True(t, done.Next.DueAt.Before(time.Now().Add(24*time.Hour)))
+	}
+
+	var count int64
+	db.Model(&models.TodoModel{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestDependenciesService(t *testing.T) {
+	db := initSQLiteDB(t)
+	service := services.NewTodoServiceImp(db, "")
+
+	design, _ := service.Create(models.TodoModel{UserID: 1, Title: "Design"})
+	build, err := service.Create(models.TodoModel{UserID: 1, Title: "Build", DependsOn: []uint{design.ID}})
+	assert.NoError(t, err)
+	assert.True(t, build.Blocked)
+	ship, err := service.Create(models.TodoModel{UserID: 1, Title: "Ship", DependsOn: []uint{build.ID, design.ID, build.ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{design.ID, build.ID}, ship.DependsOn)
+	other, _ := service.Create(models.TodoModel{UserID: 2, Title: "Other"})
+
+	_, err = service.Update(1, design.ID, services.TodoPatch{TodoModel: models.TodoModel{DependsOn: []uint{ship.ID}}})
+	assert.ErrorIs(t, err, services.ErrDependencyCycle)
+	_, err = service.Update(1, design.ID, services.TodoPatch{TodoModel: models.TodoModel{DependsOn: []uint{design.ID}}})
+	assert.ErrorIs(t, err, services.ErrDependencyCycle)
+	_, err = service.Update(1, design.ID, services.TodoPatch{TodoModel: models.TodoModel{DependsOn: []uint{other.ID}}})
+	assert.ErrorIs(t, err, services.ErrInvalidDependency)
+
+	_, err = service.Update(1, build.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrOpenDependencies)
+
+	graph, err := service.Graph(1, build.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, build.ID, graph.Root)
+	assert.Len(t, graph.Nodes, 3)
+	assert.Equal(t, []services.GraphEdge{
+		{From: build.ID, To: design.ID},
+		{From: ship.ID, To: design.ID},
+		{From: ship.ID, To: build.ID},
+	}, graph.Edges)
+	assert.False(t, graph.Nodes[0].Blocked)
+	assert.True(t, graph.Nodes[1].Blocked)
+
+	_, err = service.Update(1, design.ID, services.TodoPatch{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	todos, _ := service.List(services.TodoFilter{UserID: 1})
+	assert.False(t, todos[1].Blocked, "Build no longer blocked")
+	assert.True(t, todos[2].Blocked, "Ship still waits for Build")
+
+	forced, err := service.Update(1, ship.ID, services.TodoPatch{TodoModel: models.TodoModel{IgnoreDependencies: true}, Completed: boolPtr(true)})
+	assert.NoError(t, err)
+	assert.True(t, forced.Completed)
+	assert.True(t, forced.Blocked)
+
+	assert.NoError(t, service.Delete(1, build.ID))
+	todos, _ = service.List(services.TodoFilter{UserID: 1})
+	assert.Equal(t, []uint{design.ID}, todos[1].DependsOn)
+	_, err = service.Graph(1, build.ID)
+	assert.ErrorIs(t, err, services.ErrTodoNotFound)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+// DefaultMaxDepth est le nombre de niveaux autorisés, la racine comprise
+const DefaultMaxDepth = 5
+
+// progressBatch borne le nombre d'identifiants par requête IN du calcul d'avancement
+const progressBatch = 500
+
+var (
+	ErrInvalidParent      = errors.New("parent must be another todo of the same user, outside of its subtasks")
+	ErrMaxDepth           = errors.New("subtasks are nested too deeply")
+	ErrIncompleteChildren = errors.New("todo has incomplete subtasks")
+)
+
+func (s *TodoServiceImp) maxDepth() int {
+	if s.MaxDepth > 0 {
+		return s.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// checkParent vérifie que todo peut être rattaché à parentID sans cycle ni dépasser la profondeur maximale
+func (s *TodoServiceImp) checkParent(tx *gorm.DB, todo models.TodoModel, parentID uint) error {
+	if parentID == todo.ID {
+		return ErrInvalidParent
+	}
+	var parent models.TodoModel
+	if err := tx.Where("user_id = ?", todo.UserID).First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidParent
+		}
+		return err
+	}
+
+	depth := 1
+	for cur := parent; cur.ParentID != nil; depth++ {
+		if *cur.ParentID == todo.ID || depth > s.maxDepth() {
+			return ErrInvalidParent
+		}
+		id := *cur.ParentID
+		cur = models.TodoModel{}
+		if err := tx.First(&cur, id).Error; err != nil {
+			return err
+		}
+	}
+
+	height := 1
+	if todo.ID != 0 {
+		levels, err := descendantLevels(tx, todo.ID)
+		if err != nil {
+			return err
+		}
+		height += len(levels)
+	}
+	if depth+height > s.maxDepth() {
+		return ErrMaxDepth
+	}
+	return nil
+}
+
+// descendantLevels retourne les sous-tâches de id niveau par niveau
+func descendantLevels(tx *gorm.DB, id uint) ([][]models.TodoModel, error) {
+	var levels [][]models.TodoModel
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	for len(ids) > 0 {
+		var children []models.TodoModel
+		if err := tx.Where("parent_id IN ?", ids).Find(&children).Error; err != nil {
+			return nil, err
+		}
+		ids = nil
+		var level []models.TodoModel
+		for _, child := range children {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				level = append(level, child)
+				ids = append(ids, child.ID)
+			}
+		}
+		if len(level) > 0 {
+			levels = append(levels, level)
+		}
+	}
+	return levels, nil
+}
+
+func descendants(tx *gorm.DB, id uint) ([]models.TodoModel, error) {
+	levels, err := descendantLevels(tx, id)
+	var all []models.TodoModel
+	for _, level := range levels {
+		all = append(all, level...)
+	}
+	return all, err
+}
+
+// withProgress renseigne Progress : 0 ou 100 pour une tâche sans sous-tâche, sinon la
+// moyenne de l'avancement de ses sous-tâches ; un parent terminé est à 100. Seuls les
+// descendants des todos ouverts sont chargés, niveau par niveau.
+func withProgress(tx *gorm.DB, todos []models.TodoModel) error {
+	type node struct {
+		ID        uint
+		ParentID  *uint
+		Completed bool
+	}
+	completed := make(map[uint]bool, len(todos))
+	children := map[uint][]uint{}
+	var open []uint
+	for _, todo := range todos {
+		completed[todo.ID] = todo.Completed
+		if !todo.Completed {
+			open = append(open, todo.ID)
+		}
+	}
+	seen := map[uint]bool{}
+	for depth := 0; len(open) > 0 && depth < DefaultMaxDepth*4; depth++ {
+		var next []uint
+		for start := 0; start < len(open); start += progressBatch {
+			var nodes []node
+			err := tx.Model(&models.TodoModel{}).Select("id, parent_id, completed").
+				Where("parent_id IN ?", open[start:min(start+progressBatch, len(open))]).Scan(&nodes).Error
+			if err != nil {
+				return err
+			}
+			for _, n := range nodes {
+				if seen[n.ID] {
+					continue
+				}
+				seen[n.ID] = true
+				completed[n.ID] = n.Completed
+				children[*n.ParentID] = append(children[*n.ParentID], n.ID)
+				if !n.Completed {
+					next = append(next, n.ID)
+				}
+			}
+		}
+		open = next
+	}
+	memo := map[uint]float64{}
+	var progress func(id uint, depth int) float64
+	progress = func(id uint, depth int) float64 {
+		if p, ok := memo[id]; ok {
+			return p
+		}
+		p := 0.0
+		switch {
+		case completed[id]:
+			p = 100
+		case len(children[id]) > 0 && depth < DefaultMaxDepth*4:
+			for _, child := range children[id] {
+				p += progress(child, depth+1)
+			}
+			p /= float64(len(children[id]))
+		}
+		memo[id] = p
+		return p
+	}
+	for i := range todos {
+		todos[i].Progress = int(progress(todos[i].ID, 0))
+	}
+	return nil
+}
+
+// BuildTree imbrique les todos sous leur parent en conservant l'ordre de la liste ;
+// un todo dont le parent n'est pas dans la liste reste à la racine.
+func BuildTree(todos []models.TodoModel) []models.TodoModel {
+	index := make(map[uint]int, len(todos))
+	for i, todo := range todos {
+		index[todo.ID] = i
+	}
+	children := map[uint][]int{}
+	var roots []int
+	for i, todo := range todos {
+		if todo.ParentID != nil {
+			if _, ok := index[*todo.ParentID]; ok && *todo.ParentID != todo.ID {
+				children[*todo.ParentID] = append(children[*todo.ParentID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	var build func(i int, seen map[uint]bool) models.TodoModel
+	build = func(i int, seen map[uint]bool) models.TodoModel {
+		todo := todos[i]
+		seen[todo.ID] = true
+		for _, c := range children[todo.ID] {
+			if !seen[todos[c].ID] {
+				todo.Children = append(todo.Children, build(c, seen))
+			}
+		}
+		return todo
+	}
+	tree := make([]models.TodoModel, 0, len(roots))
+	seen := map[uint]bool{}
+	for _, i := range roots {
+		tree = append(tree, build(i, seen))
+	}
+	return tree
+}
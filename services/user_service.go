@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	handleRe        = regexp.MustCompile(`^[a-z0-9_][a-z0-9_.-]{0,62}[a-z0-9_]$|^[a-z0-9_]$`)
+)
+
+type UserService interface {
+	Create(user models.UserModel) (models.UserModel, error)
+	Get(id uint) (models.UserModel, error)
+	GetByFeedToken(token string) (models.UserModel, error)
+	RegenerateFeedToken(id uint) (models.UserModel, error)
+	GetByMailToken(token string) (models.UserModel, error)
+	RegenerateMailToken(id uint) (models.UserModel, error)
+}
+
+func NewUserServiceImp(db *gorm.DB) *UserServiceImp {
+	return &UserServiceImp{Db: db}
+}
+
+type UserServiceImp struct {
+	Db *gorm.DB
+}
+
+func (s *UserServiceImp) Create(user models.UserModel) (models.UserModel, error) {
+	if user.ID != 0 {
+		return models.UserModel{}, errors.New("invalid ID")
+	}
+	user.Name = strings.TrimSpace(user.Name)
+	if user.Name == "" {
+		return models.UserModel{}, errors.New("the name is required")
+	}
+	addr, err := mail.ParseAddress(user.Email)
+	if err != nil {
+		return models.UserModel{}, errors.New("invalid email")
+	}
+	user.Email = strings.ToLower(addr.Address)
+	if user.Handle, err = s.uniqueHandle(user.Handle, user.Email); err != nil {
+		return models.UserModel{}, err
+	}
+
+	if user.FeedToken, err = randomHex(32); err != nil {
+		return models.UserModel{}, err
+	}
+	if user.MailToken, err = randomHex(mailTokenBytes); err != nil {
+		return models.UserModel{}, err
+	}
+	if err := s.Db.Create(&user).Error; err != nil {
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+func (s *UserServiceImp) Get(id uint) (models.UserModel, error) {
+	var user models.UserModel
+	if err := s.Db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.UserModel{}, ErrUserNotFound
+		}
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+func (s *UserServiceImp) GetByFeedToken(token string) (models.UserModel, error) {
+	var user models.UserModel
+	if token == "" {
+		return user, ErrUserNotFound
+	}
+	if err := s.Db.Where("feed_token = ?", token).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.UserModel{}, ErrUserNotFound
+		}
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+// RegenerateFeedToken invalide les URL de flux déjà partagées
+func (s *UserServiceImp) RegenerateFeedToken(id uint) (models.UserModel, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return models.UserModel{}, err
+	}
+	if user.FeedToken, err = randomHex(32); err != nil {
+		return models.UserModel{}, err
+	}
+	if err := s.Db.Model(&user).Update("feed_token", user.FeedToken).Error; err != nil {
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+func (s *UserServiceImp) GetByMailToken(token string) (models.UserModel, error) {
+	var user models.UserModel
+	if token == "" {
+		return user, ErrUserNotFound
+	}
+	if err := s.Db.Where("mail_token = ?", token).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.UserModel{}, ErrUserNotFound
+		}
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+// RegenerateMailToken invalide l'adresse de réception déjà communiquée
+func (s *UserServiceImp) RegenerateMailToken(id uint) (models.UserModel, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return models.UserModel{}, err
+	}
+	if user.MailToken, err = randomHex(mailTokenBytes); err != nil {
+		return models.UserModel{}, err
+	}
+	if err := s.Db.Model(&user).Update("mail_token", user.MailToken).Error; err != nil {
+		return models.UserModel{}, err
+	}
+	return user, nil
+}
+
+// uniqueHandle valide l'identifiant demandé, ou le déduit de l'email en ajoutant
+// un suffixe numérique s'il est déjà pris
+func (s *UserServiceImp) uniqueHandle(handle, email string) (string, error) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	if handle != "" {
+		if !handleRe.MatchString(handle) {
+			return "", errors.New("invalid handle, expected letters, digits, '.', '-' or '_'")
+		}
+		var taken int64
+		if err := s.Db.Model(&models.UserModel{}).Where("handle = ?", handle).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken > 0 {
+			return "", errors.New("handle already taken")
+		}
+		return handle, nil
+	}
+
+	base := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, email[:strings.IndexByte(email, '@')])
+	base = strings.Trim(base, ".-")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 56 {
+		base = base[:56]
+	}
+	for n := 1; ; n++ {
+		candidate := base
+		if n > 1 {
+			candidate += strconv.Itoa(n)
+		}
+		var taken int64
+		if err := s.Db.Model(&models.UserModel{}).Where("handle = ?", candidate).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return candidate, nil
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"gorm.io/gorm"
+)
+
+const (
+	// Nombre maximal de tentatives avant d'abandonner une livraison
+	WebhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+	webhookBatchSize   = 50
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookService interface {
+	CreateSubscription(sub models.WebhookSubscription) (models.WebhookSubscription, error)
+	ListSubscriptions(userID uint) ([]models.WebhookSubscription, error)
+	DeleteSubscription(userID, id uint) error
+	ListDeliveries(userID, subscriptionID uint) ([]models.WebhookDelivery, error)
+	Enqueue(event events.Event) error
+	DeliverPending() (int, error)
+}
+
+func NewWebhookServiceImp(db *gorm.DB) *WebhookServiceImp {
+	return &WebhookServiceImp{Db: db, Client: resty.New().SetTimeout(10 * time.Second)}
+}
+
+type WebhookServiceImp struct {
+	Db     *gorm.DB
+	Client *resty.Client
+}
+
+type webhookPayload struct {
+	EventID    uint             `json:"event_id"`
+	Event      string           `json:"event"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Todo       models.TodoModel `json:"todo"`
+}
+
+var webhookEvents = map[string]bool{
+	models.EventTodoCreated:   true,
+	models.EventTodoCompleted: true,
+	models.EventTodoReopened:  true,
+	models.EventTodoDeleted:   true,
+}
+
+func (s *WebhookServiceImp) CreateSubscription(sub models.WebhookSubscription) (models.WebhookSubscription, error) {
+	if sub.ID != 0 {
+		return models.WebhookSubscription{}, errors.New("invalid ID")
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.WebhookSubscription{}, errors.New("invalid URL")
+	}
+
+	names := splitList(sub.Events)
+	for _, e := range names {
+		if !webhookEvents[e] {
+			return models.WebhookSubscription{}, fmt.Errorf("unknown event %q", e)
+		}
+	}
+	sub.Events = strings.Join(names, ",")
+
+	if sub.Secret == "" {
+		secret, err := randomHex(32)
+		if err != nil {
+			return models.WebhookSubscription{}, err
+		}
+		sub.Secret = secret
+	}
+	sub.Active = true
+
+	if err := s.Db.Create(&sub).Error; err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookServiceImp) ListSubscriptions(userID uint) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := s.Db.Where("user_id = ?", userID).Order("id").Find(&subs).Error
+	return subs, err
+}
+
+func (s *WebhookServiceImp) DeleteSubscription(userID, id uint) error {
+	return s.Db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("user_id = ?", userID).Delete(&models.WebhookSubscription{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+func (s *WebhookServiceImp) ListDeliveries(userID, subscriptionID uint) ([]models.WebhookDelivery, error) {
+	var owned int64
+	if err := s.Db.Model(&models.WebhookSubscription{}).Where("id = ? AND user_id = ?", subscriptionID, userID).Count(&owned).Error; err != nil {
+		return nil, err
+	}
+	if owned == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	var deliveries []models.WebhookDelivery
+	err := s.Db.Where("subscription_id = ?", subscriptionID).Order("id desc").Limit(100).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Enqueue ajoute une livraison en attente pour chaque abonnement du propriétaire du todo
+// intéressé par l'événement.
+// Il est abonné au bus d'événements : un même événement peut être reçu plusieurs fois,
+// les destinataires peuvent dédupliquer grâce à event_id.
+func (s *WebhookServiceImp) Enqueue(evt events.Event) error {
+	var subs []models.WebhookSubscription
+	if err := s.Db.Where("active = ? AND user_id = ?", true, evt.Todo.UserID).Find(&subs).Error; err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(webhookPayload{EventID: evt.ID, Event: evt.Type, OccurredAt: evt.OccurredAt.UTC(), Todo: evt.Todo})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, sub := range subs {
+		if !subscribedTo(sub, evt.Type) {
+			continue
+		}
+		var existing int64
+		if err := s.Db.Model(&models.WebhookDelivery{}).
+			Where("subscription_id = ? AND event_id = ?", sub.ID, evt.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			continue // événement déjà reçu lors d'une distribution précédente
+		}
+		delivery := models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        evt.ID,
+			Event:          evt.Type,
+			Payload:        string(body),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+		}
+		if err := s.Db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverPending envoie les livraisons arrivées à échéance et retourne le nombre traité
+func (s *WebhookServiceImp) DeliverPending() (int, error) {
+	var deliveries []models.WebhookDelivery
+	err := s.Db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(webhookBatchSize).Find(&deliveries).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		var sub models.WebhookSubscription
+		if err := s.Db.First(&sub, deliveries[i].SubscriptionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.Db.Delete(&deliveries[i])
+				continue
+			}
+			return i, err
+		}
+		s.attempt(sub, &deliveries[i])
+		if err := s.Db.Save(&deliveries[i]).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Run traite la file d'attente à intervalle régulier jusqu'à l'annulation du contexte
+func (s *WebhookServiceImp) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverPending(); err != nil {
+				log.Printf("Error delivering webhooks: %v", err)
+			}
+		}
+	}
+}
+
+func (s *WebhookServiceImp) attempt(sub models.WebhookSubscription, d *models.WebhookDelivery) {
+	d.Attempts++
+	resp, err := s.Client.R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-Todo-Event", d.Event).
+		SetHeader("X-Todo-Delivery", strconv.FormatUint(uint64(d.ID), 10)).
+		SetHeader("X-Todo-Signature", "sha256="+SignPayload(sub.Secret, []byte(d.Payload))).
+		SetBody(d.Payload).
+		Post(sub.URL)
+
+	if err == nil {
+		d.ResponseCode = resp.StatusCode()
+		if resp.StatusCode() >= 200 && resp.StatusCode() < 300 {
+			now := time.Now()
+			d.Status = models.DeliverySucceeded
+			d.DeliveredAt = &now
+			d.LastError = ""
+			return
+		}
+		err = fmt.Errorf("unexpected response code %d", resp.StatusCode())
+	}
+
+	d.LastError = err.Error()
+	if d.Attempts >= WebhookMaxAttempts {
+		d.Status = models.DeliveryFailed
+		return
+	}
+	d.NextAttemptAt = time.Now().Add(WebhookBackoff(d.Attempts))
+}
+
+// SignPayload calcule la signature HMAC-SHA256 hexadécimale du corps envoyé
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff retourne le délai avant la prochaine tentative (croissance exponentielle plafonnée)
+func WebhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return d
+}
+
+func subscribedTo(sub models.WebhookSubscription, event string) bool {
+	names := splitList(sub.Events)
+	if len(names) == 0 {
+		return true
+	}
+	for _, e := range names {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/events"
+	"github.com/go-todo1/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func initSQLiteDB(t *testing.T) *gorm.DB {
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open sqlite database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.TodoModel{}, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.TagModel{}, &models.TodoDependency{}, &models.ListModel{}, &models.WorkflowStatus{}, &models.UserModel{}, &models.CommentModel{}, &models.TodoActivity{}, &models.NotificationModel{}, &models.AttachmentModel{}, &models.TimeEntry{}, &models.FocusSession{}, &models.CustomField{}, &models.CustomFieldValue{}, &models.TemplateModel{}, &models.NotificationPreference{}, &models.ChatIntegration{}, &models.ChatDelivery{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, services.WebhookBackoff(1))
+	assert.Equal(t, 60*time.Second, services.WebhookBackoff(2))
+	assert.Equal(t, 4*time.Minute, services.WebhookBackoff(4))
+	assert.Equal(t, 6*time.Hour, services.WebhookBackoff(20))
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	testCases := []struct {
+		name        string
+		status      int
+		checkResult func(d models.WebhookDelivery)
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			checkResult: func(d models.WebhookDelivery) {
+				assert.Equal(t, models.DeliverySucceeded, d.Status)
+				assert.Equal(t, 1, d.Attempts)
+				assert.NotNil(t, d.DeliveredAt)
+			},
+		},
+		{
+			name:   "retry on server error",
+			status: http.StatusInternalServerError,
+			checkResult: func(d models.WebhookDelivery) {
+				assert.Equal(t, models.DeliveryPending, d.Status)
+				assert.Equal(t, 1, d.Attempts)
+				assert.Equal(t, http.StatusInternalServerError, d.ResponseCode)
+				assert.True(t, d.NextAttemptAt.After(time.Now()))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotSignature, gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+				gotSignature = r.Header.Get("X-Todo-Signature")
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			db := initSQLiteDB(t)
+			service := services.NewWebhookServiceImp(db)
+			sub, err := service.CreateSubscription(models.WebhookSubscription{
+				UserID: 1,
+				URL:    server.URL,
+				Secret: "s3cret",
+				Events: models.EventTodoCreated,
+			})
+			assert.NoError(t, err)
+
+			dispatcher := events.NewDispatcher(db)
+			dispatcher.Subscribe("webhooks", service.Enqueue)
+
+			todoService := services.NewTodoServiceImp(db, "")
+			_, err = todoService.Create(models.TodoModel{UserID: 1, Title: "Write report"})
+			assert.NoError(t, err)
+			assert.NoError(t, todoService.Delete(1, 1)) // not subscribed to todo.deleted
+			_, err = todoService.Create(models.TodoModel{UserID: 2, Title: "Someone else's"})
+			assert.NoError(t, err)
+
+			_, err = dispatcher.DispatchPending()
+			assert.NoError(t, err)
+
+			n, err := service.DeliverPending()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, n)
+			assert.Equal(t, "sha256="+services.SignPayload("s3cret", []byte(gotBody)), gotSignature)
+			assert.Contains(t, gotBody, `"event":"todo.created"`)
+			assert.Contains(t, gotBody, `"title":"Write report"`, "only the subscriber's todos")
+
+			deliveries, err := service.ListDeliveries(1, sub.ID)
+			assert.NoError(t, err)
+			assert.Len(t, deliveries, 1)
+			tc.checkResult(deliveries[0])
+			_, err = service.ListDeliveries(2, sub.ID)
+			assert.ErrorIs(t, err, services.ErrWebhookNotFound)
+		})
+	}
+}
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	service := services.NewWebhookServiceImp(initSQLiteDB(t))
+
+	_, err := service.CreateSubscription(models.WebhookSubscription{URL: "ftp://example.com"})
+	assert.EqualError(t, err, "invalid URL")
+
+	_, err = service.CreateSubscription(models.WebhookSubscription{URL: "https://example.com", Events: "todo.renamed"})
+	assert.EqualError(t, err, `unknown event "todo.renamed"`)
+
+	sub, err := service.CreateSubscription(models.WebhookSubscription{UserID: 1, URL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Len(t, sub.Secret, 64)
+
+	subs, err := service.ListSubscriptions(2)
+	assert.NoError(t, err)
+	assert.Empty(t, subs)
+	assert.ErrorIs(t, service.DeleteSubscription(2, sub.ID), services.ErrWebhookNotFound)
+	assert.NoError(t, service.DeleteSubscription(1, sub.ID))
+	assert.ErrorIs(t, service.DeleteSubscription(1, sub.ID), services.ErrWebhookNotFound)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	models "github.com/go-todo1/Models"
+	"gorm.io/gorm"
+)
+
+const maxWorkflowStatuses = 20
+
+var (
+	ErrListNotFound      = errors.New("list not found")
+	ErrInvalidStatus     = errors.New("status is not part of the list workflow")
+	ErrInvalidTransition = errors.New("transition not allowed by the list workflow")
+	ErrInvalidWorkflow   = errors.New("invalid workflow")
+	ErrStatusInUse       = errors.New("a removed status is still used by todos")
+
+	statusNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+// Workflow est la liste ordonnée des colonnes d'une liste
+type Workflow []models.WorkflowStatus
+
+// DefaultWorkflow s'applique aux todos sans liste et aux nouvelles listes ; toutes les transitions sont permises
+var DefaultWorkflow = Workflow{
+	{Name: "todo", Label: "To do", Position: 0},
+	{Name: "in_progress", Label: "In progress", Position: 1},
+	{Name: "in_review", Label: "In review", Position: 2},
+	{Name: "done", Label: "Done", Position: 3, Done: true},
+}
+
+func (w Workflow) Status(name string) (models.WorkflowStatus, bool) {
+	for _, status := range w {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return models.WorkflowStatus{}, false
+}
+
+// Initial est la première colonne non terminée, celle des nouveaux todos
+func (w Workflow) Initial() models.WorkflowStatus {
+	for _, status := range w {
+		if !status.Done {
+			return status
+		}
+	}
+	return w[0]
+}
+
+// Final est la première colonne terminée, utilisée quand un todo est coché sans statut
+func (w Workflow) Final() models.WorkflowStatus {
+	for _, status := range w {
+		if status.Done {
+			return status
+		}
+	}
+	return w[len(w)-1]
+}
+
+// Allows indique si un todo peut passer de from à to ; un statut inconnu peut toujours en sortir
+func (w Workflow) Allows(from, to string) bool {
+	current, ok := w.Status(from)
+	if !ok || from == to || strings.TrimSpace(current.Transitions) == "" {
+		return true
+	}
+	for _, name := range strings.Fields(current.Transitions) {
+		if name == to {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve retourne la colonne nommée, ou celle qui correspond à completed si name est vide
+func (w Workflow) resolve(name string, completed bool) (models.WorkflowStatus, error) {
+	if name == "" {
+		if completed {
+			return w.Final(), nil
+		}
+		return w.Initial(), nil
+	}
+	status, ok := w.Status(name)
+	if !ok {
+		return models.WorkflowStatus{}, fmt.Errorf("%w: %q", ErrInvalidStatus, name)
+	}
+	return status, nil
+}
+
+// workflowFor charge le flux de la liste, qui doit appartenir à l'utilisateur ; DefaultWorkflow sans liste
+func workflowFor(tx *gorm.DB, userID uint, listID *uint) (Workflow, error) {
+	if listID == nil {
+		return DefaultWorkflow, nil
+	}
+	var list models.ListModel
+	err := tx.Preload("Statuses", orderedStatuses).Where("user_id = ?", userID).First(&list, *listID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrListNotFound
+		}
+		return nil, err
+	}
+	if len(list.Statuses) == 0 {
+		return DefaultWorkflow, nil
+	}
+	return Workflow(list.Statuses), nil
+}
+
+// normalizeWorkflow vérifie les colonnes envoyées et renumérote leurs positions
+func normalizeWorkflow(statuses []models.WorkflowStatus) (Workflow, error) {
+	if len(statuses) == 0 || len(statuses) > maxWorkflowStatuses {
+		return nil, fmt.Errorf("%w: between 1 and %d statuses expected", ErrInvalidWorkflow, maxWorkflowStatuses)
+	}
+	names := map[string]bool{}
+	var done, open bool
+	out := make(Workflow, len(statuses))
+	for i, status := range statuses {
+		status.Name = strings.TrimSpace(status.Name)
+		if !statusNameRe.MatchString(status.Name) {
+			return nil, fmt.Errorf("%w: invalid status name %q", ErrInvalidWorkflow, status.Name)
+		}
+		if names[status.Name] {
+			return nil, fmt.Errorf("%w: duplicate status %q", ErrInvalidWorkflow, status.Name)
+		}
+		names[status.Name] = true
+		status.Label = strings.TrimSpace(status.Label)
+		if status.Label == "" {
+			status.Label = status.Name
+		}
+		status.ID, status.ListID, status.Position = 0, 0, i
+		done, open = done || status.Done, open || !status.Done
+		out[i] = status
+	}
+	if !done || !open {
+		return nil, fmt.Errorf("%w: at least one done and one open status expected", ErrInvalidWorkflow)
+	}
+	for i, status := range out {
+		targets := strings.Fields(status.Transitions)
+		for _, name := range targets {
+			if !names[name] {
+				return nil, fmt.Errorf("%w: unknown transition %s -> %s", ErrInvalidWorkflow, status.Name, name)
+			}
+		}
+		out[i].Transitions = strings.Join(targets, " ")
+	}
+	return out, nil
+}
@@ -64,18 +64,217 @@
         color: #FFF;
         font-weight: bold;
       }
+      .search-results li{
+        background: #f8f9fa;
+        color: #333;
+      }
+      .search-results mark{
+        padding: 0;
+        background: #f9e79f;
+      }
+      .dragging{
+        opacity: 0.4;
+      }
+      .drop-target{
+        border-top: 2px solid #f9e79f !important;
+      }
+      .view-switch{
+        padding: 5px 10px;
+        background: #f8f9fa;
+      }
+      .board{
+        display: flex;
+        overflow-x: auto;
+        background: #f8f9fa;
+      }
+      .board-column{
+        flex: 1 0 200px;
+        margin: 5px;
+        padding: 5px;
+        background: #e9ecef;
+        min-height: 200px;
+      }
+      .board-column h6{
+        padding: 5px;
+        font-weight: bold;
+      }
+      .board-card{
+        margin-bottom: 5px;
+        padding: 8px;
+        cursor: move;
+      }
+      .mention{
+        color: #2980b9;
+        font-weight: bold;
+      }
+      .activity{
+        padding: 10px;
+        background: #f8f9fa;
+        font-size: 14px;
+      }
+      .activity-entry{
+        border-bottom: 1px solid #dee2e6;
+        padding: 5px 0;
+      }
+      .activity-entry p{
+        margin-bottom: 0;
+      }
+      .activity-meta{
+        font-size: 12px;
+        color: #666;
+      }
+      .attachments{
+        padding: 5px 0;
+        border-bottom: 1px solid #dee2e6;
+      }
+      .quick-add-preview{
+        padding: 5px 10px;
+        background: #f8f9fa;
+      }
+      .search-snippet{
+        font-size: 13px;
+        color: #666;
+      }
+      .dashboard{
+        padding: 10px;
+        background: #f8f9fa;
+        font-size: 14px;
+      }
+      .dashboard-figure{
+        display: inline-block;
+        width: 24%;
+        text-align: center;
+      }
+      .dashboard-figure strong{
+        display: block;
+        font-size: 22px;
+      }
+      .chart{
+        width: 100%;
+        height: 100px;
+        background: #fff;
+        margin-bottom: 10px;
+      }
+      .chart rect{
+        fill: #4a6567;
+      }
+      .chart polyline{
+        fill: none;
+        stroke: #e67e22;
+        stroke-width: 2;
+      }
+      .focus{
+        padding: 5px 10px;
+        background: #fdebd0;
+        font-size: 14px;
+      }
+      .focus-clock{
+        font-weight: bold;
+        font-family: monospace;
+        font-size: 18px;
+      }
+      .inbox-toggle{
+        float: right;
+        cursor: pointer;
+      }
+      .inbox{
+        font-size: 14px;
+        border-bottom: 1px solid #dee2e6;
+      }
+      .inbox .unread{
+        font-weight: bold;
+      }
     </style>
   </head>
   <body>
     <div class="container" id="root">
         <div class="row">
-            <div class="col-6 offset-3">
+            <div :class="view == 'board' ? 'col-12' : 'col-6 offset-3'">
                 <br><br>
                 <div class="card">
                   <div class="todo-title">
                     Daily Todo Lists
+                    <span class="inbox-toggle" v-on:click="toggleInbox" title="Notifications">
+                      <span class="fa fa-bell"></span>
+                      <span class="badge badge-danger" v-if="inbox.unread">@{ inbox.unread }</span>
+                    </span>
                   </div>
+                  <ul class="list-group inbox" v-if="inbox.open">
+                    <li class="list-group-item" :class="{ 'unread': !n.read_at }" v-for="n in inbox.items" v-on:click="readNotification(n)">
+                      <span :class="n.type == 'mention' ? 'fa fa-at' : 'fa fa-user'"></span> @{ n.message }
+                      <span class="activity-meta float-right">@{ new Date(n.created_at).toLocaleString() }</span>
+                    </li>
+                    <li class="list-group-item" v-if="inbox.items.length == 0">No notification</li>
+                    <li class="list-group-item" v-if="inbox.unread"><a href="#" v-on:click.prevent="readAllNotifications">Mark all as read</a></li>
+                  </ul>
                   <div class="card-body">
+                      <div class="view-switch">
+                        <div class="btn-group btn-group-sm" role="group">
+                          <button type="button" class="btn custom-button" :class="view == 'list' ? 'btn-secondary' : 'btn-light'" v-on:click="view = 'list'"><span class="fa fa-list"></span></button>
+                          <button type="button" class="btn custom-button" :class="view == 'board' ? 'btn-secondary' : 'btn-light'" v-on:click="showBoard"><span class="fa fa-columns"></span></button>
+                          <button type="button" class="btn custom-button" :class="view == 'stats' ? 'btn-secondary' : 'btn-light'" v-on:click="showStats"><span class="fa fa-bar-chart"></span></button>
+                        </div>
+                        <select class="custom-select custom-select-sm" v-if="view == 'board'" v-model="board.listID" v-on:change="fetchBoard">
+                          <option :value="0">Inbox</option>
+                          <option v-for="list in lists" :value="list.id">@{ list.name }</option>
+                        </select>
+                        <button type="button" class="btn btn-light btn-sm custom-button" v-if="view == 'board' && board.listID" v-on:click="saveTemplate" title="Save as template"><span class="fa fa-clone"></span></button>
+                        <select class="custom-select custom-select-sm" v-if="view == 'board' && templates.length" v-on:change="instantiateTemplate($event.target.value); $event.target.value = ''">
+                          <option value="">New list from template…</option>
+                          <option v-for="template in templates" :value="template.id">@{ template.name }</option>
+                        </select>
+                      </div>
+                      <div class="focus">
+                        <span v-if="focus.session">
+                          <span class="focus-clock">@{ focusClock }</span>
+                          @{ focus.session.kind == 'break' ? 'Break' : focusTitle }
+                          <span class="badge badge-light" v-if="focus.session.interruptions" title="Interruptions"><i class="fa fa-bell-slash"></i> @{ focus.session.interruptions }</span>
+                          <div class="btn-group btn-group-sm float-right" role="group">
+                            <button type="button" class="btn btn-light custom-button" v-if="focus.session.state == 'running'" v-on:click="focusAction('pause')"><span class="fa fa-pause"></span></button>
+                            <button type="button" class="btn btn-light custom-button" v-if="focus.session.state == 'paused'" v-on:click="focusAction('resume')"><span class="fa fa-play"></span></button>
+                            <button type="button" class="btn btn-light custom-button" v-on:click="focusAction('complete')"><span class="fa fa-stop"></span></button>
+                          </div>
+                        </span>
+                        <span v-else>
+                          <span class="fa fa-bullseye"></span> @{ focus.message || 'No focus session' }
+                          <div class="btn-group btn-group-sm float-right" role="group">
+                            <button type="button" class="btn btn-light custom-button" v-on:click="startFocus(null, 'work')">Focus</button>
+                            <button type="button" class="btn btn-light custom-button" v-on:click="startFocus(null, 'break')">Break</button>
+                          </div>
+                        </span>
+                      </div>
+                      <div class="board" v-if="view == 'board'">
+                        <div class="board-column" :class="{ 'drop-target': board.over === column.status.name }" v-for="column in board.columns"
+                            v-on:dragover.prevent="board.over = column.status.name" v-on:drop.prevent="dropOnColumn(column)">
+                          <h6>@{ column.status.label } <span class="badge badge-light">@{ column.todos.length }</span></h6>
+                          <div class="card board-card" :class="{ 'checked': todo.completed, 'not-checked': !todo.completed }" v-for="todo in column.todos"
+                              draggable="true" v-on:dragstart="boardDragStart(todo, column, $event)" v-on:dragend="board.over = null">
+                            <span class="badge badge-warning" v-if="todo.priority">@{ todo.priority }</span>
+                            @{ todo.title }
+                            <i class="fa fa-lock" v-if="todo.blocked && !todo.completed" title="Waiting for dependencies"></i>
+                          </div>
+                        </div>
+                      </div>
+                      <div class="dashboard" v-if="view == 'stats' && stats.summary">
+                        <div>
+                          <div class="dashboard-figure"><strong>@{ stats.summary.completed }</strong>completed</div>
+                          <div class="dashboard-figure"><strong>@{ formatDuration(stats.summary.average_complete_seconds) }</strong>to complete</div>
+                          <div class="dashboard-figure"><strong>@{ Math.round(stats.summary.overdue_rate * 100) }%</strong>overdue</div>
+                          <div class="dashboard-figure"><strong>@{ stats.summary.streaks.current }</strong>day streak (best @{ stats.summary.streaks.longest })</div>
+                        </div>
+                        <h6>Completed per day</h6>
+                        <svg class="chart" viewBox="0 0 300 100" preserveAspectRatio="none">
+                          <rect v-for="(day, i) in stats.summary.completed_per_day" :x="i * 300 / stats.summary.completed_per_day.length" :width="300 / stats.summary.completed_per_day.length - 1"
+                              :y="100 - day.count * 100 / chartMax(stats.summary.completed_per_day, 'count')" :height="day.count * 100 / chartMax(stats.summary.completed_per_day, 'count')"><title>@{ day.date } : @{ day.count }</title></rect>
+                        </svg>
+                        <div v-for="list in stats.burndowns">
+                          <h6>Burndown &middot; @{ list.name } <span class="activity-meta">@{ list.days[list.days.length - 1].open } open</span></h6>
+                          <svg class="chart" viewBox="0 0 300 100" preserveAspectRatio="none">
+                            <polyline :points="burndownPoints(list)"></polyline>
+                          </svg>
+                        </div>
+                      </div>
+                      <div v-if="view == 'list'">
                       <form v-on:submit.prevent>
                         <div class="input-group">
                           <input type="text" v-model="todo.title" v-on:keyup="checkForEnter($event)" class="form-control custom-input" :class="{ 'error': showError }" placeholder="Add your todo">
@@ -84,16 +283,70 @@
                           </span>
                         </div>
                       </form>
+                      <div class="quick-add-preview" v-if="preview && (preview.due_at || preview.tags || preview.priority || preview.recurrence)">
+                        <span class="badge badge-warning" v-if="preview.priority">@{ preview.priority }</span>
+                        <span class="badge badge-info" v-if="preview.due_at"><i class="fa fa-clock-o"></i> @{ new Date(preview.due_at).toLocaleString() }</span>
+                        <span class="badge badge-secondary" v-for="tag in preview.tags">#@{ tag }</span>
+                        <span class="badge badge-light" v-if="preview.recurrence"><i class="fa fa-repeat"></i> @{ preview.recurrence }</span>
+                      </div>
+                      <div class="input-group">
+                        <input type="search" v-model="search.q" v-on:input="searchTodos" class="form-control custom-input" placeholder="Search todos">
+                      </div>
+                      <ul class="list-group search-results" v-if="search.q.length > 1">
+                        <li class="list-group-item" v-for="result in search.results">
+                          <span v-html="result.title_html"></span>
+                          <div class="search-snippet" v-if="result.snippet" v-html="result.snippet"></div>
+                        </li>
+                        <li class="list-group-item" v-if="search.results.length == 0">No result</li>
+                      </ul>
                       <ul class="list-group">
-                        <li class="list-group-item" :class="{ 'checked': todo.completed, 'not-checked': !todo.completed }" v-for="(todo, todoIndex) in todos" v-on:click="toggleTodo(todo, todoIndex)">
+                        <li class="list-group-item" :class="{ 'checked': todo.completed, 'not-checked': !todo.completed, 'dragging': drag.from === todoIndex, 'drop-target': drag.over === todoIndex }" v-for="(todo, todoIndex) in todos" v-on:click="toggleTodo(todo, todoIndex)"
+                            draggable="true" v-on:dragstart="dragStart(todoIndex, $event)" v-on:dragover.prevent="drag.over = todoIndex" v-on:dragend="dragEnd" v-on:drop.prevent="dropTodo(todoIndex)">
                             <i :class="{'fa fa-circle': !todo.completed, 'fa fa-check-circle text-success': todo.completed }">&nbsp;</i>
+                            <span class="badge badge-warning" v-if="todo.priority">@{ todo.priority }</span>
                             <span :class="{ 'del': todo.completed }">@{ todo.title }</span>
+                            <i class="fa fa-repeat" v-if="todo.recurrence" :title="todo.recurrence"></i>
+                            <i class="fa fa-lock" v-if="todo.blocked && !todo.completed" title="Waiting for dependencies"></i>
+                            <span class="badge badge-light" v-if="todo.assignee_id" title="Assignee"><i class="fa fa-user"></i> #@{ todo.assignee_id }</span>
+                            <span class="badge badge-light" v-if="todo.estimate_minutes" title="Estimate"><i class="fa fa-hourglass-half"></i> @{ todo.estimate_minutes } min</span>
+                            <span class="badge badge-light" v-for="(value, name) in todo.fields" :title="name">@{ name }: @{ value === true ? '✓' : value === false ? '✗' : value }</span>
                             <div class="btn-group float-right" role="group" aria-label="Basic example">
+                              <button type="button" class="btn btn-sm custom-button" :class="timer.todoID === todo.id ? 'btn-warning' : 'btn-light'" v-on:click.prevent.stop v-on:click="toggleTimer(todo)"><span :class="timer.todoID === todo.id ? 'fa fa-stop' : 'fa fa-play'"></span></button>
+                              <button type="button" class="btn btn-light btn-sm custom-button" title="Focus on this todo" v-on:click.prevent.stop v-on:click="startFocus(todo, 'work')"><span class="fa fa-bullseye"></span></button>
+                              <button type="button" class="btn btn-info btn-sm custom-button" v-on:click.prevent.stop v-on:click="openActivity(todo)"><span class="fa fa-comments"></span></button>
                               <button type="button" class="btn btn-success btn-sm custom-button" v-on:click.prevent.stop v-on:click="editTodo(todo, todoIndex)"><span class="fa fa-edit"></span></button>
+                              <button type="button" class="btn btn-light btn-sm custom-button" title="Archive" v-if="todo.completed" v-on:click.prevent.stop v-on:click="archiveTodo(todo)"><span class="fa fa-archive"></span></button>
                               <button type="button" class="btn btn-danger btn-sm custom-button" v-on:click.prevent.stop v-on:click="deleteTodo(todo, todoIndex)"><span class="fa fa-trash"></span></button>
                             </div>
                         </li>
                       </ul>
+                      <div class="activity" v-if="activity.todo">
+                        <button type="button" class="close" v-on:click="activity.todo = null">&times;</button>
+                        <h6>@{ activity.todo.title }</h6>
+                        <div class="attachments">
+                          <div v-for="file in activity.attachments">
+                            <span class="fa fa-paperclip"></span>
+                            <a :href="'attachments/'+file.id">@{ file.file_name }</a>
+                            <span class="activity-meta">@{ Math.ceil(file.size / 1024) } KB</span>
+                            <a href="#" v-on:click.prevent="deleteAttachment(file)"><span class="fa fa-trash"></span></a>
+                          </div>
+                          <input type="file" class="form-control-file" accept="image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain" v-on:change="uploadAttachment">
+                        </div>
+                        <div class="activity-entry" v-for="entry in activity.entries">
+                          <div class="activity-meta">
+                            #@{ entry.actor_id || '?' } &middot; @{ new Date(entry.at).toLocaleString() }
+                            <span v-if="entry.kind == 'change'">&middot; @{ entry.activity.action }</span>
+                            <a href="#" v-if="entry.kind == 'comment'" v-on:click.prevent="deleteComment(entry.comment)"><span class="fa fa-trash"></span></a>
+                          </div>
+                          <div v-if="entry.kind == 'comment'" v-html="entry.comment.body_html"></div>
+                          <div v-if="entry.kind == 'change'">
+                            <span v-for="(change, field) in entry.activity.changes">@{ field }: @{ change.from } &rarr; @{ change.to }<br></span>
+                          </div>
+                        </div>
+                        <textarea class="form-control" rows="2" v-model="activity.body" placeholder="Comment, @mention, **markdown**"></textarea>
+                        <button type="button" class="btn btn-info btn-sm custom-button" v-on:click="addComment">Comment</button>
+                      </div>
+                      </div>
                   </div>
                 </div>
             </div>
@@ -112,14 +365,286 @@
           showError: false,
           enableEdit: false,
           todo: {id: '', title: '', completed: false},
-          todos: []
+          todos: [],
+          search: {q: '', results: [], timer: null},
+          drag: {from: null, over: null},
+          view: 'list',
+          lists: [],
+          templates: [],
+          inbox: {open: false, items: [], unread: 0},
+          board: {listID: 0, columns: [], dragged: null, over: null},
+          activity: {todo: null, entries: [], attachments: [], body: ''},
+          timer: {todoID: null},
+          focus: {session: null, message: '', stream: null},
+          stats: {summary: null, burndowns: []},
+          preview: null,
+          previewTimer: null,
+          timezone: Intl.DateTimeFormat().resolvedOptions().timeZone
         },
         mounted () {
           this.$http.get('todo').then(response => {
             this.todos = response.body.data;
           });
+          this.listenFocus();
+          this.fetchInbox();
+        },
+        computed: {
+          focusClock(){
+            var left = this.focus.session ? this.focus.session.remaining_seconds : 0;
+            var seconds = left % 60;
+            return Math.floor(left / 60) + ':' + (seconds < 10 ? '0' : '') + seconds;
+          },
+          focusTitle(){
+            var id = this.focus.session && this.focus.session.todo_id;
+            var todo = this.todos.find(t => t.id === id);
+            return todo ? todo.title : 'Focus';
+          }
         },
         methods: {
+          fetchInbox(){
+            this.$http.get('notifications').then(response => {
+              this.inbox.items = response.body.data;
+              this.inbox.unread = response.body.unread;
+            });
+          },
+          toggleInbox(){
+            this.inbox.open = !this.inbox.open;
+            if (this.inbox.open){
+              this.fetchInbox();
+            }
+          },
+          readNotification(n){
+            if (n.read_at){
+              return;
+            }
+            this.$http.post('notifications/'+n.id+'/read').then(response => {
+              n.read_at = response.body.notification.read_at;
+              this.inbox.unread--;
+            });
+          },
+          readAllNotifications(){
+            this.$http.post('notifications/read').then(response => {
+              this.fetchInbox();
+            });
+          },
+          showStats(){
+            this.view = 'stats';
+            this.$http.get('stats', {params: {tz: this.timezone}}).then(response => {
+              this.stats.summary = response.body.data;
+            });
+            this.$http.get('stats/burndown', {params: {tz: this.timezone}}).then(response => {
+              this.stats.burndowns = response.body.data;
+            });
+          },
+          chartMax(points, field){
+            return Math.max(1, ...points.map(p => p[field]));
+          },
+          burndownPoints(list){
+            var max = this.chartMax(list.days, 'open');
+            var step = 300 / Math.max(1, list.days.length - 1);
+            return list.days.map((day, i) => (i * step) + ',' + (100 - day.open * 100 / max)).join(' ');
+          },
+          formatDuration(seconds){
+            if (seconds < 3600){
+              return Math.round(seconds / 60) + ' min';
+            }
+            if (seconds < 86400){
+              return Math.round(seconds / 3600) + ' h';
+            }
+            return Math.round(seconds / 86400) + ' d';
+          },
+          // le serveur envoie l'état de la session chaque seconde ; EventSource se reconnecte seul
+          listenFocus(){
+            this.focus.stream = new EventSource('focus/stream');
+            this.focus.stream.addEventListener('tick', event => {
+              this.focus.session = JSON.parse(event.data);
+            });
+            this.focus.stream.addEventListener('complete', event => {
+              var session = JSON.parse(event.data);
+              this.focus.session = null;
+              this.focus.message = session.kind == 'break' ? 'Break is over' : 'Focus session completed';
+            });
+          },
+          startFocus(todo, kind){
+            this.$http.post('focus', {todo_id: todo ? todo.id : null, kind: kind}).then(response => {
+              this.focus.session = response.body.session;
+              this.focus.message = '';
+            }, response => {
+              alert(response.body.error || response.body.message);
+            });
+          },
+          focusAction(action){
+            this.$http.post('focus/'+this.focus.session.id+'/'+action).then(response => {
+              this.focus.session = response.body.session.state == 'completed' ? null : response.body.session;
+            }, response => {
+              alert(response.body.error || response.body.message);
+            });
+          },
+          openActivity(todo){
+            this.activity = {todo: todo, entries: [], attachments: [], body: ''};
+            this.fetchActivity();
+            this.fetchAttachments();
+          },
+          toggleTimer(todo){
+            var action = this.timer.todoID === todo.id ? 'stop' : 'start';
+            this.$http.post('todo/'+todo.id+'/timer/'+action).then(response => {
+              this.timer.todoID = action == 'start' ? todo.id : null;
+            }, response => {
+              alert(response.body.error || response.body.message);
+            });
+          },
+          fetchAttachments(){
+            this.$http.get('todo/'+this.activity.todo.id+'/attachments').then(response => {
+              this.activity.attachments = response.body.data || [];
+            });
+          },
+          uploadAttachment(event){
+            var file = event.target.files[0];
+            if (!file){
+              return;
+            }
+            var form = new FormData();
+            form.append('file', file);
+            this.$http.post('todo/'+this.activity.todo.id+'/attachments', form).then(response => {
+              event.target.value = '';
+              this.fetchAttachments();
+            }, response => {
+              event.target.value = '';
+              alert(response.body.error || response.body.message);
+            });
+          },
+          deleteAttachment(file){
+            if(confirm("Delete "+file.file_name+" ?")){
+              this.$http.delete('attachments/'+file.id).then(response => {
+                this.fetchAttachments();
+              }, response => {
+                alert(response.body);
+              });
+            }
+          },
+          fetchActivity(){
+            this.$http.get('todo/'+this.activity.todo.id+'/activity').then(response => {
+              this.activity.entries = response.body.data;
+            });
+          },
+          addComment(){
+            if (this.activity.body.trim() == ''){
+              return;
+            }
+            this.$http.post('todo/'+this.activity.todo.id+'/comments', {body: this.activity.body}).then(response => {
+              this.activity.body = '';
+              this.fetchActivity();
+            });
+          },
+          deleteComment(comment){
+            if(confirm("Delete this comment ?")){
+              this.$http.delete('comments/'+comment.id).then(response => {
+                this.fetchActivity();
+              }, response => {
+                alert(response.body);
+              });
+            }
+          },
+          showBoard(){
+            this.view = 'board';
+            this.$http.get('lists').then(response => {
+              this.lists = response.body.data;
+            });
+            this.fetchTemplates();
+            this.fetchBoard();
+          },
+          fetchTemplates(){
+            this.$http.get('templates').then(response => {
+              this.templates = response.body.data || [];
+            });
+          },
+          saveTemplate(){
+            this.$http.post('lists/'+this.board.listID+'/template', {}).then(response => {
+              this.fetchTemplates();
+            }, response => {
+              alert(response.body.error);
+            });
+          },
+          instantiateTemplate(id){
+            if (!id){
+              return;
+            }
+            // les échéances du modèle partent d'aujourd'hui
+            var today = new Date();
+            var start = today.getFullYear()+'-'+('0'+(today.getMonth()+1)).slice(-2)+'-'+('0'+today.getDate()).slice(-2);
+            this.$http.post('templates/'+id+'/instantiate', {start: start}).then(response => {
+              this.lists.push(response.body.list);
+              this.board.listID = response.body.list.id;
+              this.fetchBoard();
+            }, response => {
+              alert(response.body.error);
+            });
+          },
+          fetchBoard(){
+            this.$http.get('lists/'+this.board.listID+'/board').then(response => {
+              this.board.columns = response.body.data.columns;
+            });
+          },
+          boardDragStart(todo, column, event){
+            this.board.dragged = {todo: todo, from: column};
+            event.dataTransfer.effectAllowed = 'move';
+            event.dataTransfer.setData('text/plain', String(todo.id)); // requis par Firefox
+          },
+          dropOnColumn(column){
+            var dragged = this.board.dragged;
+            this.board.dragged = null;
+            this.board.over = null;
+            if (!dragged || dragged.from === column){
+              return;
+            }
+            // le serveur refuse (409) les transitions interdites par le flux de la liste
+            this.$http.put('todo/'+dragged.todo.id, {status: column.status.name}).then(response => {
+              this.fetchBoard();
+            }, response => {
+              alert(response.body.error);
+            });
+          },
+          searchTodos(){
+            clearTimeout(this.search.timer);
+            if (this.search.q.length < 2){
+              this.search.results = [];
+              return;
+            }
+            this.search.timer = setTimeout(() => {
+              this.$http.get('todo/search', {params: {q: this.search.q}}).then(response => {
+                this.search.results = response.body.data;
+              });
+            }, 250);
+          },
+          dragStart(todoIndex, event){
+            this.drag.from = todoIndex;
+            event.dataTransfer.effectAllowed = 'move';
+            event.dataTransfer.setData('text/plain', String(todoIndex)); // requis par Firefox
+          },
+          dragEnd(){
+            this.drag = {from: null, over: null};
+          },
+          dropTodo(targetIndex){
+            var from = this.drag.from;
+            this.dragEnd();
+            if (from === null || from === targetIndex){
+              return;
+            }
+            var moved = this.todos[from];
+            var target = this.todos[targetIndex];
+            // vers le bas : après la cible, vers le haut : avant
+            var anchor = from < targetIndex ? {after: target.id} : {before: target.id};
+            this.todos.splice(from, 1);
+            this.todos.splice(targetIndex, 0, moved);
+            this.$http.post('todo/'+moved.id+'/move', anchor).then(response => {
+              moved.position = response.body.todo.position;
+            }, () => {
+              // annule le déplacement local si le serveur le refuse
+              this.$http.get('todo').then(response => {
+                this.todos = response.body.data;
+              });
+            });
+          },
           addTodo(){
             if (this.todo.title == ''){
               this.showError = true;
@@ -134,10 +659,12 @@
                 this.todo = {id: '', title: '', completed: false};
                 this.enableEdit = false;
               }else{
-                this.$http.post('todo', {title: this.todo.title}).then(response => {
+                // le titre est analysé côté serveur : échéance, #étiquettes, !priorité, récurrence
+                this.$http.post('todo', {title: this.todo.title}, {params: {parse: true, tz: this.timezone}}).then(response => {
                   if(response.status == 201){
-                    this.todos.push({id: response.body.todo_id, title: this.todo.title, completed: false});
+                    this.todos.push(response.body.todo);
                     this.todo = {id: '', title: '', completed: false};
+                    this.preview = null;
                   }
                 });
               }
@@ -146,7 +673,21 @@
           checkForEnter(event){
             if (event.key == "Enter") {
               this.addTodo();
+              return;
             }
+            this.previewTodo();
+          },
+          previewTodo(){
+            clearTimeout(this.previewTimer);
+            if (this.enableEdit || this.todo.title.length < 2){
+              this.preview = null;
+              return;
+            }
+            this.previewTimer = setTimeout(() => {
+              this.$http.get('todo/parse', {params: {text: this.todo.title, tz: this.timezone}}).then(response => {
+                this.preview = response.body.data;
+              });
+            }, 300);
           },
           toggleTodo(todo, todoIndex){
             var completedToggle;
@@ -158,6 +699,10 @@
             this.$http.put('todo/'+todo.id, {id: todo.id, title: todo.title, completed: completedToggle}).then(response => {
               if(response.status == 200){
                 this.todos[todoIndex].completed = completedToggle;
+                // un todo récurrent terminé crée sa prochaine occurrence
+                if(response.body.todo.next){
+                  this.todos.push(response.body.todo.next);
+                }
               }
             });
           },
@@ -166,6 +711,16 @@
             this.todo = todo;
             this.todo.todoIndex = todoIndex;
           },
+          archiveTodo(todo){
+            // les sous-tâches sont archivées avec leur parent
+            this.$http.post('todo/'+todo.id+'/archive').then(response => {
+              this.$http.get('todo').then(response => {
+                this.todos = response.body.data;
+              });
+            }, response => {
+              alert(response.body.error);
+            });
+          },
           deleteTodo(todo, todoIndex){
             if(confirm("Are you sure ?")){
               this.$http.delete('todo/'+todo.id).then(response => {
@@ -0,0 +1,210 @@
+// Package todotxt lit et écrit le format todo.txt (http://todotxt.org) :
+//
+//	x 2024-08-02 2024-08-01 (A) Appeler maman +famille @telephone due:2024-08-03
+//
+// et le fait correspondre aux champs de models.TodoModel.
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	models "github.com/go-todo1/Models"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	priorityRe = regexp.MustCompile(`^\([A-Z]\)$`)
+	dateRe     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+type Task struct {
+	Completed      bool
+	Priority       string
+	CompletionDate time.Time
+	CreationDate   time.Time
+	Text           string // description sans projets, contextes ni due:
+	Projects       []string
+	Contexts       []string
+	Due            time.Time
+}
+
+// Line est le résultat de l'analyse d'une ligne d'un fichier todo.txt
+type Line struct {
+	Row  int
+	Task Task
+	Err  error
+}
+
+// Parse analyse une ligne todo.txt
+func Parse(line string) (Task, error) {
+	var t Task
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return t, fmt.Errorf("empty task")
+	}
+
+	if fields[0] == "x" {
+		t.Completed = true
+		fields = fields[1:]
+		if len(fields) > 0 && dateRe.MatchString(fields[0]) {
+			d, err := time.Parse(dateLayout, fields[0])
+			if err != nil {
+				return t, fmt.Errorf("invalid completion date %q", fields[0])
+			}
+			t.CompletionDate = d
+			fields = fields[1:]
+		}
+	} else if priorityRe.MatchString(fields[0]) {
+		t.Priority = fields[0][1:2]
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && dateRe.MatchString(fields[0]) {
+		d, err := time.Parse(dateLayout, fields[0])
+		if err != nil {
+			return t, fmt.Errorf("invalid creation date %q", fields[0])
+		}
+		t.CreationDate = d
+		fields = fields[1:]
+	}
+
+	var words []string
+	for _, f := range fields {
+		switch {
+		case len(f) > 1 && f[0] == '+':
+			t.Projects = appendUnique(t.Projects, f[1:])
+		case len(f) > 1 && f[0] == '@':
+			t.Contexts = appendUnique(t.Contexts, f[1:])
+		case strings.HasPrefix(f, "due:"):
+			d, err := time.Parse(dateLayout, f[len("due:"):])
+			if err != nil {
+				return t, fmt.Errorf("invalid due date %q", f)
+			}
+			t.Due = d
+		case strings.HasPrefix(f, "pri:") && t.Completed:
+			// convention courante pour conserver la priorité d'une tâche terminée
+			if p := f[len("pri:"):]; len(p) == 1 && p[0] >= 'A' && p[0] <= 'Z' {
+				t.Priority = p
+			} else {
+				words = append(words, f)
+			}
+		default:
+			words = append(words, f)
+		}
+	}
+	t.Text = strings.Join(words, " ")
+	if t.Text == "" {
+		return t, fmt.Errorf("task description is required")
+	}
+	return t, nil
+}
+
+// String sérialise la tâche sur une ligne todo.txt
+func (t Task) String() string {
+	var parts []string
+	if t.Completed {
+		parts = append(parts, "x")
+		if !t.CompletionDate.IsZero() {
+			parts = append(parts, t.CompletionDate.Format(dateLayout))
+		}
+	} else if t.Priority != "" {
+		parts = append(parts, "("+t.Priority+")")
+	}
+	// la date de création d'une tâche terminée n'est valide qu'après sa date de fin
+	if !t.CreationDate.IsZero() && (!t.Completed || !t.CompletionDate.IsZero()) {
+		parts = append(parts, t.CreationDate.Format(dateLayout))
+	}
+	if text := strings.Join(strings.Fields(t.Text), " "); text != "" {
+		parts = append(parts, text)
+	}
+	for _, p := range t.Projects {
+		parts = append(parts, "+"+p)
+	}
+	for _, c := range t.Contexts {
+		parts = append(parts, "@"+c)
+	}
+	if !t.Due.IsZero() {
+		parts = append(parts, "due:"+t.Due.Format(dateLayout))
+	}
+	if t.Completed && t.Priority != "" {
+		parts = append(parts, "pri:"+t.Priority)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseAll lit un fichier todo.txt complet ; les lignes vides sont ignorées
+func ParseAll(r io.Reader) ([]Line, error) {
+	var lines []Line
+	scanner := bufio.NewScanner(r)
+	for row := 1; scanner.Scan(); row++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		task, err := Parse(text)
+		lines = append(lines, Line{Row: row, Task: task, Err: err})
+	}
+	return lines, scanner.Err()
+}
+
+// FromTodo convertit un todo en tâche todo.txt
+func FromTodo(todo models.TodoModel) Task {
+	t := Task{
+		Completed:    todo.Completed,
+		Priority:     todo.Priority,
+		CreationDate: day(todo.CreatedAt),
+		Text:         todo.Title,
+		Projects:     strings.Fields(todo.Projects),
+		Contexts:     strings.Fields(todo.Contexts),
+	}
+	if todo.CompletedAt != nil {
+		t.CompletionDate = day(*todo.CompletedAt)
+	}
+	if todo.DueAt != nil {
+		t.Due = day(*todo.DueAt)
+	}
+	return t
+}
+
+// ToTodo convertit une tâche todo.txt en todo (sans ID)
+func (t Task) ToTodo() models.TodoModel {
+	todo := models.TodoModel{
+		Title:     t.Text,
+		Completed: t.Completed,
+		Priority:  t.Priority,
+		Projects:  strings.Join(t.Projects, " "),
+		Contexts:  strings.Join(t.Contexts, " "),
+		CreatedAt: t.CreationDate,
+	}
+	if !t.CompletionDate.IsZero() {
+		d := t.CompletionDate
+		todo.CompletedAt = &d
+	}
+	if !t.Due.IsZero() {
+		d := t.Due
+		todo.DueAt = &d
+	}
+	return todo
+}
+
+func day(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
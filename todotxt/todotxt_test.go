@@ -0,0 +1,113 @@
+package todotxt_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	models "github.com/go-todo1/Models"
+	"github.com/go-todo1/todotxt"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected todotxt.Task
+		err      string
+	}{
+		{
+			name: "open task with priority",
+			line: "(A) 2024-08-01 Call mom +family @phone due:2024-08-03",
+			expected: todotxt.Task{
+				Priority:     "A",
+				CreationDate: date("2024-08-01"),
+				Text:         "Call mom",
+				Projects:     []string{"family"},
+				Contexts:     []string{"phone"},
+				Due:          date("2024-08-03"),
+			},
+		},
+		{
+			name: "completed task",
+			line: "x 2024-08-02 2024-08-01 Pay invoice +finance pri:B",
+			expected: todotxt.Task{
+				Completed:      true,
+				Priority:       "B",
+				CompletionDate: date("2024-08-02"),
+				CreationDate:   date("2024-08-01"),
+				Text:           "Pay invoice",
+				Projects:       []string{"finance"},
+			},
+		},
+		{
+			name:     "priority not at start is text",
+			line:     "Read chapter (A) tonight",
+			expected: todotxt.Task{Text: "Read chapter (A) tonight"},
+		},
+		{
+			name: "invalid due date",
+			line: "Renew passport due:tomorrow",
+			err:  `invalid due date "due:tomorrow"`,
+		},
+		{
+			name: "no description",
+			line: "(B) +project @context",
+			err:  "task description is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task, err := todotxt.Parse(tc.line)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, task)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	lines := []string{
+		"(A) 2024-08-01 Call mom +family @phone due:2024-08-03",
+		"x 2024-08-02 2024-08-01 Pay invoice +finance pri:B",
+		"Water plants @home",
+	}
+	for _, line := range lines {
+		task, err := todotxt.Parse(line)
+		assert.NoError(t, err)
+		todo := task.ToTodo()
+		assert.Equal(t, line, todotxt.FromTodo(todo).String())
+	}
+}
+
+func TestFromTodo(t *testing.T) {
+	completed := time.Date(2024, 8, 2, 17, 30, 0, 0, time.UTC)
+	task := todotxt.FromTodo(models.TodoModel{
+		Title:       "Ship release",
+		Completed:   true,
+		CompletedAt: &completed,
+		Projects:    "backend ops",
+		CreatedAt:   time.Date(2024, 7, 30, 9, 0, 0, 0, time.UTC),
+	})
+	assert.Equal(t, "x 2024-08-02 2024-07-30 Ship release +backend +ops", task.String())
+}
+
+func TestParseAll(t *testing.T) {
+	lines, err := todotxt.ParseAll(strings.NewReader("Buy milk\n\n(Z) due:2024-13-01 Broken\n"))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, 1, lines[0].Row)
+	assert.NoError(t, lines[0].Err)
+	assert.Equal(t, 3, lines[1].Row)
+	assert.Error(t, lines[1].Err)
+}